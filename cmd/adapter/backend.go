@@ -8,13 +8,20 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
+// ProgressFunc receives the cumulative number of bytes transferred so far for
+// a single object. Backends call it while a transfer is still in flight.
+type ProgressFunc func(bytesSoFar int64)
+
 // TransferBackend defines the storage/runtime backend used by adapter transfers.
+// The progress callback may be nil; backends that cannot observe partial
+// progress simply never call it.
 type TransferBackend interface {
 	Initialize(session *Session) error
-	Upload(session *Session, oid, sourcePath string, expectedSize int64) (int64, error)
-	Download(session *Session, oid string) (string, int64, error)
+	Upload(session *Session, oid, sourcePath string, expectedSize int64, progress ProgressFunc) (int64, error)
+	Download(session *Session, oid string, progress ProgressFunc) (string, int64, error)
 }
 
 // ErrorCode is a machine-readable error classification for structured error handling.
@@ -32,6 +39,9 @@ const (
 	ErrCodeUnknown          ErrorCode = "unknown"
 )
 
+// errHashMismatch marks a stored object whose content does not hash to its OID.
+var errHashMismatch = errors.New("content does not match oid")
+
 // BackendError maps backend-specific failures to protocol-safe transfer errors.
 type BackendError struct {
 	Code      int       // HTTP-style status code
@@ -122,6 +132,9 @@ func backendErrorDetails(err error) (int, string) {
 // which resolves credentials locally (git-credential, pass-cli, etc.).
 type OperationCredentials struct {
 	CredentialProvider string
+	// Profile and Account select one of several Proton accounts.
+	Profile string
+	Account string
 }
 
 type LocalStoreBackend struct {
@@ -147,7 +160,7 @@ func (b *LocalStoreBackend) Initialize(session *Session) error {
 	return nil
 }
 
-func (b *LocalStoreBackend) Upload(session *Session, oid, sourcePath string, expectedSize int64) (int64, error) {
+func (b *LocalStoreBackend) Upload(session *Session, oid, sourcePath string, expectedSize int64, _ ProgressFunc) (int64, error) {
 	if err := b.Initialize(session); err != nil {
 		return 0, err
 	}
@@ -167,7 +180,7 @@ func (b *LocalStoreBackend) Upload(session *Session, oid, sourcePath string, exp
 	}
 	if hash != oid {
 		_ = os.Remove(objectPath)
-		return 0, newBackendError(500, "stored object hash mismatch", nil)
+		return 0, newBackendError(500, "stored object hash mismatch", errHashMismatch)
 	}
 	if expectedSize > 0 && size != expectedSize {
 		_ = os.Remove(objectPath)
@@ -176,7 +189,7 @@ func (b *LocalStoreBackend) Upload(session *Session, oid, sourcePath string, exp
 	return size, nil
 }
 
-func (b *LocalStoreBackend) Download(session *Session, oid string) (string, int64, error) {
+func (b *LocalStoreBackend) Download(session *Session, oid string, _ ProgressFunc) (string, int64, error) {
 	if err := b.validateSession(session); err != nil {
 		return "", 0, err
 	}
@@ -193,7 +206,7 @@ func (b *LocalStoreBackend) Download(session *Session, oid string) (string, int6
 		return "", 0, newBackendError(500, "failed to read object from local store", err)
 	}
 	if hash != oid {
-		return "", 0, newBackendError(500, "stored object hash mismatch", nil)
+		return "", 0, newBackendError(500, "stored object hash mismatch", errHashMismatch)
 	}
 
 	tmpFile, err := os.CreateTemp("", "git-lfs-proton-download-*")
@@ -223,10 +236,76 @@ func (b *LocalStoreBackend) validateSession(session *Session) error {
 }
 
 func (b *LocalStoreBackend) objectPath(oid string) string {
+	return fanoutPath(b.storeDir, oid)
+}
+
+// fanoutPath lays out objects as <root>/<oid[0:2]>/<oid[2:4]>/<oid>.
+func fanoutPath(root, oid string) string {
 	if len(oid) < 4 {
-		return filepath.Join(b.storeDir, oid)
+		return filepath.Join(root, oid)
+	}
+	return filepath.Join(root, oid[:2], oid[2:4], oid)
+}
+
+// ListObjects returns every object in the local store.
+func (b *LocalStoreBackend) ListObjects(session *Session) ([]RemoteObject, error) {
+	if err := b.Initialize(session); err != nil {
+		return nil, err
+	}
+	var objects []RemoteObject
+	err := filepath.WalkDir(b.storeDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() || !oidPattern.MatchString(name) || path != b.objectPath(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, RemoteObject{OID: name, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, newBackendError(500, "failed to list local object store", err)
+	}
+	return objects, nil
+}
+
+// ExistsObjects reports which of oids are present in the local store.
+func (b *LocalStoreBackend) ExistsObjects(session *Session, oids []string) (map[string]bool, error) {
+	if err := b.Initialize(session); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(oids))
+	for _, oid := range oids {
+		info, err := os.Stat(b.objectPath(oid))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, newBackendError(500, "failed to check local object store", err)
+		}
+		result[oid] = err == nil && info.Mode().IsRegular()
 	}
-	return filepath.Join(b.storeDir, oid[:2], oid[2:4], oid)
+	return result, nil
+}
+
+// DeleteObjects removes objects from the local store. An object that is
+// already absent counts as deleted.
+func (b *LocalStoreBackend) DeleteObjects(session *Session, oids []string) (map[string]bool, error) {
+	if err := b.Initialize(session); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(oids))
+	for _, oid := range oids {
+		objectPath := b.objectPath(oid)
+		err := os.Remove(objectPath)
+		result[oid] = err == nil || errors.Is(err, os.ErrNotExist)
+		// Drop the fan-out directories once they are empty.
+		_ = os.Remove(filepath.Dir(objectPath))
+		_ = os.Remove(filepath.Dir(filepath.Dir(objectPath)))
+	}
+	return result, nil
 }
 
 // DriveCLIBackend communicates directly with proton-drive-cli via subprocess.
@@ -235,7 +314,27 @@ func (b *LocalStoreBackend) objectPath(oid string) string {
 type DriveCLIBackend struct {
 	bridge             *BridgeClient
 	credentialProvider string
+	profile            string
+	account            string
 	authenticated      bool
+
+	// exists caches batch-exists answers so uploads can skip the per-object
+	// existence subprocess. skipped holds the uploads that found their object
+	// already stored, until TakeDeduplicated reports them.
+	existsMu sync.Mutex
+	exists   map[string]bool
+	skipped  map[string]bool
+
+	// cache, when set, serves downloads locally and is filled by transfers.
+	cache *objectCache
+
+	// Objects larger than chunkThreshold bytes are stored as chunkSize parts
+	// when the bridge supports chunks.
+	chunkThreshold int64
+	chunkSize      int64
+
+	// resume, when set, persists chunk progress across adapter runs.
+	resume *resumeJournal
 }
 
 // NewDriveCLIBackend creates a backend that delegates to proton-drive-cli.
@@ -245,11 +344,20 @@ func NewDriveCLIBackend(bridge *BridgeClient, credentialProvider string) *DriveC
 	return &DriveCLIBackend{
 		bridge:             bridge,
 		credentialProvider: credentialProvider,
+		chunkThreshold:     DefaultChunkThresholdMB << 20,
+		chunkSize:          DefaultChunkSizeMB << 20,
 	}
 }
 
 func (b *DriveCLIBackend) operationCredentials() OperationCredentials {
-	return OperationCredentials{CredentialProvider: b.credentialProvider}
+	return OperationCredentials{CredentialProvider: b.credentialProvider, Profile: b.profile, Account: b.account}
+}
+
+// SetAccount selects the profile and Proton account whose credentials
+// proton-drive-cli resolves.
+func (b *DriveCLIBackend) SetAccount(profile, account string) {
+	b.profile = profile
+	b.account = account
 }
 
 func (b *DriveCLIBackend) Initialize(session *Session) error {
@@ -275,7 +383,7 @@ func (b *DriveCLIBackend) Initialize(session *Session) error {
 	return nil
 }
 
-func (b *DriveCLIBackend) Upload(session *Session, oid, sourcePath string, expectedSize int64) (int64, error) {
+func (b *DriveCLIBackend) Upload(session *Session, oid, sourcePath string, expectedSize int64, progress ProgressFunc) (int64, error) {
 	if session == nil || !session.Initialized {
 		return 0, newBackendError(500, "session not initialized", nil)
 	}
@@ -286,8 +394,13 @@ func (b *DriveCLIBackend) Upload(session *Session, oid, sourcePath string, expec
 		return 0, newBackendError(500, "drive-cli backend bridge is not configured", nil)
 	}
 
-	// Dedup: skip upload if OID already exists in remote storage
-	exists, err := b.bridge.Exists(b.operationCredentials(), oid)
+	// Dedup: skip upload if OID already exists in remote storage. A prefetched
+	// batch-exists answer avoids a separate exists subprocess.
+	exists, known := b.cachedExists(oid)
+	var err error
+	if !known {
+		exists, err = b.bridge.Exists(b.operationCredentials(), oid)
+	}
 	if err == nil && exists {
 		info, statErr := os.Stat(sourcePath)
 		if statErr != nil {
@@ -296,13 +409,11 @@ func (b *DriveCLIBackend) Upload(session *Session, oid, sourcePath string, expec
 			}
 			return 0, newBackendError(500, "failed to stat upload source file", statErr)
 		}
+		b.cacheObject(oid, sourcePath)
+		b.markSkipped(oid)
 		return info.Size(), nil
 	}
 
-	if err := b.bridge.Upload(b.operationCredentials(), oid, sourcePath); err != nil {
-		return 0, mapBridgeError(err, "drive-cli upload failed")
-	}
-
 	info, err := os.Stat(sourcePath)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -313,10 +424,78 @@ func (b *DriveCLIBackend) Upload(session *Session, oid, sourcePath string, expec
 	if expectedSize > 0 && info.Size() != expectedSize {
 		return 0, newBackendError(409, "upload size does not match transfer request", nil)
 	}
+
+	if info.Size() > b.chunkThreshold && b.bridge.supports(bridgeCapChunks) {
+		if err := b.uploadChunked(oid, sourcePath, progress); err != nil {
+			return 0, err
+		}
+	} else if err := b.bridge.Upload(b.operationCredentials(), oid, sourcePath, progress); err != nil {
+		return 0, mapBridgeError(err, "drive-cli upload failed")
+	}
+	b.rememberExists(oid, true)
+	b.cacheObject(oid, sourcePath)
 	return info.Size(), nil
 }
 
-func (b *DriveCLIBackend) Download(session *Session, oid string) (string, int64, error) {
+// PrefetchExists resolves existence for several OIDs with one batch-exists
+// call and caches the answers for Upload.
+func (b *DriveCLIBackend) PrefetchExists(oids []string) error {
+	if !b.authenticated || b.bridge == nil || len(oids) == 0 {
+		return nil
+	}
+	result, err := b.bridge.BatchExists(b.operationCredentials(), oids)
+	if err != nil {
+		return mapBridgeError(err, "drive-cli batch-exists failed")
+	}
+	for _, oid := range oids {
+		b.rememberExists(oid, result[oid])
+	}
+	return nil
+}
+
+// KnownToExist reports whether a previous batch-exists or upload showed the
+// object is already stored remotely.
+func (b *DriveCLIBackend) KnownToExist(oid string) bool {
+	exists, known := b.cachedExists(oid)
+	return known && exists
+}
+
+// TakeDeduplicated reports whether the last upload of oid was skipped because
+// the object was already stored, and forgets it.
+func (b *DriveCLIBackend) TakeDeduplicated(oid string) bool {
+	b.existsMu.Lock()
+	defer b.existsMu.Unlock()
+	skipped := b.skipped[oid]
+	delete(b.skipped, oid)
+	return skipped
+}
+
+func (b *DriveCLIBackend) markSkipped(oid string) {
+	b.existsMu.Lock()
+	defer b.existsMu.Unlock()
+	if b.skipped == nil {
+		b.skipped = make(map[string]bool)
+	}
+	b.skipped[oid] = true
+}
+
+func (b *DriveCLIBackend) cachedExists(oid string) (exists, known bool) {
+	b.existsMu.Lock()
+	defer b.existsMu.Unlock()
+	exists, known = b.exists[oid]
+	return exists, known
+}
+
+func (b *DriveCLIBackend) rememberExists(oid string, exists bool) {
+	b.existsMu.Lock()
+	defer b.existsMu.Unlock()
+	if b.exists == nil {
+		b.exists = make(map[string]bool)
+	}
+	b.exists[oid] = exists
+}
+
+func (b *DriveCLIBackend) Download(session *Session, oid string, progress ProgressFunc) (string, int64, error) {
 	if session == nil || !session.Initialized {
 		return "", 0, newBackendError(500, "session not initialized", nil)
 	}
@@ -327,31 +506,275 @@ func (b *DriveCLIBackend) Download(session *Session, oid string) (string, int64,
 		return "", 0, newBackendError(500, "drive-cli backend bridge is not configured", nil)
 	}
 
+	if b.cache != nil {
+		if tmpPath, size, ok := b.cache.stage(oid); ok {
+			if progress != nil {
+				progress(size)
+			}
+			return tmpPath, size, nil
+		}
+	}
+
+	journal, unlock := b.lockJournal(resumeDownload, oid)
+	defer unlock()
+	tmpPath, err := b.downloadWhole(journal, oid, progress)
+	if err != nil {
+		// A large object is stored as chunks; fall back to its manifest.
+		if isNotFound(err) && b.bridge.supports(bridgeCapChunks) {
+			tmpPath, err = b.downloadChunkedObject(journal, oid, progress, err)
+		}
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", 0, newBackendError(500, "drive-cli backend did not materialize download output", err)
+		}
+		return "", 0, newBackendError(500, "failed to stat downloaded object", err)
+	}
+
+	b.cacheObject(oid, tmpPath)
+	return tmpPath, info.Size(), nil
+}
+
+// downloadWhole fetches oid with one bridge command and returns the path it
+// was written to. With a resume journal and a bridge that takes an offset,
+// it is staged in the journal directory and a staged prefix left by an
+// interrupted attempt is continued instead of fetched again. An entry with
+// chunks is left alone for the manifest fallback to resume.
+func (b *DriveCLIBackend) downloadWhole(journal *resumeJournal, oid string, progress ProgressFunc) (string, error) {
+	if journal != nil && b.bridge.supports(bridgeCapRange) {
+		if prev := journal.load(resumeDownload, oid); prev == nil || prev.ChunkSize == 0 {
+			return b.downloadResumable(journal, prev, oid, progress)
+		}
+	}
 	tmpFile, err := os.CreateTemp("", "git-lfs-proton-download-*")
 	if err != nil {
-		return "", 0, newBackendError(500, "failed to create temporary download file", err)
+		return "", newBackendError(500, "failed to create temporary download file", err)
 	}
 	tmpPath := tmpFile.Name()
 	if err := tmpFile.Close(); err != nil {
 		_ = os.Remove(tmpPath)
-		return "", 0, newBackendError(500, "failed to create temporary download file", err)
+		return "", newBackendError(500, "failed to create temporary download file", err)
 	}
-
-	if err := b.bridge.Download(b.operationCredentials(), oid, tmpPath); err != nil {
+	if err := b.bridge.Download(b.operationCredentials(), oid, tmpPath, progress); err != nil {
 		_ = os.Remove(tmpPath)
-		return "", 0, mapBridgeError(err, "drive-cli download failed")
+		return "", mapBridgeError(err, "drive-cli download failed")
 	}
+	return tmpPath, nil
+}
 
-	info, err := os.Stat(tmpPath)
+// downloadResumable fetches oid into its staged file in journal, from the
+// end of the bytes that prev, the entry of an earlier attempt, left there. A
+// resumed download is re-hashed here, since its first bytes were written by
+// another process.
+func (b *DriveCLIBackend) downloadResumable(journal *resumeJournal, prev *ResumeEntry, oid string, progress ProgressFunc) (string, error) {
+	staged := journal.stagedPath(oid)
+	var offset int64
+	if prev != nil && prev.StagedPath == staged {
+		if info, err := os.Stat(staged); err == nil {
+			offset = info.Size()
+		}
+	}
+	if offset == 0 {
+		if err := os.MkdirAll(journal.dir, 0o700); err != nil {
+			return "", newBackendError(500, "failed to create resume directory", err)
+		}
+		if err := os.WriteFile(staged, nil, 0o600); err != nil {
+			return "", newBackendError(500, "failed to open download output", err)
+		}
+	}
+	// Journal write failures only cost the ability to resume.
+	_ = journal.save(&ResumeEntry{OID: oid, Operation: resumeDownload, BytesDone: offset, Chunks: []string{}, StagedPath: staged})
+
+	if err := b.bridge.DownloadFrom(b.operationCredentials(), oid, staged, offset, progress); err != nil {
+		err = mapBridgeError(err, "drive-cli download failed")
+		if isNotFound(err) {
+			// Nothing to resume: the object is missing or stored as chunks.
+			journal.remove(resumeDownload, oid)
+			_ = os.Remove(staged)
+		}
+		return "", err
+	}
+	if offset > 0 {
+		hash, _, err := calculateFileSHA256(staged)
+		if err != nil || hash != oid {
+			// Start over on the next attempt rather than resume bad data.
+			journal.remove(resumeDownload, oid)
+			_ = os.Remove(staged)
+			if err == nil {
+				err = errHashMismatch
+			}
+			return "", newBackendError(500, "resumed download failed verification", err)
+		}
+	}
+	journal.remove(resumeDownload, oid)
+	return staged, nil
+}
+
+// downloadChunkedObject reassembles a chunked object and returns the path it
+// was staged at. When the object has no manifest either, notFound (the
+// whole-object error) is returned.
+func (b *DriveCLIBackend) downloadChunkedObject(journal *resumeJournal, oid string, progress ProgressFunc, notFound error) (string, error) {
+	manifest, err := b.downloadChunkManifest(oid)
+	if err != nil {
+		if isNotFound(err) {
+			return "", notFound
+		}
+		return "", err
+	}
+	if journal != nil {
+		return b.downloadChunked(journal, manifest, "", progress)
+	}
+	tmpFile, err := os.CreateTemp("", "git-lfs-proton-download-*")
+	if err != nil {
+		return "", newBackendError(500, "failed to create temporary download file", err)
+	}
+	tmpPath := tmpFile.Name()
+	_ = tmpFile.Close()
+	staged, err := b.downloadChunked(nil, manifest, tmpPath, progress)
 	if err != nil {
 		_ = os.Remove(tmpPath)
-		if errors.Is(err, os.ErrNotExist) {
-			return "", 0, newBackendError(500, "drive-cli backend did not materialize download output", err)
+	}
+	return staged, err
+}
+
+// lockJournal returns the resume journal with the lock of op on oid held,
+// and the function that releases the lock. Without a journal, or while
+// another process transfers the same object, it returns a nil journal and
+// the transfer is not journaled.
+func (b *DriveCLIBackend) lockJournal(op, oid string) (*resumeJournal, func()) {
+	lock := b.resume.lock(op, oid)
+	if lock == nil {
+		return nil, func() {}
+	}
+	return b.resume, lock.Release
+}
+
+// SetChunking sets the size above which objects are uploaded in chunks and
+// the size of each chunk, both in bytes. Non-positive values keep the default.
+func (b *DriveCLIBackend) SetChunking(threshold, chunkSize int64) {
+	if threshold > 0 {
+		b.chunkThreshold = threshold
+	}
+	if chunkSize > 0 {
+		b.chunkSize = chunkSize
+	}
+}
+
+// SetResumeJournal enables resuming interrupted transfers.
+func (b *DriveCLIBackend) SetResumeJournal(journal *resumeJournal) {
+	b.resume = journal
+}
+
+// SetCache enables the local read-through object cache.
+func (b *DriveCLIBackend) SetCache(cache *objectCache) {
+	b.cache = cache
+}
+
+// cacheObject adds a transferred object to the cache. Content that does not
+// hash to oid is not cached, and cache failures never fail the transfer.
+func (b *DriveCLIBackend) cacheObject(oid, path string) {
+	if b.cache != nil {
+		_ = b.cache.store(oid, path)
+	}
+}
+
+// ListObjects returns every object under the Proton Drive storage base.
+func (b *DriveCLIBackend) ListObjects(session *Session) ([]RemoteObject, error) {
+	if err := b.ready(session); err != nil {
+		return nil, err
+	}
+	listed, err := b.bridge.List(b.operationCredentials())
+	if err != nil {
+		return nil, mapBridgeError(err, "drive-cli list failed")
+	}
+	objects := make([]RemoteObject, 0, len(listed))
+	for _, obj := range listed {
+		obj.OID = strings.ToLower(obj.OID)
+		if oidPattern.MatchString(obj.OID) {
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// ExistsObjects reports which of oids are stored in Proton Drive, using
+// batch-exists for at most maxExistsBatch OIDs per bridge command.
+func (b *DriveCLIBackend) ExistsObjects(session *Session, oids []string) (map[string]bool, error) {
+	if err := b.ready(session); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(oids))
+	for start := 0; start < len(oids); start += maxExistsBatch {
+		end := min(start+maxExistsBatch, len(oids))
+		found, err := b.bridge.BatchExists(b.operationCredentials(), oids[start:end])
+		if err != nil {
+			return result, mapBridgeError(err, "drive-cli batch-exists failed")
+		}
+		for _, oid := range oids[start:end] {
+			result[oid] = found[oid]
 		}
-		return "", 0, newBackendError(500, "failed to stat downloaded object", err)
 	}
+	return result, nil
+}
 
-	return tmpPath, info.Size(), nil
+// DeleteObjects removes objects from Proton Drive with batch-delete, at most
+// maxExistsBatch OIDs per bridge command.
+func (b *DriveCLIBackend) DeleteObjects(session *Session, oids []string) (map[string]bool, error) {
+	if err := b.ready(session); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(oids))
+	for start := 0; start < len(oids); start += maxExistsBatch {
+		end := min(start+maxExistsBatch, len(oids))
+		deleted, err := b.bridge.BatchDelete(b.operationCredentials(), oids[start:end])
+		if err != nil {
+			return result, mapBridgeError(err, "drive-cli batch-delete failed")
+		}
+		for _, oid := range oids[start:end] {
+			result[oid] = deleted[oid]
+			if deleted[oid] {
+				b.rememberExists(oid, false)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ready checks the preconditions shared by every drive-cli operation.
+func (b *DriveCLIBackend) ready(session *Session) error {
+	if session == nil || !session.Initialized {
+		return newBackendError(500, "session not initialized", nil)
+	}
+	if !b.authenticated {
+		return newBackendError(401, "drive-cli backend is not authenticated", nil)
+	}
+	if b.bridge == nil {
+		return newBackendError(500, "drive-cli backend bridge is not configured", nil)
+	}
+	return nil
+}
+
+// SetConcurrency makes sure the bridge allows at least n concurrent commands.
+// Chunk transfers share the same limit.
+func (b *DriveCLIBackend) SetConcurrency(n int) {
+	if b.bridge != nil {
+		b.bridge.EnsureCapacity(n)
+	}
+}
+
+// Close releases the bridge, stopping its persistent daemon if one is running.
+func (b *DriveCLIBackend) Close() error {
+	b.authenticated = false
+	if b.bridge == nil {
+		return nil
+	}
+	return b.bridge.Close()
 }
 
 // mapBridgeError converts bridge subprocess errors into BackendErrors with
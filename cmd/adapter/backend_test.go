@@ -38,7 +38,7 @@ func TestDriveCLIBackendRoundTrip(t *testing.T) {
 	}
 
 	// Upload
-	uploadedSize, err := backend.Upload(session, oid, uploadPath, int64(len(payload)))
+	uploadedSize, err := backend.Upload(session, oid, uploadPath, int64(len(payload)), nil)
 	if err != nil {
 		t.Fatalf("Upload returned error: %v", err)
 	}
@@ -47,7 +47,7 @@ func TestDriveCLIBackendRoundTrip(t *testing.T) {
 	}
 
 	// Download
-	downloadPath, downloadedSize, err := backend.Download(session, oid)
+	downloadPath, downloadedSize, err := backend.Download(session, oid, nil)
 	if err != nil {
 		t.Fatalf("Download returned error: %v", err)
 	}
@@ -89,7 +89,7 @@ func TestDriveCLIBackendUploadMapsNotFoundError(t *testing.T) {
 
 	session := &Session{Initialized: true, Token: "direct-bridge"}
 
-	_, err := backend.Upload(session, validOID, "/tmp/does-not-exist", 0)
+	_, err := backend.Upload(session, validOID, "/tmp/does-not-exist", 0, nil)
 	code, _ := backendErrorDetails(err)
 	if code != 404 {
 		t.Fatalf("expected mapped not-found code 404, got %d (%v)", code, err)
@@ -106,7 +106,7 @@ func TestDriveCLIBackendDownloadMapsAuthErrorAndCleansOutput(t *testing.T) {
 
 	session := &Session{Initialized: true, Token: "direct-bridge"}
 
-	_, _, err := backend.Download(session, validOID)
+	_, _, err := backend.Download(session, validOID, nil)
 	code, _ := backendErrorDetails(err)
 	if code != 401 {
 		t.Fatalf("expected mapped auth code 401, got %d (%v)", code, err)
@@ -130,13 +130,19 @@ func TestDriveCLIBackendUploadDedup(t *testing.T) {
 
 	session := &Session{Initialized: true, Token: "direct-bridge"}
 
-	size, err := backend.Upload(session, oid, uploadPath, int64(len(payload)))
+	size, err := backend.Upload(session, oid, uploadPath, int64(len(payload)), nil)
 	if err != nil {
 		t.Fatalf("Upload should succeed with dedup: %v", err)
 	}
 	if size != int64(len(payload)) {
 		t.Fatalf("unexpected size: %d", size)
 	}
+	if !backend.TakeDeduplicated(oid) {
+		t.Fatal("expected the skipped upload to be reported as deduplicated")
+	}
+	if backend.TakeDeduplicated(oid) {
+		t.Fatal("TakeDeduplicated must report a skip only once")
+	}
 }
 
 func TestDriveCLIBackendGitCredentialMode(t *testing.T) {
@@ -198,7 +204,7 @@ func TestDriveCLIBackendUploadNotAuthenticated(t *testing.T) {
 	// NOT authenticated
 
 	session := &Session{Initialized: true, Token: "direct-bridge"}
-	_, err := backend.Upload(session, validOID, "/tmp/test", 0)
+	_, err := backend.Upload(session, validOID, "/tmp/test", 0, nil)
 	code, _ := backendErrorDetails(err)
 	if code != 401 {
 		t.Fatalf("expected 401, got %d (%v)", code, err)
@@ -211,7 +217,7 @@ func TestDriveCLIBackendDownloadNotAuthenticated(t *testing.T) {
 	// NOT authenticated
 
 	session := &Session{Initialized: true, Token: "direct-bridge"}
-	_, _, err := backend.Download(session, validOID)
+	_, _, err := backend.Download(session, validOID, nil)
 	code, _ := backendErrorDetails(err)
 	if code != 401 {
 		t.Fatalf("expected 401, got %d (%v)", code, err)
@@ -383,7 +389,7 @@ func TestLocalStoreBackendDownloadNotFound(t *testing.T) {
 	b := NewLocalStoreBackend(t.TempDir())
 	session := &Session{Initialized: true}
 
-	_, _, err := b.Download(session, validOID)
+	_, _, err := b.Download(session, validOID, nil)
 	if err == nil {
 		t.Fatal("expected error for missing object")
 	}
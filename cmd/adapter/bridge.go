@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"proton-lfs-cli/internal/logging"
+	"proton-lfs-cli/internal/metrics"
+	"proton-lfs-cli/internal/slots"
 )
 
 // BridgeResponse is the JSON envelope returned by proton-drive-cli bridge commands.
@@ -20,14 +27,44 @@ type BridgeResponse struct {
 	Details string          `json:"details,omitempty"`
 }
 
+// bridgeProgressType marks an incremental progress line on bridge stdout.
+const bridgeProgressType = "progress"
+
+// BridgeProgress is an incremental progress line streamed on stdout by
+// long-running bridge commands (upload, download) before the final envelope.
+// Bridges only emit these when the request carries "progress": true.
+type BridgeProgress struct {
+	Type       string `json:"type"`
+	BytesSoFar int64  `json:"bytesSoFar"`
+	TotalBytes int64  `json:"totalBytes,omitempty"`
+}
+
+// bridgeCapabilitiesCommand asks proton-drive-cli which optional features
+// its bridge supports. Features that need a newer proton-drive-cli are only
+// used when it reports them; a bridge without the command reports none.
+const bridgeCapabilitiesCommand = "capabilities"
+
+// Bridge capabilities.
+const (
+	bridgeCapProgress = "progress" // "progress": true streams progress lines
+	bridgeCapServe    = "serve"    // `bridge serve` with ping and cancel
+	bridgeCapList     = "list"     // `bridge list`
+	bridgeCapChunks   = "chunks"   // part, offset and length in upload, download and exists
+	bridgeCapRange    = "range"    // offset in whole-object download
+)
+
 // BridgeClientConfig holds the configuration for creating a new BridgeClient.
 type BridgeClientConfig struct {
 	NodeBin       string
 	CLIBin        string
 	Timeout       time.Duration
-	MaxConcurrent int
+	MaxConcurrent int           // also the machine-wide limit with SlotDir
+	QueueTimeout  time.Duration // how long a command waits for a free slot
+	SlotDir       string        // slot lock files shared by all adapters; empty limits this process only
 	StorageBase   string
 	AppVersion    string
+	Persistent    bool     // use one long-lived `bridge serve` process
+	SessionDir    string   // proton-drive-cli session directory of the profile
 	ExtraEnv      []string // additional env vars (for testing)
 }
 
@@ -36,11 +73,29 @@ type BridgeClient struct {
 	nodeBin       string
 	cliBin        string
 	timeout       time.Duration
+	semMu         sync.Mutex
 	maxConcurrent int
 	semaphore     chan struct{}
+	queueTimeout  time.Duration
+	slots         *slots.Dir // nil when only this process is limited
+	slotsErr      error      // why the slot directory could not be opened
+	machineLimit  int        // machine-wide slots; EnsureCapacity never raises it
 	storageBase   string
 	appVersion    string
+	persistent    bool
+	sessionDir    string
 	extraEnv      []string
+
+	daemonMu       sync.Mutex
+	running        *bridgeDaemon
+	healthInterval time.Duration // how often a running daemon is pinged
+
+	capsOnce     sync.Once
+	capabilities map[string]bool
+
+	// metrics counts spawned processes, exits and command durations; nil
+	// records nothing.
+	metrics *metrics.Registry
 }
 
 // NewBridgeClient creates a new bridge subprocess client.
@@ -49,24 +104,58 @@ func NewBridgeClient(cfg BridgeClientConfig) *BridgeClient {
 		cfg.NodeBin = resolveNodeBinary()
 	}
 	if cfg.Timeout <= 0 {
-		cfg.Timeout = 5 * time.Minute
+		cfg.Timeout = DefaultBridgeTimeout
 	}
 	if cfg.MaxConcurrent <= 0 {
-		cfg.MaxConcurrent = 10
+		cfg.MaxConcurrent = DefaultBridgeConcurrency
+	}
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = DefaultBridgeQueueTimeout
 	}
 	if cfg.StorageBase == "" {
 		cfg.StorageBase = DefaultStorageBase
 	}
-	return &BridgeClient{
+	bc := &BridgeClient{
 		nodeBin:       cfg.NodeBin,
 		cliBin:        cfg.CLIBin,
 		timeout:       cfg.Timeout,
 		maxConcurrent: cfg.MaxConcurrent,
 		semaphore:     make(chan struct{}, cfg.MaxConcurrent),
+		machineLimit:  cfg.MaxConcurrent,
+		queueTimeout:  cfg.QueueTimeout,
 		storageBase:   cfg.StorageBase,
 		appVersion:    cfg.AppVersion,
+		persistent:    cfg.Persistent,
+		sessionDir:    cfg.SessionDir,
 		extraEnv:      cfg.ExtraEnv,
+
+		healthInterval: bridgeDaemonHealthInterval,
+	}
+	if cfg.SlotDir != "" {
+		// acquireSlot reports an unusable slot directory, rather than
+		// running commands without the machine-wide limit.
+		bc.slots, bc.slotsErr = slots.Open(cfg.SlotDir)
 	}
+	return bc
+}
+
+// EnsureCapacity raises this process's concurrency limit to at least n.
+// Commands already running keep the slot they acquired from the previous
+// semaphore. The machine-wide limit stays at the configured concurrency, so
+// that one adapter with many workers cannot lift it for every other one.
+func (bc *BridgeClient) EnsureCapacity(n int) {
+	bc.semMu.Lock()
+	defer bc.semMu.Unlock()
+	if n <= bc.maxConcurrent {
+		return
+	}
+	bc.maxConcurrent = n
+	bc.semaphore = make(chan struct{}, n)
+}
+
+// SetMetrics records bridge processes and commands in r.
+func (bc *BridgeClient) SetMetrics(r *metrics.Registry) {
+	bc.metrics = r
 }
 
 // envAllowlist lists environment variable prefixes and exact names that are
@@ -110,6 +199,9 @@ func (bc *BridgeClient) filteredEnv() []string {
 			filtered = append(filtered, e)
 		}
 	}
+	if bc.sessionDir != "" {
+		filtered = append(filtered, EnvDriveCLISessionDir+"="+bc.sessionDir)
+	}
 	filtered = append(filtered, bc.extraEnv...)
 	return filtered
 }
@@ -130,20 +222,122 @@ func matchesAllowlist(key string) bool {
 
 // runBridgeCommand executes a proton-drive-cli bridge command as a subprocess.
 func (bc *BridgeClient) runBridgeCommand(command string, request map[string]any) (*BridgeResponse, error) {
-	// Non-blocking semaphore acquire
+	return bc.runBridgeCommandWithProgress(command, request, nil)
+}
+
+// runBridgeCommandWithProgress executes a bridge command and, when onProgress
+// is non-nil, asks the bridge to stream progress lines and forwards each one
+// to onProgress while the command is still running. Commands go to the
+// persistent daemon when one is configured, otherwise to a fresh subprocess.
+func (bc *BridgeClient) runBridgeCommandWithProgress(command string, request map[string]any, onProgress ProgressFunc) (*BridgeResponse, error) {
+	// Ask for the capabilities before taking a slot: the probe needs one.
+	if onProgress != nil && !bc.supports(bridgeCapProgress) {
+		onProgress = nil
+	}
+	persistent := bc.persistent && bc.supports(bridgeCapServe)
+
+	release, err := bc.acquireSlot()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	defer func() {
+		bc.metrics.Observe(metrics.BridgeCommandDuration, time.Since(start).Seconds(), "command", command)
+	}()
+	if persistent {
+		return bc.runDaemonCommand(command, request, onProgress)
+	}
+	return bc.runSubprocessCommand(command, request, onProgress)
+}
+
+// supports reports whether the bridge has capability. The bridge is asked
+// once, when a command first needs to know.
+func (bc *BridgeClient) supports(capability string) bool {
+	bc.capsOnce.Do(func() { bc.capabilities = bc.probeCapabilities() })
+	return bc.capabilities[capability]
+}
+
+// probeCapabilities runs `bridge capabilities` in a subprocess. A bridge that
+// fails it, such as one that predates the command, has no capabilities.
+func (bc *BridgeClient) probeCapabilities() map[string]bool {
+	caps := make(map[string]bool)
+	release, err := bc.acquireSlot()
+	if err != nil {
+		return caps
+	}
+	defer release()
+	resp, err := bc.runSubprocessCommand(bridgeCapabilitiesCommand, map[string]any{}, nil)
+	if err != nil {
+		return caps
+	}
+	var payload struct {
+		Capabilities []string `json:"capabilities"`
+	}
+	if err := json.Unmarshal(resp.Payload, &payload); err == nil {
+		for _, c := range payload.Capabilities {
+			caps[c] = true
+		}
+	}
+	return caps
+}
+
+// acquireSlot waits up to the queue timeout for a slot under this process's
+// limit and, with a slot directory, for one of the machine-wide slots that
+// all adapters share. The returned function releases both. A slot directory
+// that cannot be opened or locked fails the command.
+func (bc *BridgeClient) acquireSlot() (func(), error) {
+	if bc.slotsErr != nil {
+		return nil, fmt.Errorf("bridge concurrency slots: %w", bc.slotsErr)
+	}
+	bc.semMu.Lock()
+	semaphore, limit := bc.semaphore, bc.maxConcurrent
+	bc.semMu.Unlock()
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), bc.queueTimeout)
+	defer cancel()
 	select {
-	case bc.semaphore <- struct{}{}:
-		defer func() { <-bc.semaphore }()
-	default:
-		return nil, fmt.Errorf("bridge concurrency limit reached (%d)", bc.maxConcurrent)
+	case semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("bridge concurrency limit reached (%d): no free slot after %s", limit, bc.queueTimeout)
 	}
+	release := func() { <-semaphore }
+	if bc.slots != nil {
+		slot, err := bc.slots.Acquire(ctx, bc.machineLimit)
+		if err != nil {
+			<-semaphore
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("bridge concurrency limit reached (%d on this machine): no free slot after %s", bc.machineLimit, bc.queueTimeout)
+			}
+			return nil, fmt.Errorf("bridge concurrency slots: %w", err)
+		}
+		release = func() { slot.Release(); <-semaphore }
+	}
+	bc.metrics.Observe(metrics.BridgeQueueWait, time.Since(start).Seconds())
+	return release, nil
+}
 
+// recordExit counts a bridge process that ran command and exited. A process
+// killed by a signal or the timeout has exit code -1.
+func (bc *BridgeClient) recordExit(command string, state *os.ProcessState) {
+	if state != nil {
+		bc.metrics.Inc(metrics.BridgeExits, "command", command, "exit_code", strconv.Itoa(state.ExitCode()))
+	}
+}
+
+// runSubprocessCommand spawns `node <cli> bridge <command>` for a single request.
+func (bc *BridgeClient) runSubprocessCommand(command string, request map[string]any, onProgress ProgressFunc) (*BridgeResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), bc.timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, bc.nodeBin, bc.cliBin, "bridge", command)
 	cmd.Env = bc.filteredEnv()
 
+	if onProgress != nil {
+		request["progress"] = true
+	}
 	stdinBytes, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal bridge request: %w", err)
@@ -152,9 +346,16 @@ func (bc *BridgeClient) runBridgeCommand(command string, request map[string]any)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
+	if onProgress != nil {
+		cmd.Stdout = &progressLineWriter{buf: &stdout, onProgress: onProgress}
+	}
 	cmd.Stderr = &stderr
 
 	err = cmd.Run()
+	if cmd.ProcessState != nil {
+		bc.metrics.Inc(metrics.BridgeSpawns, "mode", BridgeModeSubprocess)
+		bc.recordExit(command, cmd.ProcessState)
+	}
 
 	resp, parseErr := parseBridgeOutput(stdout.Bytes(), stderr.Bytes())
 	if parseErr != nil {
@@ -168,6 +369,11 @@ func (bc *BridgeClient) runBridgeCommand(command string, request map[string]any)
 		return nil, fmt.Errorf("bridge %s: %w", command, parseErr)
 	}
 
+	return checkBridgeResponse(resp)
+}
+
+// checkBridgeResponse turns a failed envelope into a "[code] message" error.
+func checkBridgeResponse(resp *BridgeResponse) (*BridgeResponse, error) {
 	if !resp.OK {
 		errMsg := resp.Error
 		if errMsg == "" {
@@ -185,6 +391,49 @@ func (bc *BridgeClient) runBridgeCommand(command string, request map[string]any)
 	return resp, nil
 }
 
+// progressLineWriter captures bridge stdout while scanning complete lines for
+// streamed progress updates.
+type progressLineWriter struct {
+	mu         sync.Mutex
+	buf        *bytes.Buffer
+	pending    []byte
+	onProgress ProgressFunc
+}
+
+func (w *progressLineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.pending[:idx]
+		w.pending = w.pending[idx+1:]
+		if progress, ok := parseProgressLine(line); ok {
+			w.onProgress(progress.BytesSoFar)
+		}
+	}
+	return len(p), nil
+}
+
+// parseProgressLine decodes a streamed progress line. It returns false for
+// anything else, including the final response envelope.
+func parseProgressLine(line []byte) (BridgeProgress, bool) {
+	var progress BridgeProgress
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return progress, false
+	}
+	if err := json.Unmarshal(line, &progress); err != nil || progress.Type != bridgeProgressType {
+		return progress, false
+	}
+	return progress, true
+}
+
 // sanitizeStderr strips sensitive data (tokens, paths, session info) from
 // subprocess stderr before surfacing it in error messages.
 func sanitizeStderr(raw string) string {
@@ -198,18 +447,12 @@ func sanitizeStderr(raw string) string {
 		s = s[:maxLen] + "..."
 	}
 	// Redact anything that looks like a token, session ID, or bearer header
-	for _, pattern := range []string{"Bearer ", "token=", "session=", "AccessToken", "RefreshToken", "UID:"} {
-		if idx := strings.Index(s, pattern); idx >= 0 {
-			// Truncate from the sensitive prefix onward
-			s = s[:idx] + "[redacted]"
-			break
-		}
-	}
-	return s
+	return logging.Redact(s)
 }
 
 // parseBridgeOutput extracts a JSON envelope from stdout, tolerating non-JSON
-// noise (e.g. debug logging) by scanning from the last line backwards.
+// noise (e.g. debug logging) and streamed progress lines by scanning from the
+// last line backwards.
 func parseBridgeOutput(stdout, _ []byte) (*BridgeResponse, error) {
 	trimmed := bytes.TrimSpace(stdout)
 	if len(trimmed) == 0 {
@@ -218,8 +461,10 @@ func parseBridgeOutput(stdout, _ []byte) (*BridgeResponse, error) {
 
 	// Try the entire stdout first (fast path)
 	var resp BridgeResponse
-	if err := json.Unmarshal(trimmed, &resp); err == nil {
-		return &resp, nil
+	if _, isProgress := parseProgressLine(trimmed); !isProgress {
+		if err := json.Unmarshal(trimmed, &resp); err == nil {
+			return &resp, nil
+		}
 	}
 
 	// Scan lines from end looking for a JSON object
@@ -229,6 +474,9 @@ func parseBridgeOutput(stdout, _ []byte) (*BridgeResponse, error) {
 		if len(line) == 0 || line[0] != '{' {
 			continue
 		}
+		if _, isProgress := parseProgressLine(line); isProgress {
+			continue
+		}
 		var lineResp BridgeResponse
 		if err := json.Unmarshal(line, &lineResp); err == nil {
 			return &lineResp, nil
@@ -245,6 +493,12 @@ func buildCredentials(creds OperationCredentials, storageBase, appVersion string
 	if creds.CredentialProvider != "" {
 		m["credentialProvider"] = creds.CredentialProvider
 	}
+	if creds.Profile != "" {
+		m["profile"] = creds.Profile
+	}
+	if creds.Account != "" {
+		m["account"] = creds.Account
+	}
 	if storageBase != "" {
 		m["storageBase"] = storageBase
 	}
@@ -269,20 +523,61 @@ func (bc *BridgeClient) InitLFSStorage(creds OperationCredentials) error {
 }
 
 // Upload runs `bridge upload` to encrypt and store a file in Proton Drive.
-func (bc *BridgeClient) Upload(creds OperationCredentials, oid, filePath string) error {
+// If onProgress is non-nil it receives streamed byte counts during the upload.
+func (bc *BridgeClient) Upload(creds OperationCredentials, oid, filePath string, onProgress ProgressFunc) error {
 	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
 	req["oid"] = oid
 	req["path"] = filePath
-	_, err := bc.runBridgeCommand("upload", req)
+	_, err := bc.runBridgeCommandWithProgress("upload", req, onProgress)
 	return err
 }
 
 // Download runs `bridge download` to decrypt and retrieve a file from Proton Drive.
-func (bc *BridgeClient) Download(creds OperationCredentials, oid, outputPath string) error {
+// If onProgress is non-nil it receives streamed byte counts during the download.
+func (bc *BridgeClient) Download(creds OperationCredentials, oid, outputPath string, onProgress ProgressFunc) error {
+	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
+	req["oid"] = oid
+	req["outputPath"] = outputPath
+	_, err := bc.runBridgeCommandWithProgress("download", req, onProgress)
+	return err
+}
+
+// DownloadFrom runs `bridge download` for the bytes of oid from offset on.
+// They are written at the same offset of outputPath, which already holds the
+// first offset bytes, and progress counts those bytes as done.
+func (bc *BridgeClient) DownloadFrom(creds OperationCredentials, oid, outputPath string, offset int64, onProgress ProgressFunc) error {
+	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
+	req["oid"] = oid
+	req["outputPath"] = outputPath
+	req["offset"] = offset
+	progress := onProgress
+	if onProgress != nil && offset > 0 {
+		progress = func(bytes int64) { onProgress(offset + bytes) }
+	}
+	_, err := bc.runBridgeCommandWithProgress("download", req, progress)
+	return err
+}
+
+// UploadPart runs `bridge upload` for length bytes of filePath starting at
+// offset, storing them as part under the OID's folder.
+func (bc *BridgeClient) UploadPart(creds OperationCredentials, oid, part, filePath string, offset, length int64, onProgress ProgressFunc) error {
+	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
+	req["oid"] = oid
+	req["part"] = part
+	req["path"] = filePath
+	req["offset"] = offset
+	req["length"] = length
+	_, err := bc.runBridgeCommandWithProgress("upload", req, onProgress)
+	return err
+}
+
+// DownloadPart runs `bridge download` for one part stored under an OID's folder.
+func (bc *BridgeClient) DownloadPart(creds OperationCredentials, oid, part, outputPath string, onProgress ProgressFunc) error {
 	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
 	req["oid"] = oid
+	req["part"] = part
 	req["outputPath"] = outputPath
-	_, err := bc.runBridgeCommand("download", req)
+	_, err := bc.runBridgeCommandWithProgress("download", req, onProgress)
 	return err
 }
 
@@ -290,6 +585,18 @@ func (bc *BridgeClient) Download(creds OperationCredentials, oid, outputPath str
 func (bc *BridgeClient) Exists(creds OperationCredentials, oid string) (bool, error) {
 	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
 	req["oid"] = oid
+	return bc.exists(req)
+}
+
+// ExistsPart runs `bridge exists` for one part stored under an OID's folder.
+func (bc *BridgeClient) ExistsPart(creds OperationCredentials, oid, part string) (bool, error) {
+	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
+	req["oid"] = oid
+	req["part"] = part
+	return bc.exists(req)
+}
+
+func (bc *BridgeClient) exists(req map[string]any) (bool, error) {
 	resp, err := bc.runBridgeCommand("exists", req)
 	if err != nil {
 		// A 404 error means the object does not exist — not a failure.
@@ -354,6 +661,38 @@ func (bc *BridgeClient) BatchDelete(creds OperationCredentials, oids []string) (
 	return result, nil
 }
 
+// RemoteObject is one object stored under the LFS storage base.
+type RemoteObject struct {
+	OID     string    `json:"oid"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modifiedAt,omitzero"` // zero when the bridge does not report it
+}
+
+// errListUnsupported is returned by List when the bridge lacks the list
+// capability.
+var errListUnsupported = errors.New("bridge list failed: proton-drive-cli is too old to list stored objects, update it")
+
+// List runs `bridge list` to enumerate every object under the storage base.
+func (bc *BridgeClient) List(creds OperationCredentials) ([]RemoteObject, error) {
+	if !bc.supports(bridgeCapList) {
+		return nil, errListUnsupported
+	}
+	req := buildCredentials(creds, bc.storageBase, bc.appVersion)
+	resp, err := bc.runBridgeCommand("list", req)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Objects []RemoteObject `json:"objects"`
+	}
+	if len(resp.Payload) > 0 {
+		if err := json.Unmarshal(resp.Payload, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse list response: %w", err)
+		}
+	}
+	return result.Objects, nil
+}
+
 // resolveNodeBinary returns the path to the Node.js binary.
 func resolveNodeBinary() string {
 	if bin := os.Getenv("NODE_BIN"); bin != "" {
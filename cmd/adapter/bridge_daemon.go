@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"proton-lfs-cli/internal/metrics"
+)
+
+// bridgeServeCommand starts proton-drive-cli in persistent mode. The process
+// reads one JSON request per line on stdin and answers with JSON lines on
+// stdout tagged with the request ID.
+const bridgeServeCommand = "serve"
+
+// bridgePingCommand is the health-check command understood by `bridge serve`.
+const bridgePingCommand = "ping"
+
+// bridgeCancelCommand asks `bridge serve` to abort the request whose ID is
+// in the request's "id" field. The daemon answers nothing for the aborted
+// request.
+const bridgeCancelCommand = "cancel"
+
+// bridgeDaemonHealthInterval is how often a running daemon is pinged. A
+// daemon that does not answer within one interval is killed and restarted on
+// the next request.
+const bridgeDaemonHealthInterval = 30 * time.Second
+
+// bridgeDaemonShutdownGrace is how long Close waits for the daemon to exit
+// after its stdin is closed before killing it.
+const bridgeDaemonShutdownGrace = 5 * time.Second
+
+// errBridgeDaemonExited is returned for requests that were in flight when the
+// daemon process died.
+var errBridgeDaemonExited = errors.New("bridge daemon exited")
+
+// BridgeDaemonRequest is one framed request sent to `bridge serve`.
+type BridgeDaemonRequest struct {
+	ID      uint64         `json:"id"`
+	Command string         `json:"command"`
+	Request map[string]any `json:"request,omitempty"`
+}
+
+// bridgeDaemonFrame is one line read from `bridge serve`. It is either a
+// progress update (Type == "progress") or the final response envelope for ID.
+type bridgeDaemonFrame struct {
+	ID uint64 `json:"id"`
+	BridgeResponse
+	Type       string `json:"type,omitempty"`
+	BytesSoFar int64  `json:"bytesSoFar,omitempty"`
+}
+
+// pendingBridgeCall tracks one in-flight daemon request.
+type pendingBridgeCall struct {
+	done       chan *BridgeResponse
+	onProgress ProgressFunc
+}
+
+// bridgeDaemon is a long-lived `proton-drive-cli bridge serve` process shared
+// by all bridge commands of one adapter session.
+type bridgeDaemon struct {
+	client *BridgeClient
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *lockedBuffer
+	exited chan struct{}
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingBridgeCall
+	dead    bool
+}
+
+// startBridgeDaemon spawns `bridge serve` and waits for a successful ping.
+func (bc *BridgeClient) startBridgeDaemon() (*bridgeDaemon, error) {
+	cmd := exec.Command(bc.nodeBin, bc.cliBin, "bridge", bridgeServeCommand)
+	cmd.Env = bc.filteredEnv()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("bridge daemon: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("bridge daemon: %w", err)
+	}
+	stderr := &lockedBuffer{buf: new(bytes.Buffer)}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start bridge daemon: %w", err)
+	}
+	bc.metrics.Inc(metrics.BridgeSpawns, "mode", BridgeModeDaemon)
+
+	d := &bridgeDaemon{
+		client:  bc,
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  stderr,
+		exited:  make(chan struct{}),
+		pending: make(map[uint64]*pendingBridgeCall),
+	}
+	go d.readLoop(stdout)
+
+	if err := d.ping(bc.timeout); err != nil {
+		d.kill()
+		return nil, fmt.Errorf("bridge daemon health check failed: %w", err)
+	}
+	go d.healthLoop(bc.healthInterval)
+	return d, nil
+}
+
+// ping sends the health-check command and waits up to timeout for it.
+func (d *bridgeDaemon) ping(timeout time.Duration) error {
+	resp, err := d.call(bridgePingCommand, nil, nil, timeout)
+	if err == nil {
+		_, err = checkBridgeResponse(resp)
+	}
+	return err
+}
+
+// healthLoop pings the daemon every interval until it exits, and kills it
+// when a ping fails. Requests still waiting on a killed daemon fail, and the
+// next request starts a fresh one.
+func (d *bridgeDaemon) healthLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.exited:
+			return
+		case <-ticker.C:
+		}
+		if err := d.ping(interval); err != nil && !d.isDead() {
+			d.kill()
+			return
+		}
+	}
+}
+
+// readLoop dispatches stdout frames to their pending calls until the daemon
+// exits, then fails every call still waiting.
+func (d *bridgeDaemon) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var frame bridgeDaemonFrame
+		if err := json.Unmarshal(line, &frame); err != nil || frame.ID == 0 {
+			continue
+		}
+
+		d.mu.Lock()
+		call := d.pending[frame.ID]
+		if call != nil && frame.Type != bridgeProgressType {
+			delete(d.pending, frame.ID)
+		}
+		d.mu.Unlock()
+		if call == nil {
+			continue
+		}
+
+		if frame.Type == bridgeProgressType {
+			if call.onProgress != nil {
+				call.onProgress(frame.BytesSoFar)
+			}
+			continue
+		}
+		resp := frame.BridgeResponse
+		call.done <- &resp
+	}
+
+	_ = d.cmd.Wait()
+	d.client.recordExit(bridgeServeCommand, d.cmd.ProcessState)
+
+	d.mu.Lock()
+	d.dead = true
+	for id, call := range d.pending {
+		delete(d.pending, id)
+		close(call.done)
+	}
+	d.mu.Unlock()
+	close(d.exited)
+}
+
+// call sends one request and waits for its response. A nil response with
+// errBridgeDaemonExited means the daemon died before answering.
+func (d *bridgeDaemon) call(command string, request map[string]any, onProgress ProgressFunc, timeout time.Duration) (*BridgeResponse, error) {
+	d.mu.Lock()
+	if d.dead {
+		d.mu.Unlock()
+		return nil, errBridgeDaemonExited
+	}
+	d.nextID++
+	id := d.nextID
+	call := &pendingBridgeCall{done: make(chan *BridgeResponse, 1), onProgress: onProgress}
+	d.pending[id] = call
+	d.mu.Unlock()
+
+	line, err := json.Marshal(BridgeDaemonRequest{ID: id, Command: command, Request: request})
+	if err != nil {
+		d.forget(id)
+		return nil, fmt.Errorf("failed to marshal bridge request: %w", err)
+	}
+	line = append(line, '\n')
+
+	d.writeMu.Lock()
+	_, err = d.stdin.Write(line)
+	d.writeMu.Unlock()
+	if err != nil {
+		d.forget(id)
+		return nil, errBridgeDaemonExited
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp, ok := <-call.done:
+		if !ok {
+			return nil, errBridgeDaemonExited
+		}
+		return resp, nil
+	case <-timer.C:
+		// Abort only this request: the daemon keeps serving the others. A
+		// daemon that stopped answering altogether fails its health check.
+		d.forget(id)
+		d.cancel(id)
+		return nil, fmt.Errorf("bridge %s timed out after %s", command, timeout)
+	}
+}
+
+// cancel asks the daemon to abort request id without waiting for an answer.
+func (d *bridgeDaemon) cancel(id uint64) {
+	d.mu.Lock()
+	d.nextID++
+	cancelID := d.nextID
+	d.mu.Unlock()
+
+	line, err := json.Marshal(BridgeDaemonRequest{ID: cancelID, Command: bridgeCancelCommand, Request: map[string]any{"id": id}})
+	if err != nil {
+		return
+	}
+	d.writeMu.Lock()
+	_, _ = d.stdin.Write(append(line, '\n'))
+	d.writeMu.Unlock()
+}
+
+func (d *bridgeDaemon) forget(id uint64) {
+	d.mu.Lock()
+	delete(d.pending, id)
+	d.mu.Unlock()
+}
+
+func (d *bridgeDaemon) isDead() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dead
+}
+
+// stderrText returns the sanitized stderr captured from the daemon so far.
+func (d *bridgeDaemon) stderrText() string {
+	return sanitizeStderr(d.stderr.String())
+}
+
+func (d *bridgeDaemon) kill() {
+	if d.cmd.Process != nil {
+		_ = d.cmd.Process.Kill()
+	}
+}
+
+// close asks the daemon to exit by closing its stdin and kills it if it has
+// not exited within the grace period.
+func (d *bridgeDaemon) close() {
+	_ = d.stdin.Close()
+	select {
+	case <-d.exited:
+	case <-time.After(bridgeDaemonShutdownGrace):
+		d.kill()
+		<-d.exited
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent Write and String.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// daemon returns the running bridge daemon, starting or restarting it if it
+// is not running.
+func (bc *BridgeClient) daemon() (*bridgeDaemon, error) {
+	bc.daemonMu.Lock()
+	defer bc.daemonMu.Unlock()
+
+	if bc.running != nil && !bc.running.isDead() {
+		return bc.running, nil
+	}
+	d, err := bc.startBridgeDaemon()
+	if err != nil {
+		return nil, err
+	}
+	bc.running = d
+	return d, nil
+}
+
+// discardDaemon forgets d so the next request starts a fresh daemon, even if
+// the old process has not been reaped yet.
+func (bc *BridgeClient) discardDaemon(d *bridgeDaemon) {
+	bc.daemonMu.Lock()
+	if bc.running == d {
+		bc.running = nil
+	}
+	bc.daemonMu.Unlock()
+	d.kill()
+}
+
+// bridgeNoReplayCommands are not sent again after the daemon died without
+// answering them: the first attempt may already have taken effect.
+var bridgeNoReplayCommands = map[string]bool{
+	"batch-delete": true,
+}
+
+// runDaemonCommand sends a command to the persistent bridge. If the daemon
+// dies before answering, it is restarted and the command is sent once more,
+// unless it is listed in bridgeNoReplayCommands. The other commands are
+// idempotent per OID, so a single replay is safe.
+func (bc *BridgeClient) runDaemonCommand(command string, request map[string]any, onProgress ProgressFunc) (*BridgeResponse, error) {
+	if onProgress != nil {
+		request["progress"] = true
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		d, err := bc.daemon()
+		if err != nil {
+			return nil, fmt.Errorf("bridge %s failed: %w", command, err)
+		}
+		resp, err := d.call(command, request, onProgress, bc.timeout)
+		if errors.Is(err, errBridgeDaemonExited) {
+			bc.discardDaemon(d)
+			lastErr = err
+			if stderrText := d.stderrText(); stderrText != "" {
+				lastErr = fmt.Errorf("%w: %s", err, stderrText)
+			}
+			if bridgeNoReplayCommands[command] {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return checkBridgeResponse(resp)
+	}
+	return nil, fmt.Errorf("bridge %s failed: %w", command, lastErr)
+}
+
+// Ping runs the daemon health check. In subprocess mode, or when the bridge
+// cannot serve, it is a no-op.
+func (bc *BridgeClient) Ping() error {
+	if !bc.persistent || !bc.supports(bridgeCapServe) {
+		return nil
+	}
+	_, err := bc.runDaemonCommand(bridgePingCommand, map[string]any{}, nil)
+	return err
+}
+
+// Close stops the persistent bridge daemon, if one is running.
+func (bc *BridgeClient) Close() error {
+	bc.daemonMu.Lock()
+	d := bc.running
+	bc.running = nil
+	bc.daemonMu.Unlock()
+
+	if d != nil {
+		d.close()
+	}
+	return nil
+}
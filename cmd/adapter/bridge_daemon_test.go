@@ -0,0 +1,261 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// helperDaemonBridgeClient creates a persistent BridgeClient whose `bridge serve`
+// daemon is the test binary. Every spawn is appended to the returned log path.
+func helperDaemonBridgeClient(t *testing.T, extraEnv ...string) (*BridgeClient, string) {
+	t.Helper()
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	env := []string{"GO_TEST_HELPER_PROCESS=1", "MOCK_BRIDGE_SPAWN_LOG=" + spawnLog}
+	env = append(env, extraEnv...)
+	bc := NewBridgeClient(BridgeClientConfig{
+		NodeBin:       os.Args[0],
+		CLIBin:        "-test.run=TestHelperProcess",
+		Timeout:       10 * time.Second,
+		MaxConcurrent: 10,
+		StorageBase:   "LFS",
+		AppVersion:    "test-1.0",
+		Persistent:    true,
+		ExtraEnv:      env,
+	})
+	t.Cleanup(func() { _ = bc.Close() })
+	return bc, spawnLog
+}
+
+func countSpawns(t *testing.T, spawnLog string) int {
+	t.Helper()
+	data, err := os.ReadFile(spawnLog)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0
+		}
+		t.Fatalf("failed to read spawn log: %v", err)
+	}
+	return len(strings.Fields(string(data)))
+}
+
+func TestBridgeDaemonReusesSingleProcess(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t)
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	if err := bc.Authenticate(creds); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if err := bc.InitLFSStorage(creds); err != nil {
+		t.Fatalf("InitLFSStorage failed: %v", err)
+	}
+	exists, err := bc.Exists(creds, validOID)
+	if err != nil || !exists {
+		t.Fatalf("Exists = %v, %v; want true, nil", exists, err)
+	}
+	if err := bc.Upload(creds, validOID, "/tmp/test.bin", nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	if got := countSpawns(t, spawnLog); got != 1 {
+		t.Fatalf("expected 1 daemon spawn for 4 commands, got %d", got)
+	}
+}
+
+func TestBridgeDaemonStreamsProgress(t *testing.T) {
+	bc, _ := helperDaemonBridgeClient(t)
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	tmpPath := filepath.Join(t.TempDir(), "download.bin")
+
+	var seen []int64
+	if err := bc.Download(creds, validOID, tmpPath, func(n int64) { seen = append(seen, n) }); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	want := int64(len("mock-download-content"))
+	if len(seen) != 2 || seen[1] != want {
+		t.Fatalf("unexpected streamed progress: %v (want final %d)", seen, want)
+	}
+	data, err := os.ReadFile(tmpPath)
+	if err != nil || string(data) != "mock-download-content" {
+		t.Fatalf("unexpected download content: %q (%v)", data, err)
+	}
+}
+
+func TestBridgeDaemonErrorMapping(t *testing.T) {
+	bc, _ := helperDaemonBridgeClient(t, "MOCK_BRIDGE_ERROR=unauthorized", "MOCK_BRIDGE_ERROR_CODE=401")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	err := bc.Authenticate(creds)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "[401]") {
+		t.Fatalf("expected [401] in error, got: %v", err)
+	}
+}
+
+func TestBridgeDaemonRestartsAfterCrash(t *testing.T) {
+	// The daemon exits after answering the ping and one command.
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_SERVE_EXIT_AFTER=2")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	for i := 0; i < 3; i++ {
+		if err := bc.Authenticate(creds); err != nil {
+			t.Fatalf("Authenticate #%d failed: %v", i+1, err)
+		}
+	}
+	if got := countSpawns(t, spawnLog); got < 2 {
+		t.Fatalf("expected the daemon to be restarted, got %d spawns", got)
+	}
+}
+
+func TestBridgeDaemonDoesNotReplayBatchDelete(t *testing.T) {
+	// The daemon exits after answering the ping, so batch-delete is lost.
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_SERVE_EXIT_AFTER=1")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	if _, err := bc.BatchDelete(creds, []string{validOID}); err == nil {
+		t.Fatal("expected batch-delete to fail when the daemon dies")
+	}
+	if got := countSpawns(t, spawnLog); got != 1 {
+		t.Fatalf("expected batch-delete not to be replayed, got %d spawns", got)
+	}
+}
+
+func TestBridgeDaemonTimeoutAbortsOnlyThatRequest(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_DELAY=300ms")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	bc.timeout = 100 * time.Millisecond
+	if _, err := bc.Exists(creds, validOID); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	bc.timeout = 10 * time.Second
+	if exists, err := bc.Exists(creds, validOID); err != nil || !exists {
+		t.Fatalf("Exists after a timeout = %v, %v; want true, nil", exists, err)
+	}
+	if got := countSpawns(t, spawnLog); got != 1 {
+		t.Fatalf("expected the daemon to survive a timed-out request, got %d spawns", got)
+	}
+}
+
+func TestBridgeDaemonHealthCheckRestartsUnresponsiveDaemon(t *testing.T) {
+	// The daemon answers the startup ping and then ignores pings.
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_PING_ANSWERS=1")
+	bc.healthInterval = 50 * time.Millisecond
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	if err := bc.Authenticate(creds); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	d := bc.running
+	deadline := time.Now().Add(5 * time.Second)
+	for !d.isDead() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the failed health check to kill the daemon")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := bc.Authenticate(creds); err != nil {
+		t.Fatalf("Authenticate after restart failed: %v", err)
+	}
+	if got := countSpawns(t, spawnLog); got != 2 {
+		t.Fatalf("expected the daemon to be restarted, got %d spawns", got)
+	}
+}
+
+func TestBridgeDaemonConcurrentRequests(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_DELAY=50ms")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := bc.Exists(creds, validOID); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent Exists failed: %v", err)
+	}
+	if got := countSpawns(t, spawnLog); got != 1 {
+		t.Fatalf("expected 1 daemon spawn, got %d", got)
+	}
+}
+
+func TestBridgeDaemonPingAndClose(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t)
+
+	if err := bc.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if err := bc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if bc.running != nil {
+		t.Fatal("expected no running daemon after Close")
+	}
+
+	// A command after Close starts a fresh daemon.
+	if err := bc.Ping(); err != nil {
+		t.Fatalf("Ping after Close failed: %v", err)
+	}
+	if got := countSpawns(t, spawnLog); got != 2 {
+		t.Fatalf("expected 2 daemon spawns, got %d", got)
+	}
+}
+
+func TestBridgeDaemonNeedsBridgeSupport(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t, "MOCK_BRIDGE_CAPABILITIES=")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	if err := bc.Authenticate(creds); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got := spawnedCommands(t, spawnLog); len(got) != 1 || got[0] != "auth" {
+		t.Fatalf("a bridge without serve must run commands as subprocesses, got %v", got)
+	}
+	if err := bc.Ping(); err != nil {
+		t.Fatalf("Ping without serve should be a no-op: %v", err)
+	}
+}
+
+func TestBridgePingSubprocessModeIsNoop(t *testing.T) {
+	bc := helperBridgeClient(t)
+	if err := bc.Ping(); err != nil {
+		t.Fatalf("Ping in subprocess mode should be a no-op: %v", err)
+	}
+	if err := bc.Close(); err != nil {
+		t.Fatalf("Close in subprocess mode should be a no-op: %v", err)
+	}
+}
+
+func TestAdapterTerminateStopsBridgeDaemon(t *testing.T) {
+	bc, spawnLog := helperDaemonBridgeClient(t)
+	adapter := NewAdapter()
+	adapter.backend = NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+
+	input := strings.Join([]string{
+		`{"event":"init","operation":"upload","remote":"origin","concurrent":false,"concurrenttransfers":1}`,
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+
+	out := new(strings.Builder)
+	if err := adapter.Run(strings.NewReader(input), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := countSpawns(t, spawnLog); got != 1 {
+		t.Fatalf("expected init to use one daemon, got %d spawns", got)
+	}
+	if bc.running != nil {
+		t.Fatal("expected terminate to stop the bridge daemon")
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -33,6 +36,31 @@ func TestHelperProcess(_ *testing.T) {
 	}
 	command := args[bridgeIdx+1]
 
+	// MOCK_BRIDGE_CAPABILITIES lists the capabilities reported; all of them
+	// when unset. The probe is not recorded as a spawn, and fails like any
+	// other command with MOCK_BRIDGE_ERROR.
+	if command == bridgeCapabilitiesCommand && os.Getenv("MOCK_BRIDGE_ERROR") == "" {
+		caps := []string{bridgeCapProgress, bridgeCapServe, bridgeCapList, bridgeCapChunks, bridgeCapRange}
+		if v, ok := os.LookupEnv("MOCK_BRIDGE_CAPABILITIES"); ok {
+			caps = strings.FieldsFunc(v, func(r rune) bool { return r == ',' })
+		}
+		writeOKResponse(os.Stdout, map[string]any{"capabilities": caps})
+		return
+	}
+
+	// Record each spawn so tests can count subprocesses
+	if spawnLog := os.Getenv("MOCK_BRIDGE_SPAWN_LOG"); spawnLog != "" {
+		if f, err := os.OpenFile(spawnLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600); err == nil {
+			fmt.Fprintln(f, command)
+			f.Close()
+		}
+	}
+
+	if command == bridgeServeCommand {
+		mockBridgeServe()
+		return
+	}
+
 	// Read stdin JSON
 	var req map[string]any
 	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
@@ -40,14 +68,75 @@ func TestHelperProcess(_ *testing.T) {
 		os.Exit(1)
 	}
 
+	if code := mockBridgeCommand(os.Stdout, command, req); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// mockBridgeServe emulates `bridge serve`: one framed request per stdin line,
+// each output line tagged with the request ID. MOCK_BRIDGE_SERVE_EXIT_AFTER
+// makes the daemon exit after answering that many requests, and
+// MOCK_BRIDGE_PING_ANSWERS makes it ignore pings after that many.
+func mockBridgeServe() {
+	exitAfter, pingAnswers := 0, -1
+	if v := os.Getenv("MOCK_BRIDGE_SERVE_EXIT_AFTER"); v != "" {
+		fmt.Sscanf(v, "%d", &exitAfter)
+	}
+	if v := os.Getenv("MOCK_BRIDGE_PING_ANSWERS"); v != "" {
+		fmt.Sscanf(v, "%d", &pingAnswers)
+	}
+
+	dec := json.NewDecoder(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for handled := 1; ; handled++ {
+		var frame BridgeDaemonRequest
+		if err := dec.Decode(&frame); err != nil {
+			return
+		}
+		if frame.Request == nil {
+			frame.Request = map[string]any{}
+		}
+
+		var out bytes.Buffer
+		switch frame.Command {
+		case bridgeCancelCommand:
+			continue
+		case bridgePingCommand:
+			if pingAnswers == 0 {
+				continue
+			}
+			pingAnswers--
+			writeOKResponse(&out, nil)
+		default:
+			mockBridgeCommand(&out, frame.Command, frame.Request)
+		}
+		for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+			var msg map[string]any
+			if err := json.Unmarshal(line, &msg); err != nil {
+				fmt.Fprintln(os.Stdout, string(line))
+				continue
+			}
+			msg["id"] = frame.ID
+			enc.Encode(msg)
+		}
+
+		if exitAfter > 0 && handled >= exitAfter {
+			os.Exit(1)
+		}
+	}
+}
+
+// mockBridgeCommand writes the mock output for one bridge command and returns
+// the exit code a one-shot subprocess would use.
+func mockBridgeCommand(out io.Writer, command string, req map[string]any) int {
 	// Check for mock error injection via env
 	if mockErr := os.Getenv("MOCK_BRIDGE_ERROR"); mockErr != "" {
 		code := 500
 		if codeStr := os.Getenv("MOCK_BRIDGE_ERROR_CODE"); codeStr != "" {
 			fmt.Sscanf(codeStr, "%d", &code)
 		}
-		writeErrorResponse(os.Stdout, code, mockErr)
-		os.Exit(1)
+		writeErrorResponse(out, code, mockErr)
+		return 1
 	}
 
 	// Check for mock delay
@@ -59,45 +148,77 @@ func TestHelperProcess(_ *testing.T) {
 
 	// Check for mock noise prefix (tests stdout noise tolerance)
 	if noise := os.Getenv("MOCK_BRIDGE_NOISE"); noise != "" {
-		fmt.Fprintln(os.Stdout, noise)
+		fmt.Fprintln(out, noise)
+	}
+
+	// MOCK_BRIDGE_PART_DIR stores chunk parts at <dir>/<oid>/<part>.
+	if partDir := os.Getenv("MOCK_BRIDGE_PART_DIR"); partDir != "" {
+		if handled, code := mockPartCommand(out, partDir, command, req); handled {
+			return code
+		}
 	}
 
 	switch command {
 	case "auth":
-		writeOKResponse(os.Stdout, nil)
+		writeOKResponse(out, nil)
 	case "init":
-		writeOKResponse(os.Stdout, nil)
+		writeOKResponse(out, nil)
 	case "upload":
 		oid, _ := req["oid"].(string)
 		if oid == "" {
-			writeErrorResponse(os.Stdout, 400, "missing oid")
-			os.Exit(1)
+			writeErrorResponse(out, 400, "missing oid")
+			return 1
+		}
+		if streamProgress, _ := req["progress"].(bool); streamProgress {
+			writeProgressLines(out, 1000)
 		}
-		writeOKResponse(os.Stdout, nil)
+		writeOKResponse(out, nil)
 	case "download":
 		oid, _ := req["oid"].(string)
 		outputPath, _ := req["outputPath"].(string)
 		if oid == "" || outputPath == "" {
-			writeErrorResponse(os.Stdout, 400, "missing oid or outputPath")
-			os.Exit(1)
+			writeErrorResponse(out, 400, "missing oid or outputPath")
+			return 1
 		}
 		// Write test content to the output file
 		content := os.Getenv("MOCK_BRIDGE_DOWNLOAD_CONTENT")
 		if content == "" {
 			content = "mock-download-content"
 		}
-		if err := os.WriteFile(outputPath, []byte(content), 0o600); err != nil {
-			writeErrorResponse(os.Stdout, 500, "failed to write download: "+err.Error())
-			os.Exit(1)
+		// An offset writes only the rest of the content, at that offset.
+		// MOCK_BRIDGE_DOWNLOAD_FAIL_AT stops writing at that byte and fails,
+		// like an interrupted download.
+		offset, _ := req["offset"].(float64)
+		end := len(content)
+		if v := os.Getenv("MOCK_BRIDGE_DOWNLOAD_FAIL_AT"); v != "" {
+			fmt.Sscanf(v, "%d", &end)
+		}
+		if streamProgress, _ := req["progress"].(bool); streamProgress {
+			writeProgressLines(out, int64(len(content))-int64(offset))
+		}
+		f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE, 0o600)
+		if err == nil {
+			_, err = f.WriteAt([]byte(content[int(offset):end]), int64(offset))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			writeErrorResponse(out, 500, "failed to write download: "+err.Error())
+			return 1
 		}
-		writeOKResponse(os.Stdout, nil)
+		if end < len(content) {
+			writeErrorResponse(out, 503, "connection reset")
+			return 1
+		}
+		writeOKResponse(out, nil)
 	case "exists":
 		existsResult := os.Getenv("MOCK_BRIDGE_EXISTS_RESULT")
 		if existsResult == "false" {
-			writeErrorResponse(os.Stdout, 404, "not found")
-			os.Exit(1)
+			writeErrorResponse(out, 404, "not found")
+			return 1
 		}
-		writeOKResponse(os.Stdout, map[string]bool{"exists": true})
+		writeOKResponse(out, map[string]bool{"exists": true})
 	case "batch-exists":
 		oids, _ := req["oids"].([]any)
 		result := make(map[string]bool)
@@ -106,7 +227,7 @@ func TestHelperProcess(_ *testing.T) {
 				result[s] = true
 			}
 		}
-		writeOKResponse(os.Stdout, result)
+		writeOKResponse(out, result)
 	case "batch-delete":
 		oids, _ := req["oids"].([]any)
 		result := make(map[string]bool)
@@ -115,14 +236,93 @@ func TestHelperProcess(_ *testing.T) {
 				result[s] = true
 			}
 		}
-		writeOKResponse(os.Stdout, result)
+		writeOKResponse(out, result)
+	case "list":
+		// MOCK_BRIDGE_LIST is a comma-separated list of oid:size entries,
+		// optionally followed by :unix-seconds for the modification time.
+		objects := []RemoteObject{}
+		for _, entry := range strings.Split(os.Getenv("MOCK_BRIDGE_LIST"), ",") {
+			fields := strings.Split(entry, ":")
+			if len(fields) < 2 {
+				continue
+			}
+			obj := RemoteObject{OID: fields[0]}
+			fmt.Sscanf(fields[1], "%d", &obj.Size)
+			if len(fields) > 2 {
+				var sec int64
+				fmt.Sscanf(fields[2], "%d", &sec)
+				obj.ModTime = time.Unix(sec, 0).UTC()
+			}
+			objects = append(objects, obj)
+		}
+		writeOKResponse(out, map[string]any{"objects": objects})
 	default:
-		writeErrorResponse(os.Stdout, 400, "unknown command: "+command)
-		os.Exit(1)
+		writeErrorResponse(out, 400, "unknown command: "+command)
+		return 1
+	}
+	return 0
+}
+
+// mockPartCommand serves upload, download and exists requests that carry a
+// part, and reports a whole-object download of a chunked object as missing.
+func mockPartCommand(out io.Writer, partDir, command string, req map[string]any) (bool, int) {
+	oid, _ := req["oid"].(string)
+	part, _ := req["part"].(string)
+	if part == "" {
+		if _, err := os.Stat(filepath.Join(partDir, oid)); command == "download" && err == nil {
+			writeErrorResponse(out, 404, "not found")
+			return true, 1
+		}
+		return false, 0
+	}
+	partPath := filepath.Join(partDir, oid, part)
+	switch command {
+	case "upload":
+		path, _ := req["path"].(string)
+		offset, _ := req["offset"].(float64)
+		length, _ := req["length"].(float64)
+		src, err := os.Open(path)
+		if err != nil {
+			writeErrorResponse(out, 404, "upload source not found")
+			return true, 1
+		}
+		defer src.Close()
+		data := make([]byte, int64(length))
+		if _, err := src.ReadAt(data, int64(offset)); err != nil {
+			writeErrorResponse(out, 500, "failed to read part: "+err.Error())
+			return true, 1
+		}
+		_ = os.MkdirAll(filepath.Dir(partPath), 0o700)
+		if err := os.WriteFile(partPath, data, 0o600); err != nil {
+			writeErrorResponse(out, 500, "failed to store part: "+err.Error())
+			return true, 1
+		}
+		writeOKResponse(out, nil)
+	case "download":
+		outputPath, _ := req["outputPath"].(string)
+		data, err := os.ReadFile(partPath)
+		if err != nil {
+			writeErrorResponse(out, 404, "not found")
+			return true, 1
+		}
+		if err := os.WriteFile(outputPath, data, 0o600); err != nil {
+			writeErrorResponse(out, 500, "failed to write download: "+err.Error())
+			return true, 1
+		}
+		writeOKResponse(out, nil)
+	case "exists":
+		if _, err := os.Stat(partPath); err != nil {
+			writeErrorResponse(out, 404, "not found")
+			return true, 1
+		}
+		writeOKResponse(out, map[string]bool{"exists": true})
+	default:
+		return false, 0
 	}
+	return true, 0
 }
 
-func writeOKResponse(f *os.File, payload any) {
+func writeOKResponse(f io.Writer, payload any) {
 	resp := map[string]any{"ok": true}
 	if payload != nil {
 		payloadBytes, _ := json.Marshal(payload)
@@ -131,7 +331,15 @@ func writeOKResponse(f *os.File, payload any) {
 	json.NewEncoder(f).Encode(resp)
 }
 
-func writeErrorResponse(f *os.File, code int, message string) {
+// writeProgressLines emits a half-way and a final progress line, the way
+// proton-drive-cli streams them when a request carries "progress": true.
+func writeProgressLines(f io.Writer, total int64) {
+	enc := json.NewEncoder(f)
+	enc.Encode(map[string]any{"type": "progress", "bytesSoFar": total / 2, "totalBytes": total})
+	enc.Encode(map[string]any{"type": "progress", "bytesSoFar": total, "totalBytes": total})
+}
+
+func writeErrorResponse(f io.Writer, code int, message string) {
 	resp := map[string]any{
 		"ok":    false,
 		"error": message,
@@ -175,16 +383,41 @@ func TestBridgeInitLFSStorage(t *testing.T) {
 func TestBridgeUpload(t *testing.T) {
 	bc := helperBridgeClient(t)
 	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
-	if err := bc.Upload(creds, validOID, "/tmp/test.bin"); err != nil {
+	if err := bc.Upload(creds, validOID, "/tmp/test.bin", nil); err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
 }
 
+func TestBridgeUploadStreamsProgress(t *testing.T) {
+	bc := helperBridgeClient(t)
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	var seen []int64
+	if err := bc.Upload(creds, validOID, "/tmp/test.bin", func(n int64) { seen = append(seen, n) }); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != 500 || seen[1] != 1000 {
+		t.Fatalf("unexpected streamed progress: %v", seen)
+	}
+}
+
+func TestBridgeDownloadStreamsProgress(t *testing.T) {
+	bc := helperBridgeClient(t)
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	tmpPath := t.TempDir() + "/download.bin"
+	var last int64
+	if err := bc.Download(creds, validOID, tmpPath, func(n int64) { last = n }); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if last != int64(len("mock-download-content")) {
+		t.Fatalf("expected final progress to equal content length, got %d", last)
+	}
+}
+
 func TestBridgeDownload(t *testing.T) {
 	bc := helperBridgeClient(t)
 	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
 	tmpPath := t.TempDir() + "/download.bin"
-	if err := bc.Download(creds, validOID, tmpPath); err != nil {
+	if err := bc.Download(creds, validOID, tmpPath, nil); err != nil {
 		t.Fatalf("Download failed: %v", err)
 	}
 	data, err := os.ReadFile(tmpPath)
@@ -235,7 +468,7 @@ func TestBridgeErrorMapping401(t *testing.T) {
 func TestBridgeErrorMapping404(t *testing.T) {
 	bc := helperBridgeClient(t, "MOCK_BRIDGE_ERROR=not found", "MOCK_BRIDGE_ERROR_CODE=404")
 	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
-	err := bc.Upload(creds, validOID, "/tmp/test.bin")
+	err := bc.Upload(creds, validOID, "/tmp/test.bin", nil)
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -276,12 +509,37 @@ func TestBridgeStdoutNoiseTolerance(t *testing.T) {
 	}
 }
 
+func TestBridgeOptionalFeaturesNeedBridgeSupport(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_CAPABILITIES=")
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+
+	var calls int
+	if err := bc.Download(creds, validOID, filepath.Join(t.TempDir(), "out.bin"), func(int64) { calls++ }); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("a bridge without progress must not be asked to stream it, got %d updates", calls)
+	}
+	if _, err := bc.List(creds); err == nil || !strings.Contains(err.Error(), "too old") {
+		t.Fatalf("expected List to need a newer bridge, got %v", err)
+	}
+}
+
+func TestBridgeCapabilitiesFailedProbe(t *testing.T) {
+	// A bridge that predates the capabilities command fails every probe.
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_ERROR=unknown command")
+	if bc.supports(bridgeCapProgress) || len(bc.capabilities) != 0 {
+		t.Fatalf("expected no capabilities, got %v", bc.capabilities)
+	}
+}
+
 func TestBridgeSemaphoreExhaustion(t *testing.T) {
 	bc := NewBridgeClient(BridgeClientConfig{
 		NodeBin:       os.Args[0],
 		CLIBin:        "-test.run=TestHelperProcess",
 		Timeout:       10 * time.Second,
 		MaxConcurrent: 1,
+		QueueTimeout:  100 * time.Millisecond,
 		ExtraEnv:      []string{"GO_TEST_HELPER_PROCESS=1", "MOCK_BRIDGE_DELAY=2s"},
 	})
 
@@ -301,6 +559,59 @@ func TestBridgeSemaphoreExhaustion(t *testing.T) {
 	<-bc.semaphore
 }
 
+func TestBridgeQueuesForFreeSlot(t *testing.T) {
+	bc := NewBridgeClient(BridgeClientConfig{
+		NodeBin:       os.Args[0],
+		CLIBin:        "-test.run=TestHelperProcess",
+		Timeout:       10 * time.Second,
+		MaxConcurrent: 1,
+		QueueTimeout:  5 * time.Second,
+		ExtraEnv:      []string{"GO_TEST_HELPER_PROCESS=1"},
+	})
+
+	// The command waits for the slot instead of failing at once.
+	bc.semaphore <- struct{}{}
+	time.AfterFunc(100*time.Millisecond, func() { <-bc.semaphore })
+
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	if err := bc.Authenticate(creds); err != nil {
+		t.Fatalf("Authenticate should wait for the slot: %v", err)
+	}
+}
+
+func TestBridgeSharesSlotsAcrossClients(t *testing.T) {
+	// Two clients stand in for two adapter processes: flock excludes
+	// separate opens of a slot file even within one process.
+	dir := t.TempDir()
+	newClient := func() *BridgeClient {
+		return NewBridgeClient(BridgeClientConfig{
+			NodeBin:       os.Args[0],
+			CLIBin:        "-test.run=TestHelperProcess",
+			Timeout:       10 * time.Second,
+			MaxConcurrent: 1,
+			QueueTimeout:  200 * time.Millisecond,
+			SlotDir:       dir,
+			ExtraEnv:      []string{"GO_TEST_HELPER_PROCESS=1"},
+		})
+	}
+	first, second := newClient(), newClient()
+
+	release, err := first.acquireSlot()
+	if err != nil {
+		t.Fatalf("first client: %v", err)
+	}
+	creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI}
+	err = second.Authenticate(creds)
+	if err == nil || !strings.Contains(err.Error(), "concurrency limit reached (1 on this machine)") {
+		t.Fatalf("second client must wait for the machine-wide slot, got %v", err)
+	}
+
+	release()
+	if err := second.Authenticate(creds); err != nil {
+		t.Fatalf("second client after release: %v", err)
+	}
+}
+
 func TestBridgeCredentialPassthroughPassCLI(t *testing.T) {
 	// This test verifies that credentials are included in the request
 	// The mock subprocess doesn't validate them, but the bridge client
@@ -370,6 +681,24 @@ func TestParseBridgeOutput(t *testing.T) {
 		}
 	})
 
+	t.Run("progress lines before envelope", func(t *testing.T) {
+		stdout := []byte("{\"type\":\"progress\",\"bytesSoFar\":10}\n{\"ok\":true}\n{\"type\":\"progress\",\"bytesSoFar\":20}\n")
+		resp, err := parseBridgeOutput(stdout, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !resp.OK {
+			t.Fatal("expected envelope, not a progress line")
+		}
+	})
+
+	t.Run("only progress lines", func(t *testing.T) {
+		_, err := parseBridgeOutput([]byte(`{"type":"progress","bytesSoFar":10}`), nil)
+		if err == nil {
+			t.Fatal("expected error when no envelope follows progress lines")
+		}
+	})
+
 	t.Run("empty stdout", func(t *testing.T) {
 		_, err := parseBridgeOutput([]byte(""), nil)
 		if err == nil {
@@ -420,9 +749,28 @@ func TestBuildCredentials(t *testing.T) {
 		if m["storageBase"] != "LFS" {
 			t.Fatalf("expected storageBase=LFS, got %v", m["storageBase"])
 		}
+		if _, ok := m["profile"]; ok {
+			t.Fatal("profile should not be set for the default profile")
+		}
+	})
+
+	t.Run("named profile", func(t *testing.T) {
+		creds := OperationCredentials{CredentialProvider: CredentialProviderPassCLI, Profile: "work", Account: "me@work.example"}
+		m := buildCredentials(creds, "LFS", "")
+		if m["profile"] != "work" || m["account"] != "me@work.example" {
+			t.Fatalf("expected profile and account in request, got %v", m)
+		}
 	})
 }
 
+func TestFilteredEnvSessionDir(t *testing.T) {
+	bc := &BridgeClient{sessionDir: "/tmp/work-session"}
+	env := bc.filteredEnv()
+	if got := env[len(env)-1]; got != EnvDriveCLISessionDir+"=/tmp/work-session" {
+		t.Fatalf("expected the profile session dir to override the environment, got %q", got)
+	}
+}
+
 func TestFilteredEnvAllowlist(t *testing.T) {
 	bc := &BridgeClient{extraEnv: []string{"EXTRA_VAR=1"}}
 
@@ -574,3 +922,49 @@ func TestBuildCredentialsEmptyProvider(t *testing.T) {
 		t.Fatal("storageBase should still be present")
 	}
 }
+
+func TestBridgeEnsureCapacity(t *testing.T) {
+	bc := NewBridgeClient(BridgeClientConfig{NodeBin: os.Args[0], MaxConcurrent: 2})
+
+	bc.EnsureCapacity(1)
+	if bc.maxConcurrent != 2 || cap(bc.semaphore) != 2 {
+		t.Fatalf("EnsureCapacity must not shrink the limit, got %d/%d", bc.maxConcurrent, cap(bc.semaphore))
+	}
+	bc.EnsureCapacity(8)
+	if bc.maxConcurrent != 8 || cap(bc.semaphore) != 8 {
+		t.Fatalf("expected limit raised to 8, got %d/%d", bc.maxConcurrent, cap(bc.semaphore))
+	}
+	if bc.machineLimit != 2 {
+		t.Fatalf("EnsureCapacity must not raise the machine-wide limit, got %d", bc.machineLimit)
+	}
+}
+
+func TestBridgeEnsureCapacityKeepsMachineWideLimit(t *testing.T) {
+	bc := NewBridgeClient(BridgeClientConfig{
+		NodeBin:       os.Args[0],
+		MaxConcurrent: 1,
+		QueueTimeout:  200 * time.Millisecond,
+		SlotDir:       t.TempDir(),
+	})
+	bc.EnsureCapacity(4)
+
+	release, err := bc.acquireSlot()
+	if err != nil {
+		t.Fatalf("first slot: %v", err)
+	}
+	defer release()
+	if _, err := bc.acquireSlot(); err == nil || !strings.Contains(err.Error(), "(1 on this machine)") {
+		t.Fatalf("a second command must wait for the one machine-wide slot, got %v", err)
+	}
+}
+
+func TestBridgeReportsUnusableSlotDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bc := NewBridgeClient(BridgeClientConfig{NodeBin: os.Args[0], SlotDir: filepath.Join(file, "slots")})
+	if _, err := bc.acquireSlot(); err == nil || !strings.Contains(err.Error(), "bridge concurrency slots") {
+		t.Fatalf("expected the slot directory error, got %v", err)
+	}
+}
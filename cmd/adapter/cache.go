@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTempPrefix names partially written cache entries.
+const cacheTempPrefix = ".tmp-"
+
+// staleCacheTempAge is how old a partial entry must be before eviction
+// removes it; younger ones may still be written by another process.
+const staleCacheTempAge = time.Hour
+
+// objectCache is a content-addressed, size-bounded LRU cache of LFS objects
+// shared by every adapter process of the user. Entries use the same
+// <oid[0:2]>/<oid[2:4]>/<oid> layout as LocalStoreBackend and recency is the
+// file modification time, so there is no index to keep consistent across
+// processes. Entries are written to a temp file and renamed into place, and
+// are re-hashed on every read.
+type objectCache struct {
+	dir      string
+	maxBytes int64
+
+	// mu serializes eviction within this process and guards total.
+	mu sync.Mutex
+	// total is the cache size found by the last walk plus what this process
+	// stored since, or -1 before the first walk. Other processes store too,
+	// so it is an estimate: once it passes maxBytes, eviction walks the
+	// cache for the real size.
+	total int64
+}
+
+func newObjectCache(dir string, maxBytes int64) *objectCache {
+	return &objectCache{dir: dir, maxBytes: maxBytes, total: -1}
+}
+
+func (c *objectCache) path(oid string) string {
+	return fanoutPath(c.dir, oid)
+}
+
+// stage copies a cached object into a new temp file, verifying its SHA-256
+// on the way. It reports false on a miss; an entry that fails verification is
+// removed and treated as a miss.
+func (c *objectCache) stage(oid string) (string, int64, bool) {
+	entry := c.path(oid)
+	src, err := os.Open(entry)
+	if err != nil {
+		return "", 0, false
+	}
+	defer func() { _ = src.Close() }()
+
+	tmpFile, err := os.CreateTemp("", "git-lfs-proton-download-*")
+	if err != nil {
+		return "", 0, false
+	}
+	tmpPath := tmpFile.Name()
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hasher), src)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", 0, false
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != oid {
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(entry)
+		return "", 0, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(entry, now, now)
+	return tmpPath, size, true
+}
+
+// store adds the file at srcPath to the cache under oid, provided its content
+// hashes to oid, then evicts least recently used entries over the size bound.
+// A file larger than the whole cache is not copied at all.
+func (c *objectCache) store(oid, srcPath string) error {
+	entry := c.path(oid)
+	if _, err := os.Stat(entry); err == nil {
+		now := time.Now()
+		return os.Chtimes(entry, now, now)
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > c.maxBytes {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(entry), 0o700); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(entry), cacheTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hasher), src)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(hasher.Sum(nil)) != oid {
+		err = errHashMismatch
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, entry)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	c.evict(size)
+	return nil
+}
+
+// evict accounts for added bytes just stored. When the cache may have grown
+// past maxBytes, it removes the least recently used entries until the cache
+// fits, along with partial entries abandoned by crashed processes.
+func (c *objectCache) evict(added int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.total >= 0 {
+		c.total += added
+		if c.total <= c.maxBytes {
+			return
+		}
+	}
+
+	type cacheEntry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []cacheEntry
+	var total int64
+	_ = filepath.WalkDir(c.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, cacheTempPrefix) {
+			if time.Since(info.ModTime()) > staleCacheTempAge {
+				_ = os.Remove(path)
+			}
+			return nil
+		}
+		if !oidPattern.MatchString(name) {
+			return nil
+		}
+		entries = append(entries, cacheEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	c.total = total
+	if total <= c.maxBytes {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err == nil || os.IsNotExist(err) {
+			total -= e.size
+		}
+	}
+	c.total = total
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func oidOf(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeTempObject(t *testing.T, payload []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "object.bin")
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestObjectCacheStoreAndStage(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 1<<20)
+	payload := []byte("cached payload")
+	oid := oidOf(payload)
+
+	if _, _, ok := cache.stage(oid); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	if err := cache.store(oid, writeTempObject(t, payload)); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if _, err := os.Stat(cache.path(oid)); err != nil {
+		t.Fatalf("expected entry at the fan-out path: %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(cache.path(oid), old, old); err != nil {
+		t.Fatal(err)
+	}
+	staged, size, ok := cache.stage(oid)
+	if !ok {
+		t.Fatal("expected a hit after store")
+	}
+	defer os.Remove(staged)
+	data, err := os.ReadFile(staged)
+	if err != nil || string(data) != string(payload) || size != int64(len(payload)) {
+		t.Fatalf("unexpected staged content %q (size %d, err %v)", data, size, err)
+	}
+	info, err := os.Stat(cache.path(oid))
+	if err != nil || !info.ModTime().After(old) {
+		t.Fatal("expected a hit to refresh the entry's recency")
+	}
+}
+
+func TestObjectCacheStoreRejectsHashMismatch(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 1<<20)
+	if err := cache.store(validOID, writeTempObject(t, []byte("not the oid"))); err == nil {
+		t.Fatal("expected store to reject content that does not hash to the oid")
+	}
+	if _, err := os.Stat(cache.path(validOID)); !os.IsNotExist(err) {
+		t.Fatalf("mismatched content must not be cached, stat err = %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(cache.path(validOID)))
+	if len(entries) != 0 {
+		t.Fatalf("expected no leftover temp files, found %d", len(entries))
+	}
+}
+
+func TestObjectCacheDropsCorruptEntry(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 1<<20)
+	payload := []byte("will rot")
+	oid := oidOf(payload)
+	if err := cache.store(oid, writeTempObject(t, payload)); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if err := os.WriteFile(cache.path(oid), []byte("rotted"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := cache.stage(oid); ok {
+		t.Fatal("expected a corrupt entry to be a miss")
+	}
+	if _, err := os.Stat(cache.path(oid)); !os.IsNotExist(err) {
+		t.Fatalf("expected the corrupt entry to be removed, stat err = %v", err)
+	}
+}
+
+func TestObjectCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 20)
+	payloads := [][]byte{[]byte("0123456789"), []byte("abcdefghij"), []byte("ABCDEFGHIJ")}
+	base := time.Now().Add(-time.Hour)
+	for i, payload := range payloads[:2] {
+		oid := oidOf(payload)
+		if err := cache.store(oid, writeTempObject(t, payload)); err != nil {
+			t.Fatalf("store failed: %v", err)
+		}
+		stamp := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(cache.path(oid), stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A stale partial entry from a crashed process is cleaned up too.
+	stale := filepath.Join(cache.dir, cacheTempPrefix+"crashed")
+	if err := os.WriteFile(stale, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(stale, base.Add(-time.Hour), base.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	newest := oidOf(payloads[2])
+	if err := cache.store(newest, writeTempObject(t, payloads[2])); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	if _, err := os.Stat(cache.path(oidOf(payloads[0]))); !os.IsNotExist(err) {
+		t.Fatalf("expected the least recently used entry to be evicted, stat err = %v", err)
+	}
+	for _, oid := range []string{oidOf(payloads[1]), newest} {
+		if _, err := os.Stat(cache.path(oid)); err != nil {
+			t.Fatalf("expected %s to stay cached: %v", oid, err)
+		}
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale temp file to be removed, stat err = %v", err)
+	}
+}
+
+func TestDriveCLIBackendDownloadUsesCache(t *testing.T) {
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	backend.SetCache(newObjectCache(t.TempDir(), 1<<20))
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	oid := oidOf([]byte("mock-download-content"))
+
+	for i := 0; i < 2; i++ {
+		var reported int64
+		path, size, err := backend.Download(session, oid, func(n int64) { reported = n })
+		if err != nil {
+			t.Fatalf("Download #%d failed: %v", i+1, err)
+		}
+		data, _ := os.ReadFile(path)
+		_ = os.Remove(path)
+		if string(data) != "mock-download-content" || size != int64(len(data)) {
+			t.Fatalf("Download #%d returned %q (size %d)", i+1, data, size)
+		}
+		if reported != size {
+			t.Fatalf("Download #%d reported progress %d, want %d", i+1, reported, size)
+		}
+	}
+
+	logged, _ := os.ReadFile(spawnLog)
+	if got := strings.Count(string(logged), "download"); got != 1 {
+		t.Fatalf("expected the second download to be served from cache, got %d bridge downloads", got)
+	}
+}
+
+func TestDriveCLIBackendUploadPopulatesCache(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_EXISTS_RESULT=false")
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	cache := newObjectCache(t.TempDir(), 1<<20)
+	backend.SetCache(cache)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	payload := []byte("uploaded payload")
+	oid := oidOf(payload)
+	if _, err := backend.Upload(session, oid, writeTempObject(t, payload), int64(len(payload)), nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if _, err := os.Stat(cache.path(oid)); err != nil {
+		t.Fatalf("expected upload to populate the cache: %v", err)
+	}
+}
+
+func TestObjectCacheSkipsObjectsLargerThanLimit(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 8)
+	payload := []byte("larger than eight bytes")
+	oid := oidOf(payload)
+	if err := cache.store(oid, writeTempObject(t, payload)); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if _, err := os.Stat(cache.path(oid)); !os.IsNotExist(err) {
+		t.Fatalf("an object larger than the cache must not be cached, stat err = %v", err)
+	}
+}
+
+func TestObjectCacheTracksSizeBetweenWalks(t *testing.T) {
+	cache := newObjectCache(t.TempDir(), 1<<20)
+	for _, payload := range [][]byte{[]byte("first"), []byte("second")} {
+		if err := cache.store(oidOf(payload), writeTempObject(t, payload)); err != nil {
+			t.Fatalf("store failed: %v", err)
+		}
+	}
+	if cache.total != int64(len("first")+len("second")) {
+		t.Fatalf("total = %d, want the size of both entries", cache.total)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Objects larger than DefaultChunkThresholdMB are stored as parts under their
+// OID's folder so that no single bridge command runs into the timeout.
+const (
+	// chunkWorkers is how many parts of one object are transferred at once.
+	chunkWorkers = 4
+
+	chunkManifestPart    = "manifest.json"
+	chunkManifestVersion = 1
+)
+
+// ChunkManifest records how a chunked object was split. It is uploaded after
+// every chunk, so its presence marks the object as complete.
+type ChunkManifest struct {
+	Version   int         `json:"version"`
+	OID       string      `json:"oid"`
+	Size      int64       `json:"size"`
+	ChunkSize int64       `json:"chunkSize"`
+	Chunks    []ChunkInfo `json:"chunks"`
+}
+
+// ChunkInfo describes one part of a chunked object. Name embeds the chunk's
+// SHA-256, so a part that already exists remotely never needs re-uploading.
+type ChunkInfo struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func chunkName(index int, sha string) string {
+	return fmt.Sprintf("chunk-%06d-%s", index, sha)
+}
+
+// buildChunkManifest splits the file at path into chunkSize parts and hashes
+// each of them.
+func buildChunkManifest(oid, path string, chunkSize int64) (*ChunkManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ChunkManifest{Version: chunkManifestVersion, OID: oid, Size: info.Size(), ChunkSize: chunkSize}
+	for offset, index := int64(0), 0; offset < info.Size(); offset, index = offset+chunkSize, index+1 {
+		size := min(chunkSize, info.Size()-offset)
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, io.NewSectionReader(f, offset, size)); err != nil {
+			return nil, err
+		}
+		sha := hex.EncodeToString(hasher.Sum(nil))
+		manifest.Chunks = append(manifest.Chunks, ChunkInfo{
+			Index:  index,
+			Name:   chunkName(index, sha),
+			Offset: offset,
+			Size:   size,
+			SHA256: sha,
+		})
+	}
+	return manifest, nil
+}
+
+// validate checks that the manifest belongs to oid and that its chunks cover
+// the object contiguously.
+func (m *ChunkManifest) validate(oid string) error {
+	if m.Version != chunkManifestVersion {
+		return fmt.Errorf("unsupported chunk manifest version %d", m.Version)
+	}
+	if m.OID != oid {
+		return fmt.Errorf("chunk manifest is for %s", m.OID)
+	}
+	var next int64
+	for i, c := range m.Chunks {
+		if c.Index != i || c.Offset != next || c.Size <= 0 || c.Name != chunkName(i, c.SHA256) {
+			return fmt.Errorf("chunk manifest entry %d is inconsistent", i)
+		}
+		next += c.Size
+	}
+	if next != m.Size {
+		return fmt.Errorf("chunk manifest covers %d of %d bytes", next, m.Size)
+	}
+	return nil
+}
+
+// chunkProgress sums per-chunk progress into one monotonic byte count.
+type chunkProgress struct {
+	mu       sync.Mutex
+	done     map[int]int64
+	total    int64
+	progress ProgressFunc
+}
+
+func newChunkProgress(progress ProgressFunc) *chunkProgress {
+	return &chunkProgress{done: make(map[int]int64), progress: progress}
+}
+
+func (p *chunkProgress) set(index int, bytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if bytes <= p.done[index] {
+		return
+	}
+	p.total += bytes - p.done[index]
+	p.done[index] = bytes
+	if p.progress != nil {
+		p.progress(p.total)
+	}
+}
+
+func (p *chunkProgress) forChunk(index int) ProgressFunc {
+	return func(bytes int64) { p.set(index, bytes) }
+}
+
+// forEachChunk runs fn for every chunk on up to chunkWorkers goroutines and
+// returns the first error. Remaining chunks are skipped after a failure.
+func forEachChunk(chunks []ChunkInfo, fn func(ChunkInfo) error) error {
+	jobs := make(chan ChunkInfo)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for i := 0; i < min(chunkWorkers, len(chunks)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if failed() {
+					continue
+				}
+				if err := fn(c); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, c := range chunks {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
+
+// uploadChunked uploads sourcePath as parts plus a manifest. Parts recorded
+// in the resume journal are skipped outright, and parts that are already
+// stored (from an interrupted attempt without a journal) are not re-sent.
+func (b *DriveCLIBackend) uploadChunked(oid, sourcePath string, progress ProgressFunc) error {
+	manifest, err := buildChunkManifest(oid, sourcePath, b.chunkSize)
+	if err != nil {
+		return newBackendError(500, "failed to split object into chunks", err)
+	}
+	creds := b.operationCredentials()
+	tracker := newChunkProgress(progress)
+	journal, unlock := b.lockJournal(resumeUpload, oid)
+	defer unlock()
+	state := journal.resume(resumeUpload, manifest)
+
+	err = forEachChunk(manifest.Chunks, func(c ChunkInfo) error {
+		if state.isDone(c.Name) {
+			tracker.set(c.Index, c.Size)
+			return nil
+		}
+		exists, err := b.bridge.ExistsPart(creds, oid, c.Name)
+		if err != nil {
+			return mapBridgeError(err, "drive-cli chunk existence check failed")
+		}
+		if !exists {
+			if err := b.bridge.UploadPart(creds, oid, c.Name, sourcePath, c.Offset, c.Size, tracker.forChunk(c.Index)); err != nil {
+				return mapBridgeError(err, "drive-cli chunk upload failed")
+			}
+		}
+		state.complete(c)
+		tracker.set(c.Index, c.Size)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return newBackendError(500, "failed to encode chunk manifest", err)
+	}
+	manifestFile, err := os.CreateTemp("", "git-lfs-proton-manifest-*")
+	if err != nil {
+		return newBackendError(500, "failed to stage chunk manifest", err)
+	}
+	manifestPath := manifestFile.Name()
+	defer func() { _ = os.Remove(manifestPath) }()
+	_, err = manifestFile.Write(data)
+	if closeErr := manifestFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return newBackendError(500, "failed to stage chunk manifest", err)
+	}
+	if err := b.bridge.UploadPart(creds, oid, chunkManifestPart, manifestPath, 0, int64(len(data)), nil); err != nil {
+		return mapBridgeError(err, "drive-cli chunk manifest upload failed")
+	}
+	state.finish()
+	return nil
+}
+
+// downloadChunkManifest fetches and validates the manifest of a chunked
+// object. A 404 BackendError means the object is not stored chunked either.
+func (b *DriveCLIBackend) downloadChunkManifest(oid string) (*ChunkManifest, error) {
+	tmpFile, err := os.CreateTemp("", "git-lfs-proton-manifest-*")
+	if err != nil {
+		return nil, newBackendError(500, "failed to create temporary manifest file", err)
+	}
+	tmpPath := tmpFile.Name()
+	_ = tmpFile.Close()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if err := b.bridge.DownloadPart(b.operationCredentials(), oid, chunkManifestPart, tmpPath, nil); err != nil {
+		return nil, mapBridgeError(err, "drive-cli chunk manifest download failed")
+	}
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, newBackendError(500, "failed to read chunk manifest", err)
+	}
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, newBackendError(500, "failed to parse chunk manifest", err)
+	}
+	if err := manifest.validate(oid); err != nil {
+		return nil, newBackendError(500, "invalid chunk manifest", err)
+	}
+	return &manifest, nil
+}
+
+// downloadChunked fetches every chunk in parallel, verifies each against its
+// manifest hash and writes it at its offset in the staged file, whose path it
+// returns. With a resume journal the staged file lives in the journal
+// directory and chunks written by an interrupted attempt are kept; the
+// reassembled object is then re-hashed here, since those chunks were written
+// by another process. Otherwise outputPath is used and the caller verifies.
+func (b *DriveCLIBackend) downloadChunked(journal *resumeJournal, manifest *ChunkManifest, outputPath string, progress ProgressFunc) (string, error) {
+	state := journal.resume(resumeDownload, manifest)
+	staged := outputPath
+	if journal != nil {
+		staged = journal.stagedPath(manifest.OID)
+	}
+	if state.resumed {
+		if info, err := os.Stat(staged); err != nil || state.entry.StagedPath != staged || info.Size() != manifest.Size {
+			state.reset()
+		}
+	}
+	state.entry.StagedPath = staged
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if !state.resumed {
+		flags |= os.O_TRUNC
+	}
+	if journal != nil {
+		if err := os.MkdirAll(filepath.Dir(staged), 0o700); err != nil {
+			return "", newBackendError(500, "failed to create resume directory", err)
+		}
+	}
+	out, err := os.OpenFile(staged, flags, 0o600)
+	if err != nil {
+		return "", newBackendError(500, "failed to open download output", err)
+	}
+	defer func() { _ = out.Close() }()
+	if err := out.Truncate(manifest.Size); err != nil {
+		return "", newBackendError(500, "failed to allocate download output", err)
+	}
+
+	creds := b.operationCredentials()
+	tracker := newChunkProgress(progress)
+	err = forEachChunk(manifest.Chunks, func(c ChunkInfo) error {
+		if state.isDone(c.Name) {
+			tracker.set(c.Index, c.Size)
+			return nil
+		}
+		partFile, err := os.CreateTemp("", "git-lfs-proton-chunk-*")
+		if err != nil {
+			return newBackendError(500, "failed to create temporary chunk file", err)
+		}
+		partPath := partFile.Name()
+		_ = partFile.Close()
+		defer func() { _ = os.Remove(partPath) }()
+
+		if err := b.bridge.DownloadPart(creds, manifest.OID, c.Name, partPath, tracker.forChunk(c.Index)); err != nil {
+			return mapBridgeError(err, "drive-cli chunk download failed")
+		}
+		if err := copyVerifiedChunk(partPath, out, c); err != nil {
+			return err
+		}
+		if err := out.Sync(); err != nil {
+			return newBackendError(500, "failed to write chunk into download output", err)
+		}
+		state.complete(c)
+		tracker.set(c.Index, c.Size)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if state.resumed {
+		hash, _, err := calculateFileSHA256(staged)
+		if err != nil || hash != manifest.OID {
+			// Start over on the next attempt rather than resume bad data.
+			state.finish()
+			_ = os.Remove(staged)
+			if err == nil {
+				err = errHashMismatch
+			}
+			return "", newBackendError(500, "resumed download failed verification", err)
+		}
+	}
+	state.finish()
+	return staged, nil
+}
+
+// copyVerifiedChunk writes the part at partPath into out at the chunk's
+// offset, failing if its size or SHA-256 differ from the manifest.
+func copyVerifiedChunk(partPath string, out *os.File, c ChunkInfo) error {
+	part, err := os.Open(partPath)
+	if err != nil {
+		return newBackendError(500, "drive-cli backend did not materialize chunk output", err)
+	}
+	defer func() { _ = part.Close() }()
+
+	hasher := sha256.New()
+	n, err := io.Copy(io.MultiWriter(io.NewOffsetWriter(out, c.Offset), hasher), io.LimitReader(part, c.Size+1))
+	if err != nil {
+		return newBackendError(500, "failed to write chunk into download output", err)
+	}
+	if n != c.Size || hex.EncodeToString(hasher.Sum(nil)) != c.SHA256 {
+		return newBackendError(500, fmt.Sprintf("chunk %d failed verification", c.Index), errHashMismatch)
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a 404 BackendError.
+func isNotFound(err error) bool {
+	var backendErr *BackendError
+	return errors.As(err, &backendErr) && backendErr.Code == 404
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildChunkManifest(t *testing.T) {
+	payload := []byte("0123456789")
+	path := writeTempObject(t, payload)
+
+	manifest, err := buildChunkManifest(oidOf(payload), path, 4)
+	if err != nil {
+		t.Fatalf("buildChunkManifest failed: %v", err)
+	}
+	if manifest.Size != 10 || len(manifest.Chunks) != 3 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	for i, want := range []string{"0123", "4567", "89"} {
+		c := manifest.Chunks[i]
+		if c.Offset != int64(i*4) || c.Size != int64(len(want)) || c.SHA256 != oidOf([]byte(want)) {
+			t.Fatalf("unexpected chunk %d: %+v", i, c)
+		}
+	}
+	if err := manifest.validate(oidOf(payload)); err != nil {
+		t.Fatalf("expected a valid manifest: %v", err)
+	}
+
+	if err := manifest.validate(validOID); err == nil {
+		t.Fatal("expected a manifest for another oid to be rejected")
+	}
+	manifest.Chunks = manifest.Chunks[:2]
+	if err := manifest.validate(oidOf(payload)); err == nil {
+		t.Fatal("expected a manifest missing a chunk to be rejected")
+	}
+}
+
+// chunkedBackend returns an initialized drive-cli backend that chunks objects
+// over 8 bytes into 4-byte parts stored by the mock bridge in partDir.
+func chunkedBackend(t *testing.T, partDir string, env ...string) (*DriveCLIBackend, *Session) {
+	t.Helper()
+	env = append(env, "MOCK_BRIDGE_PART_DIR="+partDir, "MOCK_BRIDGE_EXISTS_RESULT=false")
+	backend := NewDriveCLIBackend(helperBridgeClient(t, env...), CredentialProviderPassCLI)
+	backend.SetChunking(8, 4)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return backend, session
+}
+
+func TestDriveCLIBackendChunkedRoundTrip(t *testing.T) {
+	partDir := t.TempDir()
+	backend, session := chunkedBackend(t, partDir)
+	payload := []byte("a large object split into parts")
+	oid := oidOf(payload)
+
+	var uploaded int64
+	size, err := backend.Upload(session, oid, writeTempObject(t, payload), int64(len(payload)), func(n int64) { uploaded = n })
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if size != int64(len(payload)) || uploaded != size {
+		t.Fatalf("Upload returned size %d and progress %d, want %d", size, uploaded, len(payload))
+	}
+	parts, _ := os.ReadDir(filepath.Join(partDir, oid))
+	if len(parts) != 9 { // 8 chunks and the manifest
+		t.Fatalf("expected 8 chunks and a manifest, found %d parts", len(parts))
+	}
+
+	var downloaded int64
+	path, size, err := backend.Download(session, oid, func(n int64) { downloaded = n })
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer os.Remove(path)
+	data, _ := os.ReadFile(path)
+	if string(data) != string(payload) || size != int64(len(payload)) || downloaded != size {
+		t.Fatalf("Download returned %q (size %d, progress %d)", data, size, downloaded)
+	}
+}
+
+func TestDriveCLIBackendChunkingNeedsBridgeSupport(t *testing.T) {
+	partDir := t.TempDir()
+	backend, session := chunkedBackend(t, partDir, "MOCK_BRIDGE_CAPABILITIES=progress")
+	payload := []byte("a large object for an older bridge")
+	if _, err := backend.Upload(session, oidOf(payload), writeTempObject(t, payload), int64(len(payload)), nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if parts, _ := os.ReadDir(filepath.Join(partDir, oidOf(payload))); len(parts) != 0 {
+		t.Fatalf("a bridge without chunks must get the whole object, found %d parts", len(parts))
+	}
+}
+
+func TestDriveCLIBackendChunkedUploadResumes(t *testing.T) {
+	partDir := t.TempDir()
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	backend, session := chunkedBackend(t, partDir, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	payload := []byte("0123456789abcdef")
+	oid := oidOf(payload)
+	source := writeTempObject(t, payload)
+	if _, err := backend.Upload(session, oid, source, 0, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// Simulate an interrupted upload: one chunk and the manifest never arrived.
+	manifest, err := buildChunkManifest(oid, source, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{manifest.Chunks[2].Name, chunkManifestPart} {
+		if err := os.Remove(filepath.Join(partDir, oid, part)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(spawnLog); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process retries the transfer.
+	backend, session = chunkedBackend(t, partDir, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	if _, err := backend.Upload(session, oid, source, 0, nil); err != nil {
+		t.Fatalf("resumed Upload failed: %v", err)
+	}
+	logged, _ := os.ReadFile(spawnLog)
+	if got := strings.Count(string(logged), "upload"); got != 2 {
+		t.Fatalf("expected only the missing chunk and the manifest to be uploaded, got %d uploads", got)
+	}
+	if _, err := os.Stat(filepath.Join(partDir, oid, manifest.Chunks[2].Name)); err != nil {
+		t.Fatalf("expected the missing chunk to be re-uploaded: %v", err)
+	}
+}
+
+func TestDriveCLIBackendChunkedDownloadDetectsCorruptChunk(t *testing.T) {
+	partDir := t.TempDir()
+	backend, session := chunkedBackend(t, partDir)
+	payload := []byte("0123456789abcdef")
+	oid := oidOf(payload)
+	source := writeTempObject(t, payload)
+	if _, err := backend.Upload(session, oid, source, 0, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	manifest, err := buildChunkManifest(oid, source, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(partDir, oid, manifest.Chunks[1].Name), []byte("rot!"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = backend.Download(session, oid, nil)
+	if !errors.Is(err, errHashMismatch) {
+		t.Fatalf("expected a chunk hash mismatch, got %v", err)
+	}
+}
+
+func TestDriveCLIBackendDownloadMissingObject(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_ERROR=not found", "MOCK_BRIDGE_ERROR_CODE=404")
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	backend.authenticated = true
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+
+	_, _, err := backend.Download(session, validOID, nil)
+	if !isNotFound(err) {
+		t.Fatalf("expected a 404 when neither the object nor a manifest exists, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"proton-lfs-cli/internal/config"
 )
 
@@ -16,11 +18,32 @@ const (
 	CredentialProviderGitCredential = config.CredentialProviderGitCredential
 )
 
+// Bridge modes
+const (
+	BridgeModeSubprocess = config.BridgeModeSubprocess
+	BridgeModeDaemon     = config.BridgeModeDaemon
+)
+
+// Pause modes
+const (
+	PauseModeFail = config.PauseModeFail
+	PauseModeWait = config.PauseModeWait
+)
+
 // Default values
 const (
 	DefaultDriveCLIBin        = config.DefaultDriveCLIBin
 	DefaultStorageBase        = config.DefaultStorageBase
 	DefaultCredentialProvider = config.DefaultCredentialProvider
+	DefaultBridgeMode         = config.DefaultBridgeMode
+	DefaultCacheMaxMB         = config.DefaultCacheMaxMB
+	DefaultChunkThresholdMB   = config.DefaultChunkThresholdMB
+	DefaultChunkSizeMB        = config.DefaultChunkSizeMB
+	DefaultBridgeTimeout      = config.DefaultBridgeTimeout
+	DefaultBridgeConcurrency  = config.DefaultBridgeConcurrency
+	DefaultBridgeQueueTimeout = config.DefaultBridgeQueueTimeout
+	DefaultPauseMode          = config.DefaultPauseMode
+	DefaultLogLevel           = config.DefaultLogLevel
 )
 
 // Environment variable names
@@ -33,6 +56,21 @@ const (
 	EnvAllowMockTransfers = config.EnvAllowMockTransfers
 	EnvLocalStoreDir      = config.EnvLocalStoreDir
 	EnvCredentialProvider = config.EnvCredentialProvider
+	EnvBridgeMode         = config.EnvBridgeMode
+	EnvRetryAttempts      = config.EnvRetryAttempts
+	EnvRetryMaxElapsed    = config.EnvRetryMaxElapsed
+	EnvCache              = config.EnvCache
+	EnvCacheDir           = config.EnvCacheDir
+	EnvCacheMaxMB         = config.EnvCacheMaxMB
+	EnvChunkThresholdMB   = config.EnvChunkThresholdMB
+	EnvChunkSizeMB        = config.EnvChunkSizeMB
+	EnvProfile            = config.EnvProfile
+	EnvDriveCLISessionDir = config.EnvDriveCLISessionDir
+	EnvTimeout            = config.EnvTimeout
+	EnvConcurrency        = config.EnvConcurrency
+	EnvQueueTimeout       = config.EnvQueueTimeout
+	EnvPauseMode          = config.EnvPauseMode
+	EnvLogLevel           = config.EnvLogLevel
 )
 
 func envTrim(key string) string {
@@ -46,3 +84,11 @@ func envOrDefault(key, fallback string) string {
 func envBoolOrDefault(key string, fallback bool) bool {
 	return config.EnvBoolOrDefault(key, fallback)
 }
+
+func envIntOrDefault(key string, fallback int) int {
+	return config.EnvIntOrDefault(key, fallback)
+}
+
+func envDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	return config.EnvDurationOrDefault(key, fallback)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const doctorCommand = "doctor"
+
+// MinNodeMajor is the oldest Node.js major version proton-drive-cli
+// supports (see the prerequisites in USAGE.md).
+const MinNodeMajor = 25
+
+var nodeVersionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// doctorRun holds the inputs of one doctor run.
+type doctorRun struct {
+	opts  *backendOptions
+	route *config.Route
+}
+
+// checks runs the adapter-side checks. The Node.js and proton-drive-cli
+// checks only apply to the sdk backend; when either fails the storage
+// round-trip is skipped, since it would fail the same way.
+func (d *doctorRun) checks() []config.DoctorCheck {
+	var checks []config.DoctorCheck
+	if d.opts.kindFor(d.route) == BackendSDK {
+		node := resolveNodeBinary()
+		checks = append(checks, checkNode(node), checkDriveCLI(node, strings.TrimSpace(*d.opts.driveCLIBin)))
+		if checks[0].Status == config.CheckFail || checks[1].Status == config.CheckFail {
+			return append(checks, config.DoctorCheck{Name: "storage", Status: config.CheckSkip, Detail: "needs a working node and drive-cli"})
+		}
+	} else {
+		skip := config.DoctorCheck{Status: config.CheckSkip, Detail: "not used by the local backend"}
+		node, cli := skip, skip
+		node.Name, cli.Name = "node", "drive-cli"
+		checks = append(checks, node, cli)
+	}
+	return append(checks, d.checkStorage())
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+// checkNode checks that the Node.js binary the bridge runs is recent enough.
+func checkNode(bin string) config.DoctorCheck {
+	check := config.DoctorCheck{Name: "node"}
+	fix := fmt.Sprintf("Install Node.js %d or later, or set %s to its path", MinNodeMajor, EnvNodeBin)
+	out, err := config.Probe(bin, "--version")
+	if err != nil {
+		check.Status, check.Detail, check.Fix = config.CheckFail, fmt.Sprintf("%s: %v", bin, err), fix
+		return check
+	}
+	m := nodeVersionPattern.FindStringSubmatch(out)
+	if m == nil {
+		check.Status, check.Detail, check.Fix = config.CheckWarn, fmt.Sprintf("%s: unrecognized version %q", bin, out), fix
+		return check
+	}
+	check.Detail = fmt.Sprintf("%s (%s)", strings.TrimPrefix(m[0], "v"), bin)
+	if major, _ := strconv.Atoi(m[1]); major < MinNodeMajor {
+		check.Status, check.Fix = config.CheckFail, fix
+		check.Detail += fmt.Sprintf(", need %d or later", MinNodeMajor)
+		return check
+	}
+	check.Status = config.CheckOK
+	return check
+}
+
+// checkDriveCLI checks that proton-drive-cli exists and answers when run the
+// way the bridge runs it.
+func checkDriveCLI(node, cliBin string) config.DoctorCheck {
+	check := config.DoctorCheck{Name: "drive-cli", Fix: fmt.Sprintf("Build it with 'make build-drive-cli', or point --drive-cli-bin or %s at it", EnvDriveCLIBin)}
+	if cliBin == "" {
+		check.Status, check.Detail = config.CheckFail, "no proton-drive-cli path configured"
+		return check
+	}
+	if info, err := os.Stat(cliBin); err != nil || info.IsDir() {
+		check.Status, check.Detail = config.CheckFail, cliBin+": not found"
+		return check
+	}
+	out, err := config.Probe(node, cliBin, "--version")
+	if err != nil {
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("%s does not answer: %v", cliBin, err)
+		return check
+	}
+	check.Status, check.Detail, check.Fix = config.CheckOK, fmt.Sprintf("%s (%s)", firstLine(out), cliBin), ""
+	return check
+}
+
+// checkStorage writes a scratch object to the store, reads it back and
+// deletes it. For the sdk backend this also proves that proton-drive-cli
+// can authenticate.
+func (d *doctorRun) checkStorage() config.DoctorCheck {
+	check := config.DoctorCheck{Name: "storage"}
+	fail := func(step string, err error) config.DoctorCheck {
+		code, message := backendErrorDetails(err)
+		_, _, fix := classifyError(code, message)
+		if fix == "" {
+			fix = "Check the storage settings with 'git-lfs-proton-adapter --print-config'"
+		}
+		check.Status, check.Detail, check.Fix = config.CheckFail, fmt.Sprintf("%s: %s", step, firstLine(err.Error())), fix
+		return check
+	}
+
+	start := time.Now()
+	store, err := openStoreSession(d.opts, d.route)
+	if err != nil {
+		return fail("open", err)
+	}
+	defer store.close()
+
+	payload := make([]byte, 64)
+	_, _ = rand.Read(payload)
+	payload = append([]byte("proton-lfs doctor scratch object\n"), hex.EncodeToString(payload)...)
+	sum := sha256.Sum256(payload)
+	oid := hex.EncodeToString(sum[:])
+	src := filepath.Join(os.TempDir(), "proton-lfs-doctor-"+oid[:12])
+	if err := os.WriteFile(src, payload, 0o600); err != nil {
+		return fail("write scratch file", err)
+	}
+	defer func() { _ = os.Remove(src) }()
+
+	if _, err := store.backend.Upload(store.session, oid, src, int64(len(payload)), nil); err != nil {
+		return fail("upload", err)
+	}
+	// Remove the scratch object whatever happens next.
+	deleted := false
+	defer func() {
+		if !deleted {
+			_, _ = store.store.DeleteObjects(store.session, []string{oid})
+		}
+	}()
+
+	exists, err := store.store.ExistsObjects(store.session, []string{oid})
+	if err != nil {
+		return fail("exists", err)
+	}
+	if !exists[oid] {
+		return fail("exists", fmt.Errorf("uploaded object %s is not listed", oid[:12]))
+	}
+	path, _, err := store.backend.Download(store.session, oid, nil)
+	if err != nil {
+		return fail("download", err)
+	}
+	got, err := os.ReadFile(path)
+	_ = os.Remove(path)
+	if err != nil {
+		return fail("download", err)
+	}
+	if !bytes.Equal(got, payload) {
+		return fail("download", errHashMismatch)
+	}
+	if _, err := store.store.DeleteObjects(store.session, []string{oid}); err != nil {
+		return fail("delete", err)
+	}
+	deleted = true
+
+	check.Status = config.CheckOK
+	check.Detail = fmt.Sprintf("%s backend: wrote, read back and deleted a scratch object in %s", store.kind, time.Since(start).Round(time.Millisecond))
+	return check
+}
+
+func printDoctorUsage(w io.Writer, fs *flag.FlagSet) {
+	_, _ = fmt.Fprint(w, `Usage: git-lfs-proton-adapter doctor [flags]
+
+Check what the adapter needs with the given backend flags: the Node.js
+version and proton-drive-cli (sdk backend only), then write, read back and
+delete a scratch object in the store. "proton-lfs-cli doctor" runs these
+checks with the registered flags, along with checks of the git and
+credential setup.
+
+Exit status is 1 when a check fails.
+
+Flags:
+`)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// runDoctor implements the doctor subcommand and returns the process exit
+// code.
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(doctorCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	backendOpts := addBackendFlags(fs)
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	remote := fs.String("remote", "", "Use the storage route configured for this git remote")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			printDoctorUsage(stdout, fs)
+			return 0
+		}
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		printDoctorUsage(stderr, fs)
+		return 2
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(stderr, "error: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+	if _, err := resolveSettings(fs, "."); err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	route, err := remoteRoute(".", *remote)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	run := &doctorRun{opts: backendOpts, route: route}
+	report := config.NewDoctorReport(run.checks())
+	report.Version = Version
+	report.Backend = backendOpts.kindFor(route)
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		report.WriteText(stdout)
+	}
+	if !report.Healthy {
+		return 1
+	}
+	return 0
+}
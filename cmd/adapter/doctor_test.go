@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func TestRunDoctorLocalBackendRoundTrip(t *testing.T) {
+	storeDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := runDoctor([]string{"--backend", "local", "--local-store-dir", storeDir, "--json"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s, stdout = %s", code, stderr.String(), stdout.String())
+	}
+	var report config.DoctorReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if !report.Healthy || report.Backend != BackendLocal {
+		t.Fatalf("report = %+v", report)
+	}
+	statuses := map[string]string{}
+	for _, c := range report.Checks {
+		statuses[c.Name] = c.Status
+	}
+	want := map[string]string{"node": config.CheckSkip, "drive-cli": config.CheckSkip, "storage": config.CheckOK}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("%s = %q, want %q", name, statuses[name], status)
+		}
+	}
+
+	// The scratch object must not be left behind.
+	objects, err := NewLocalStoreBackend(storeDir).ListObjects(&Session{Initialized: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("store holds %d objects after doctor", len(objects))
+	}
+}
+
+func TestRunDoctorFailsOnUnwritableStore(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	storeDir := t.TempDir()
+	if err := os.Chmod(storeDir, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(storeDir, 0o700) })
+
+	var stdout, stderr bytes.Buffer
+	code := runDoctor([]string{"--backend", "local", "--local-store-dir", storeDir}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1\n%s", code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "fail  storage") || !strings.Contains(stdout.String(), "fix: ") {
+		t.Fatalf("output = %q", stdout.String())
+	}
+}
+
+func TestCheckNodeVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		want    string
+	}{
+		{"v20.19.5", config.CheckFail},
+		{"v25.1.0", config.CheckOK},
+		{"v26.0.0-nightly", config.CheckOK},
+		{"not a version", config.CheckWarn},
+	} {
+		bin := writeVersionScript(t, tc.version)
+		if got := checkNode(bin); got.Status != tc.want {
+			t.Errorf("node %q: status = %q (%s), want %q", tc.version, got.Status, got.Detail, tc.want)
+		}
+	}
+	if got := checkNode("/nonexistent/node"); got.Status != config.CheckFail || got.Fix == "" {
+		t.Errorf("missing node: %+v", got)
+	}
+}
+
+// writeVersionScript returns an executable that prints version.
+func writeVersionScript(t *testing.T, version string) string {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	path := t.TempDir() + "/node"
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho '"+version+"'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+const fsckCommand = "fsck"
+
+// Verification modes for fsck --verify.
+const (
+	fsckVerifyNone   = "none"
+	fsckVerifySample = "sample"
+	fsckVerifyAll    = "all"
+)
+
+// DefaultFsckSampleSize is how many present objects --verify sample re-hashes.
+const DefaultFsckSampleSize = 20
+
+// FsckIssue is one object that failed a check.
+type FsckIssue struct {
+	OID    string `json:"oid"`
+	Size   int64  `json:"size,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Reasons for leaving orphans unchecked, reported as FsckReport.OrphanedSkipped.
+const (
+	fsckOrphansNoRepos       = "no repositories scanned"
+	fsckOrphansDisabled      = "disabled with --orphans=false"
+	fsckOrphansNoListSupport = "proton-drive-cli cannot list stored objects, update it"
+)
+
+// FsckReport is the result of an fsck run. Orphaned is only computed when
+// repositories were scanned, since otherwise nothing defines "referenced",
+// and the store can list its objects.
+type FsckReport struct {
+	Backend         string      `json:"backend"`
+	Repositories    []string    `json:"repositories,omitempty"`
+	Checked         int         `json:"checked"`
+	Verified        int         `json:"verified"`
+	Missing         []FsckIssue `json:"missing"`
+	Corrupt         []FsckIssue `json:"corrupt"`
+	Orphaned        []FsckIssue `json:"orphaned"`
+	Errors          []FsckIssue `json:"errors"`
+	OrphanedChecked bool        `json:"orphanedChecked"`
+	OrphanedSkipped string      `json:"orphanedSkipped,omitempty"` // why orphans were not checked
+}
+
+// Healthy reports whether every checked object is present and intact.
+// Orphans are informational: other repositories may share the storage base.
+func (r *FsckReport) Healthy() bool {
+	return len(r.Missing) == 0 && len(r.Corrupt) == 0 && len(r.Errors) == 0
+}
+
+// fsckRun holds the inputs of one fsck run.
+type fsckRun struct {
+	store      *storeSession
+	referenced map[string]int64 // nil in --all mode
+	orphans    bool             // list the store to find orphans in repository mode
+	verify     string
+	sampleSize int
+	shuffle    func(n int, swap func(i, j int))
+}
+
+// check builds the report: existence of every target via the store, orphans
+// when a reference set exists, and re-hashing of the chosen present objects.
+// The store is only listed in --all mode and to find orphans; checking the
+// referenced objects needs nothing but batch-exists.
+func (f *fsckRun) check() (*FsckReport, error) {
+	report := &FsckReport{
+		Missing:  []FsckIssue{},
+		Corrupt:  []FsckIssue{},
+		Orphaned: []FsckIssue{},
+		Errors:   []FsckIssue{},
+	}
+
+	targets := make(map[string]int64)
+	switch {
+	case f.referenced == nil:
+		report.OrphanedSkipped = fsckOrphansNoRepos
+		stored, err := f.store.store.ListObjects(f.store.session)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range stored {
+			targets[obj.OID] = obj.Size
+		}
+	case !f.orphans:
+		report.OrphanedSkipped = fsckOrphansDisabled
+	default:
+		stored, err := f.store.store.ListObjects(f.store.session)
+		switch {
+		case errors.Is(err, errListUnsupported):
+			report.OrphanedSkipped = fsckOrphansNoListSupport
+		case err != nil:
+			return nil, err
+		default:
+			report.OrphanedChecked = true
+			orphans, _ := pruneCandidates(stored, f.referenced, time.Time{})
+			for _, obj := range orphans {
+				report.Orphaned = append(report.Orphaned, FsckIssue{OID: obj.OID, Size: obj.Size})
+			}
+		}
+	}
+	for oid, size := range f.referenced {
+		targets[oid] = size
+	}
+
+	oids := make([]string, 0, len(targets))
+	for oid := range targets {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+	report.Checked = len(oids)
+
+	exists, err := f.store.store.ExistsObjects(f.store.session, oids)
+	if err != nil {
+		return nil, err
+	}
+	var present []string
+	for _, oid := range oids {
+		if exists[oid] {
+			present = append(present, oid)
+		} else {
+			report.Missing = append(report.Missing, FsckIssue{OID: oid, Size: targets[oid]})
+		}
+	}
+
+	for _, oid := range f.verifyTargets(present) {
+		issue, corrupt, err := f.verifyObject(oid, targets[oid])
+		switch {
+		case err != nil:
+			report.Errors = append(report.Errors, FsckIssue{OID: oid, Detail: err.Error()})
+		case corrupt:
+			report.Corrupt = append(report.Corrupt, issue)
+			report.Verified++
+		default:
+			report.Verified++
+		}
+	}
+	return report, nil
+}
+
+// verifyTargets picks which present objects to download and re-hash.
+func (f *fsckRun) verifyTargets(present []string) []string {
+	switch f.verify {
+	case fsckVerifyAll:
+		return present
+	case fsckVerifySample:
+		if len(present) <= f.sampleSize {
+			return present
+		}
+		sample := append([]string(nil), present...)
+		shuffle := rand.Shuffle
+		if f.shuffle != nil {
+			shuffle = f.shuffle
+		}
+		shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+		sample = sample[:f.sampleSize]
+		sort.Strings(sample)
+		return sample
+	default:
+		return nil
+	}
+}
+
+// verifyObject downloads oid and re-hashes it. A hash or size mismatch is a
+// corrupt object; any other failure is returned as an error.
+func (f *fsckRun) verifyObject(oid string, expectedSize int64) (FsckIssue, bool, error) {
+	issue := FsckIssue{OID: oid, Size: expectedSize}
+	tmpPath, _, err := f.store.backend.Download(f.store.session, oid, nil)
+	if err != nil {
+		if errors.Is(err, errHashMismatch) {
+			issue.Detail = "content does not hash to its oid"
+			return issue, true, nil
+		}
+		return issue, false, err
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	hash, size, err := calculateFileSHA256(tmpPath)
+	if err != nil {
+		return issue, false, err
+	}
+	if hash != oid {
+		issue.Detail = "content hashes to " + hash
+		return issue, true, nil
+	}
+	if expectedSize > 0 && size != expectedSize {
+		issue.Detail = fmt.Sprintf("size %d, pointer records %d", size, expectedSize)
+		return issue, true, nil
+	}
+	return issue, false, nil
+}
+
+func printFsckReport(w io.Writer, r *FsckReport) {
+	if len(r.Repositories) > 0 {
+		_, _ = fmt.Fprintf(w, "Checked:  %d objects referenced by %d repositories (%s backend)\n", r.Checked, len(r.Repositories), r.Backend)
+	} else {
+		_, _ = fmt.Fprintf(w, "Checked:  %d stored objects (%s backend)\n", r.Checked, r.Backend)
+	}
+	_, _ = fmt.Fprintf(w, "Verified: %d re-hashed\n", r.Verified)
+	_, _ = fmt.Fprintf(w, "Missing:  %d\n", len(r.Missing))
+	_, _ = fmt.Fprintf(w, "Corrupt:  %d\n", len(r.Corrupt))
+	if r.OrphanedChecked {
+		_, _ = fmt.Fprintf(w, "Orphaned: %d\n", len(r.Orphaned))
+	} else {
+		_, _ = fmt.Fprintf(w, "Orphaned: not checked (%s)\n", r.OrphanedSkipped)
+	}
+	if len(r.Errors) > 0 {
+		_, _ = fmt.Fprintf(w, "Errors:   %d\n", len(r.Errors))
+	}
+	for _, issue := range r.Missing {
+		_, _ = fmt.Fprintf(w, "  missing   %s\n", issue.OID)
+	}
+	for _, issue := range r.Corrupt {
+		_, _ = fmt.Fprintf(w, "  corrupt   %s  %s\n", issue.OID, issue.Detail)
+	}
+	for _, issue := range r.Orphaned {
+		_, _ = fmt.Fprintf(w, "  orphaned  %s  %s\n", issue.OID, formatBytes(issue.Size))
+	}
+	for _, issue := range r.Errors {
+		_, _ = fmt.Fprintf(w, "  error     %s  %s\n", issue.OID, issue.Detail)
+	}
+}
+
+func printFsckUsage(w io.Writer, fs *flag.FlagSet) {
+	_, _ = fmt.Fprint(w, `Usage: git-lfs-proton-adapter fsck [flags] [repo...]
+       git-lfs-proton-adapter fsck --all [flags]
+
+Check that the objects referenced by the given repositories (default: the
+current directory) exist in the store, and report stored objects none of them
+reference. With --all, check every object under the storage base instead.
+--verify sample|all downloads objects and re-hashes them against their OID.
+Finding orphans lists the store; --orphans=false skips it, and it is skipped
+when proton-drive-cli is too old to list objects.
+
+Exit status is 1 when objects are missing, corrupt or could not be verified.
+Orphaned objects are reported but do not fail the check; remove them with prune.
+
+Flags:
+`)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// runFsck implements the fsck subcommand and returns the process exit code.
+func runFsck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(fsckCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	backendOpts := addBackendFlags(fs)
+	all := fs.Bool("all", false, "Check every object under the storage base instead of repository references")
+	verify := fs.String("verify", fsckVerifyNone, "Re-hash stored objects: none, sample or all")
+	sampleSize := fs.Int("sample-size", DefaultFsckSampleSize, "Objects to re-hash with --verify sample")
+	orphans := fs.Bool("orphans", true, "List the store to report objects no repository references")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	remote := fs.String("remote", "", "Use the storage route configured for this git remote")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			printFsckUsage(stdout, fs)
+			return 0
+		}
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		printFsckUsage(stderr, fs)
+		return 2
+	}
+	switch *verify {
+	case fsckVerifyNone, fsckVerifySample, fsckVerifyAll:
+	default:
+		_, _ = fmt.Fprintf(stderr, "error: invalid --verify %q (supported: none, sample, all)\n", *verify)
+		return 2
+	}
+	repos := fs.Args()
+	if *all && len(repos) > 0 {
+		_, _ = fmt.Fprintln(stderr, "error: --all does not take repositories")
+		return 2
+	}
+
+	run := &fsckRun{orphans: *orphans, verify: *verify, sampleSize: *sampleSize}
+	if !*all {
+		if len(repos) == 0 {
+			repos = []string{"."}
+		}
+		referenced, err := referencedObjects(repos, time.Time{})
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+		run.referenced = referenced
+	}
+
+	routeDir := "."
+	if len(repos) > 0 {
+		routeDir = repos[0]
+	}
+	if _, err := resolveSettings(fs, routeDir); err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	route, err := remoteRoute(routeDir, *remote)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	store, err := openStoreSession(backendOpts, route)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	defer store.close()
+	run.store = store
+
+	report, err := run.check()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	report.Backend = store.kind
+	report.Repositories = repos
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		printFsckReport(stdout, report)
+	}
+	if !report.Healthy() {
+		return 1
+	}
+	return 0
+}
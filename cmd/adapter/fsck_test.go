@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// setupFsck returns a repo referencing three objects (intact, missing and
+// corrupt) and a local store that also holds one unreferenced object.
+func setupFsck(t *testing.T) (repo, storeDir string, oids map[string]string) {
+	t.Helper()
+	repo = newTestRepo(t)
+	commitPointer(t, repo, "intact.bin", []byte("intact"))
+	commitPointer(t, repo, "missing.bin", []byte("missing"))
+	commitPointer(t, repo, "corrupt.bin", []byte("corrupt"))
+
+	storeDir = t.TempDir()
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, storeDir)
+	oids = map[string]string{
+		"intact":  seedLocalObject(t, adapter, []byte("intact")),
+		"orphan":  seedLocalObject(t, adapter, []byte("orphan")),
+		"corrupt": seedLocalObject(t, adapter, []byte("corrupt")),
+	}
+	oid, _, _ := parseLFSPointer([]byte(lfsPointer([]byte("missing"))))
+	oids["missing"] = oid
+	if err := os.WriteFile(adapter.localObjectPath(oids["corrupt"]), []byte("bit rot"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return repo, storeDir, oids
+}
+
+func TestRunFsckReportsMissingCorruptAndOrphaned(t *testing.T) {
+	repo, storeDir, oids := setupFsck(t)
+
+	var stdout, stderr bytes.Buffer
+	code := runFsck([]string{"--backend", "local", "--local-store-dir", storeDir, "--verify", "all", repo}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 for an unhealthy store, got %d; stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"Checked:  3 objects referenced by 1 repositories (local backend)",
+		"Verified: 2 re-hashed",
+		"missing   " + oids["missing"],
+		"corrupt   " + oids["corrupt"],
+		"orphaned  " + oids["orphan"],
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, oids["intact"]) {
+		t.Errorf("intact object must not be reported:\n%s", out)
+	}
+}
+
+func TestRunFsckJSON(t *testing.T) {
+	repo, storeDir, oids := setupFsck(t)
+
+	var stdout, stderr bytes.Buffer
+	runFsck([]string{"--backend", "local", "--local-store-dir", storeDir, "--verify", "all", "--json", repo}, &stdout, &stderr)
+
+	var report FsckReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON report: %v\n%s", err, stdout.String())
+	}
+	if report.Checked != 3 || !report.OrphanedChecked {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].OID != oids["missing"] {
+		t.Fatalf("unexpected missing: %+v", report.Missing)
+	}
+	if len(report.Corrupt) != 1 || report.Corrupt[0].OID != oids["corrupt"] {
+		t.Fatalf("unexpected corrupt: %+v", report.Corrupt)
+	}
+	if len(report.Orphaned) != 1 || report.Orphaned[0].OID != oids["orphan"] {
+		t.Fatalf("unexpected orphaned: %+v", report.Orphaned)
+	}
+}
+
+func TestRunFsckAllChecksStorageBase(t *testing.T) {
+	_, storeDir, oids := setupFsck(t)
+
+	var stdout, stderr bytes.Buffer
+	code := runFsck([]string{"--backend", "local", "--local-store-dir", storeDir, "--all", "--verify", "all"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 for the corrupt object, got %d; stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"Checked:  3 stored objects (local backend)",
+		"Missing:  0",
+		"corrupt   " + oids["corrupt"],
+		"Orphaned: not checked",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunFsckHealthyWithoutVerify(t *testing.T) {
+	repo := newTestRepo(t)
+	commitPointer(t, repo, "intact.bin", []byte("intact"))
+	storeDir := t.TempDir()
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, storeDir)
+	seedLocalObject(t, adapter, []byte("intact"))
+
+	var stdout, stderr bytes.Buffer
+	code := runFsck([]string{"--backend", "local", "--local-store-dir", storeDir, repo}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d; output:\n%s%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Verified: 0 re-hashed") {
+		t.Fatalf("expected no re-hashing without --verify:\n%s", stdout.String())
+	}
+}
+
+func TestRunFsckRejectsInvalidFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runFsck([]string{"--verify", "some"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit 2 for an invalid --verify, got %d", code)
+	}
+	if code := runFsck([]string{"--all", "."}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit 2 for --all with repositories, got %d", code)
+	}
+}
+
+func TestFsckVerifyTargetsSample(t *testing.T) {
+	present := []string{"a", "b", "c", "d", "e"}
+	run := &fsckRun{verify: fsckVerifySample, sampleSize: 2, shuffle: func(n int, swap func(i, j int)) {
+		swap(0, 4) // e b c d a
+	}}
+	got := run.verifyTargets(present)
+	if len(got) != 2 || got[0] != "b" || got[1] != "e" {
+		t.Fatalf("unexpected sample: %v", got)
+	}
+	run.sampleSize = 10
+	if got := run.verifyTargets(present); len(got) != 5 {
+		t.Fatalf("expected every object when the sample exceeds the set, got %v", got)
+	}
+	run.verify = fsckVerifyNone
+	if got := run.verifyTargets(present); len(got) != 0 {
+		t.Fatalf("expected no targets without --verify, got %v", got)
+	}
+}
+
+func TestFsckDriveCLIBackendDetectsCorruptDownload(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_LIST="+validOID+":21")
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	run := &fsckRun{
+		store:  &storeSession{backend: backend, store: backend, session: session},
+		verify: fsckVerifyAll,
+	}
+	report, err := run.check()
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	// The mock download content does not hash to validOID.
+	if report.Checked != 1 || len(report.Missing) != 0 || len(report.Corrupt) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !strings.Contains(report.Corrupt[0].Detail, "content hashes to") {
+		t.Fatalf("unexpected corrupt detail: %q", report.Corrupt[0].Detail)
+	}
+}
+
+func TestFsckRepoModeWithoutListCapability(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_CAPABILITIES=progress,serve,chunks")
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	run := &fsckRun{
+		store:      &storeSession{backend: backend, store: backend, session: session},
+		referenced: map[string]int64{validOID: 21},
+		orphans:    true,
+	}
+	report, err := run.check()
+	if err != nil {
+		t.Fatalf("check must not fail when the bridge cannot list: %v", err)
+	}
+	if report.Checked != 1 || len(report.Missing) != 0 {
+		t.Fatalf("referenced objects must still be checked: %+v", report)
+	}
+	if report.OrphanedChecked || report.OrphanedSkipped != fsckOrphansNoListSupport {
+		t.Fatalf("expected orphans not checked for lack of list support: %+v", report)
+	}
+
+	var out bytes.Buffer
+	printFsckReport(&out, report)
+	if !strings.Contains(out.String(), "Orphaned: not checked (proton-drive-cli cannot list stored objects, update it)") {
+		t.Fatalf("unexpected report output:\n%s", out.String())
+	}
+
+	run.referenced = nil
+	if _, err := run.check(); !errors.Is(err, errListUnsupported) {
+		t.Fatalf("--all needs the list capability, got %v", err)
+	}
+}
+
+func TestRunFsckOrphansDisabled(t *testing.T) {
+	repo, storeDir, oids := setupFsck(t)
+
+	var stdout, stderr bytes.Buffer
+	code := runFsck([]string{"--backend", "local", "--local-store-dir", storeDir, "--orphans=false", repo}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 for the missing object, got %d; stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "Orphaned: not checked (disabled with --orphans=false)") {
+		t.Errorf("expected orphans to be skipped:\n%s", out)
+	}
+	if strings.Contains(out, oids["orphan"]) {
+		t.Errorf("orphan must not be reported when disabled:\n%s", out)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// transferStart is when an in-flight transfer began and its requested size.
+type transferStart struct {
+	at   time.Time
+	size int64
+}
+
+// transferOutcome describes how a transfer ended.
+type transferOutcome struct {
+	size         int64
+	retries      int
+	state        string // config.StateOK or the classified error state
+	code         int    // error code sent to git-lfs; 0 on success
+	errorCode    string
+	message      string
+	deduplicated bool // upload skipped, the object was already stored
+}
+
+// transferStarted records when the transfer of oid began and publishes it in
+// the process status record.
+func (a *Adapter) transferStarted(oid string, size int64) {
+	oid = strings.ToLower(oid)
+	a.started.Store(oid, transferStart{at: time.Now(), size: size})
+	a.status.begin(string(a.currentOperation), oid, size)
+}
+
+// transferFinished ends the transfer of oid: it leaves the process status
+// record, is appended to the history journal and counted in the metrics. It
+// returns the transfer's log attributes.
+func (a *Adapter) transferFinished(oid string, outcome transferOutcome) []any {
+	oid = strings.ToLower(oid)
+	a.status.end(oid)
+
+	entry := config.HistoryEntry{
+		OID:          oid,
+		Size:         outcome.size,
+		Direction:    string(a.currentOperation),
+		State:        outcome.state,
+		Repo:         a.repoPath(),
+		Remote:       a.remote,
+		Backend:      a.backendKind,
+		Retries:      outcome.retries,
+		Deduplicated: outcome.deduplicated,
+		ErrorCode:    outcome.errorCode,
+		Error:        outcome.message,
+	}
+	attrs := []any{"op", entry.Direction, "oid", oid}
+	if v, ok := a.started.LoadAndDelete(oid); ok {
+		start := v.(transferStart)
+		entry.DurationMS = time.Since(start.at).Milliseconds()
+		if entry.Size == 0 {
+			entry.Size = start.size
+		}
+		attrs = append(attrs, "duration_ms", entry.DurationMS)
+	}
+	if entry.Size > 0 {
+		attrs = append(attrs, "size", entry.Size)
+	}
+	attrs = append(attrs, "retries", outcome.retries)
+	if outcome.deduplicated {
+		attrs = append(attrs, "deduplicated", true)
+	}
+	if outcome.code != 0 {
+		attrs = append(attrs, "code", outcome.code, "error_code", outcome.errorCode, "error", outcome.message)
+	}
+	if a.history != nil {
+		_ = a.history(entry)
+	}
+	a.metrics.recordTransfer(entry)
+	return attrs
+}
+
+// repoPath returns the top of the repository the adapter runs in, or the
+// working directory outside of one. git-lfs starts the adapter inside the
+// repository.
+func (a *Adapter) repoPath() string {
+	a.repoOnce.Do(func() {
+		if out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+			a.repo = strings.TrimSpace(string(out))
+			return
+		}
+		a.repo, _ = os.Getwd()
+	})
+	return a.repo
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func TestTransfersAreRecordedInHistory(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	t.Setenv(config.EnvHistoryFile, filepath.Join(t.TempDir(), "history.jsonl"))
+
+	adapter := NewAdapter()
+	adapter.history = config.AppendHistory
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.repo = "/work/app"
+	adapter.repoOnce.Do(func() {})
+
+	payload := []byte("history payload")
+	oid := oidOf(payload)
+	missing := oidOf([]byte("not stored"))
+	input := strings.Join([]string{
+		`{"event":"init","operation":"upload","remote":"origin","concurrent":false,"concurrenttransfers":1}`,
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":%d,"path":%q,"action":null}`, oid, len(payload), writeTempObject(t, payload)),
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":%d,"path":%q,"action":null}`, oid, len(payload), writeTempObject(t, payload)),
+		`{"event":"terminate"}`,
+		`{"event":"init","operation":"download","remote":"origin","concurrent":false,"concurrenttransfers":1}`,
+		fmt.Sprintf(`{"event":"download","oid":"%s","size":5,"action":null}`, missing),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+	if err := adapter.Run(strings.NewReader(input), new(bytes.Buffer)); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	entries, err := config.ReadHistory(config.HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 history entries, got %+v", entries)
+	}
+	upload := entries[0]
+	if upload.OID != oid || upload.Direction != "upload" || upload.State != config.StateOK || upload.Size != int64(len(payload)) ||
+		upload.Repo != "/work/app" || upload.Remote != "origin" || upload.Backend != BackendLocal {
+		t.Fatalf("unexpected upload entry: %+v", upload)
+	}
+	if entries[1].State != config.StateOK {
+		t.Fatalf("the repeated upload should succeed: %+v", entries[1])
+	}
+	failed := entries[2]
+	if failed.OID != missing || failed.Direction != "download" || failed.State != config.StateError ||
+		failed.ErrorCode != "not_found" || failed.Size != 5 || failed.Error == "" {
+		t.Fatalf("unexpected failure entry: %+v", failed)
+	}
+}
+
+func TestTransferFinishedForgetsStart(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	t.Setenv(config.EnvHistoryFile, filepath.Join(t.TempDir(), "history.jsonl"))
+	adapter := NewAdapter()
+	adapter.currentOperation = DirectionDownload
+	adapter.transferStarted("ABC", 3)
+
+	attrs := adapter.transferFinished("abc", transferOutcome{state: config.StateOK})
+	if len(attrs) != 10 || attrs[1] != "download" || attrs[3] != "abc" || attrs[4] != "duration_ms" || attrs[7] != int64(3) {
+		t.Fatalf("transferFinished() = %v", attrs)
+	}
+	if attrs := adapter.transferFinished("abc", transferOutcome{state: config.StateOK}); len(attrs) != 6 {
+		t.Fatalf("expected no duration once the transfer was logged, got %v", attrs)
+	}
+	// An Adapter built outside main has no history sink.
+	if _, err := os.Stat(config.HistoryFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("expected no history journal without a sink, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"proton-lfs-cli/internal/logging"
+)
+
+// adapterLogName names the adapter's files in the log directory:
+// adapter-<date>.log.
+const adapterLogName = "adapter"
+
+// newSessionID returns a short random ID that ties together the log records
+// of one adapter process.
+func newSessionID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// configureLogging writes the adapter's log as JSON lines at level to the
+// rotating adapter log file and, with debug, as text to stderr. Without a
+// usable log file only stderr remains.
+func (a *Adapter) configureLogging(level slog.Level, debug bool) {
+	var handlers []slog.Handler
+	file, err := logging.OpenAppLog(adapterLogName)
+	if err == nil {
+		handlers = append(handlers, logging.NewJSONHandler(file, level))
+	}
+	if debug {
+		handlers = append(handlers, logging.NewTextHandler(os.Stderr, slog.LevelDebug))
+	}
+	a.logger = slog.New(logging.Fanout(handlers...)).With("session_id", a.sessionID)
+	if err != nil {
+		a.logger.Warn("Adapter log file unavailable", "error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// readLogRecords decodes every JSON line of the adapter log files in dir.
+func readLogRecords(t *testing.T, dir string) []map[string]any {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, adapterLogName+"-*.log"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one adapter log file in %s, got %v (%v)", dir, files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line is not JSON: %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func findRecord(records []map[string]any, msg string) map[string]any {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestAdapterLogsTransfersAsJSON(t *testing.T) {
+	logDir := t.TempDir()
+	t.Setenv(config.EnvLogDir, logDir)
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	t.Setenv(config.EnvHistoryFile, filepath.Join(t.TempDir(), "history.jsonl"))
+
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.configureLogging(slog.LevelInfo, false)
+
+	payload := []byte("logged payload")
+	oid := oidOf(payload)
+	missing := oidOf([]byte("never stored"))
+	input := strings.Join([]string{
+		`{"event":"init","operation":"upload","concurrent":false,"concurrenttransfers":1}`,
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":%d,"path":%q,"action":null}`, oid, len(payload), writeTempObject(t, payload)),
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":1,"path":%q,"action":null}`, missing, filepath.Join(t.TempDir(), "gone.bin")),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+	if err := adapter.Run(strings.NewReader(input), new(bytes.Buffer)); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	records := readLogRecords(t, logDir)
+	for _, r := range records {
+		if r["session_id"] != adapter.sessionID {
+			t.Fatalf("record without the session ID: %v", r)
+		}
+		if r["level"] == "DEBUG" {
+			t.Fatalf("debug record written at info level: %v", r)
+		}
+	}
+	done := findRecord(records, "Upload complete")
+	if done == nil || done["oid"] != oid || done["op"] != "upload" || done["duration_ms"] == nil {
+		t.Fatalf("unexpected upload record: %v", done)
+	}
+	failed := findRecord(records, "Transfer failed")
+	if failed == nil || failed["oid"] != missing || failed["code"] != float64(404) || failed["error_code"] != "not_found" || failed["level"] != "ERROR" {
+		t.Fatalf("unexpected failure record: %v", failed)
+	}
+}
@@ -8,14 +8,16 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"proton-lfs-cli/internal/config"
+	"proton-lfs-cli/internal/logging"
 )
 
 const (
@@ -53,7 +55,8 @@ const (
 // Adapter manages the transfer session with Git LFS
 type Adapter struct {
 	driveCLIBin        string
-	logger             *log.Logger
+	logger             *slog.Logger
+	sessionID          string
 	session            *Session
 	currentOperation   Direction
 	allowMockTransfers bool
@@ -61,6 +64,38 @@ type Adapter struct {
 	backendKind        string
 	backend            TransferBackend
 	credentialProvider string
+	retry              RetryPolicy
+	transferWorkers    int
+	pool               *transferPool
+
+	// routes select a backend per git remote at init; newBackend builds it.
+	routes     []config.Route
+	newBackend func(route *config.Route) (TransferBackend, error)
+	remoteURL  func(remote string) string
+
+	// paused reports whether the user paused transfers; nil never pauses.
+	// pauseMode selects whether init then fails or waits.
+	paused    func() bool
+	pauseMode string
+	pausePoll time.Duration
+
+	// started holds when each in-flight transfer began, by OID, so that its
+	// log record can carry the duration. status publishes the transfers to
+	// this process's status record, and metrics pushes their samples to the
+	// metrics collector. main sets both; nil publishes nothing, so an Adapter
+	// built elsewhere leaves the user's status directory and collector alone.
+	started sync.Map
+	status  *processStatus
+	metrics *metricsPusher
+	// history appends each finished transfer to the history journal. main
+	// sets it; nil records nothing.
+	history func(config.HistoryEntry) error
+
+	// remote is the git remote announced at init; repo is resolved once by
+	// repoPath. Both are recorded in the transfer history.
+	remote   string
+	repo     string
+	repoOnce sync.Once
 }
 
 // Message received from Git LFS
@@ -108,39 +143,92 @@ type Session struct {
 
 // NewAdapter creates a new adapter instance
 func NewAdapter() *Adapter {
+	sessionID := newSessionID()
 	adapter := &Adapter{
-		logger:             log.New(os.Stderr, Name+": ", log.LstdFlags),
+		logger:             slog.New(logging.NewTextHandler(os.Stderr, slog.LevelDebug)).With("session_id", sessionID),
+		sessionID:          sessionID,
 		currentOperation:   "",
 		allowMockTransfers: false,
 		localStoreDir:      envTrim(EnvLocalStoreDir),
 		backendKind:        BackendLocal,
+		retry:              DefaultRetryPolicy(),
 	}
 	adapter.backend = NewLocalStoreBackend(adapter.localStoreDir)
 	return adapter
 }
 
-// Run starts the adapter's main message loop
+// Run starts the adapter's main message loop. Uploads and downloads are
+// handed to a worker pool when init negotiated more than one worker; every
+// other message waits for in-flight transfers to finish first. Uploads that
+// are already queued are batched into one existence check. The process status
+// record is removed on every return.
 func (a *Adapter) Run(r io.Reader, w io.Writer) error {
-	decoder := json.NewDecoder(r)
-	encoder := json.NewEncoder(w)
+	defer a.status.close()
+	done := make(chan struct{})
+	defer close(done)
+	msgs := readMessages(r, done)
 
+	out := &syncWriter{w: w}
+	encoder := json.NewEncoder(out)
+
+	var leftover *inboundResult
 	for {
-		var msg InboundMessage
-		err := decoder.Decode(&msg)
-		if err != nil {
-			if err == io.EOF {
+		if a.pool != nil {
+			if err := a.pool.firstErr(); err != nil {
+				_ = a.drainWorkers()
+				return err
+			}
+		}
+
+		var in inboundResult
+		if leftover != nil {
+			in, leftover = *leftover, nil
+		} else {
+			in = <-msgs
+		}
+		if in.err != nil {
+			if drainErr := a.drainWorkers(); drainErr != nil {
+				return drainErr
+			}
+			if in.err == io.EOF {
 				return nil // Clean shutdown
 			}
-			return a.sendProtocolError(encoder, 1, "failed to decode message: "+err.Error())
+			return a.sendProtocolError(encoder, 1, "failed to decode message: "+in.err.Error())
 		}
 
-		if err := a.handleMessage(&msg, encoder); err != nil {
-			a.logger.Printf("Error handling message: %v", err)
-			return err
+		batch := []InboundMessage{in.msg}
+		if in.msg.Event == EventUpload {
+			batch, leftover = collectUploadBatch(in.msg, msgs)
+			a.prefetchExists(batch)
+		}
+		for i := range batch {
+			if err := a.dispatch(&batch[i], out, encoder); err != nil {
+				return err
+			}
 		}
 	}
 }
 
+// dispatch routes one message to the worker pool or handles it inline.
+func (a *Adapter) dispatch(msg *InboundMessage, out io.Writer, enc *json.Encoder) error {
+	if (msg.Event == EventUpload || msg.Event == EventDownload) && a.transferWorkers > 1 {
+		if a.pool == nil {
+			a.pool = a.newTransferPool(a.transferWorkers, out)
+		}
+		a.pool.submit(*msg)
+		return nil
+	}
+
+	if err := a.drainWorkers(); err != nil {
+		return err
+	}
+	if err := a.handleMessage(msg, enc); err != nil {
+		a.logger.Error("Error handling message", "event", msg.Event, "error", err)
+		return err
+	}
+	return nil
+}
+
 // handleMessage processes a single message from Git LFS
 func (a *Adapter) handleMessage(msg *InboundMessage, enc *json.Encoder) error {
 	switch msg.Event {
@@ -159,13 +247,27 @@ func (a *Adapter) handleMessage(msg *InboundMessage, enc *json.Encoder) error {
 
 // handleInit initializes the transfer session
 func (a *Adapter) handleInit(msg *InboundMessage, enc *json.Encoder) error {
-	a.logger.Printf("Initializing adapter for %s operation", msg.Operation)
+	a.logger.Info("Initializing adapter", "op", string(msg.Operation), "remote", msg.Remote)
 
 	if msg.Operation != DirectionUpload && msg.Operation != DirectionDownload {
 		return a.sendProtocolError(enc, 400, "invalid operation for init")
 	}
 
+	// 423 Locked: the pause is deliberate, so nothing should retry it.
+	if !a.waitUntilResumed() {
+		return a.sendProtocolError(enc, 423, pausedMessage)
+	}
+
+	a.remote = msg.Remote
+	if err := a.routeBackend(msg.Remote); err != nil {
+		return a.sendProtocolError(enc, 400, err.Error())
+	}
+
 	a.currentOperation = msg.Operation
+	a.transferWorkers = transferWorkerCount(msg)
+	if setter, ok := a.backend.(concurrencySetter); ok {
+		setter.SetConcurrency(a.transferWorkers)
+	}
 
 	// Initialize session with Proton LFS bridge
 	a.session = &Session{
@@ -186,14 +288,13 @@ func (a *Adapter) handleInit(msg *InboundMessage, enc *json.Encoder) error {
 		return a.sendProtocolError(enc, code, message)
 	}
 
-	_ = config.WriteStatus(config.StatusReport{State: config.StateIdle, LastOp: "init"})
 	// Send empty response to indicate success
 	return enc.Encode(OutboundMessage{})
 }
 
 // handleUpload processes a file upload request
 func (a *Adapter) handleUpload(msg *InboundMessage, enc *json.Encoder) error {
-	a.logger.Printf("Upload request: OID=%s Size=%d Path=%s", msg.OID, msg.Size, msg.Path)
+	a.logger.Debug("Upload request", "oid", msg.OID, "size", msg.Size, "path", msg.Path)
 
 	if err := a.validateTransferRequest(msg, true); err != nil {
 		return a.sendTransferError(enc, msg.OID, 400, err.Error())
@@ -202,6 +303,7 @@ func (a *Adapter) handleUpload(msg *InboundMessage, enc *json.Encoder) error {
 	if a.allowMockTransfers {
 		return a.handleMockUpload(msg, enc)
 	}
+	a.transferStarted(msg.OID, msg.Size)
 
 	if a.backend == nil {
 		return a.sendTransferError(enc, msg.OID, 500, "transfer backend is not configured")
@@ -221,27 +323,59 @@ func (a *Adapter) handleUpload(msg *InboundMessage, enc *json.Encoder) error {
 	if hash != normalizedOID {
 		return a.sendTransferError(enc, msg.OID, 409, "upload content hash does not match oid")
 	}
+	if cache, ok := a.backend.(existenceCache); ok && cache.KnownToExist(normalizedOID) {
+		return a.completeKnownUpload(enc, normalizedOID, sourceSize)
+	}
 
-	storedSize, err := a.backend.Upload(a.session, normalizedOID, msg.Path, sourceSize)
+	progress := a.newProgressReporter(enc, normalizedOID, sourceSize)
+	var storedSize int64
+	retries, err := a.retry.Do(func() error {
+		var uploadErr error
+		storedSize, uploadErr = a.backend.Upload(a.session, normalizedOID, msg.Path, sourceSize, progress.report)
+		return uploadErr
+	})
 	if err != nil {
-		code, message := backendErrorDetails(err)
-		return a.sendTransferError(enc, msg.OID, code, message)
+		if encErr := progress.encodeErr(); encErr != nil {
+			return encErr
+		}
+		return a.sendBackendError(enc, msg.OID, err, retries)
 	}
 
-	if err := a.sendProgressSequence(enc, normalizedOID, storedSize); err != nil {
+	if err := progress.finish(storedSize); err != nil {
 		return err
 	}
 
-	_ = config.WriteStatus(config.StatusReport{State: config.StateOK, LastOID: normalizedOID, LastOp: "upload"})
+	outcome := transferOutcome{size: storedSize, retries: retries, state: config.StateOK}
+	if d, ok := a.backend.(dedupReporter); ok {
+		outcome.deduplicated = d.TakeDeduplicated(normalizedOID)
+	}
+	a.logger.Info("Upload complete", a.transferFinished(normalizedOID, outcome)...)
+	_ = config.WriteStatus(config.StatusReport{State: config.StateOK, LastOID: normalizedOID, LastOp: "upload", RetryCount: retries})
 	return enc.Encode(OutboundMessage{
 		Event: EventComplete,
 		OID:   normalizedOID,
 	})
 }
 
+// completeKnownUpload answers an upload whose object the remote already has,
+// without transferring the source file. The caller has checked that the
+// source hashes to oid.
+func (a *Adapter) completeKnownUpload(enc *json.Encoder, oid string, size int64) error {
+	a.logger.Info("Upload skipped, object already stored", a.transferFinished(oid, transferOutcome{size: size, state: config.StateOK, deduplicated: true})...)
+
+	if err := a.sendProgressSequence(enc, oid, size); err != nil {
+		return err
+	}
+	_ = config.WriteStatus(config.StatusReport{State: config.StateOK, LastOID: oid, LastOp: "upload"})
+	return enc.Encode(OutboundMessage{
+		Event: EventComplete,
+		OID:   oid,
+	})
+}
+
 // handleDownload processes a file download request
 func (a *Adapter) handleDownload(msg *InboundMessage, enc *json.Encoder) error {
-	a.logger.Printf("Download request: OID=%s Size=%d", msg.OID, msg.Size)
+	a.logger.Debug("Download request", "oid", msg.OID, "size", msg.Size)
 
 	if err := a.validateTransferRequest(msg, false); err != nil {
 		return a.sendTransferError(enc, msg.OID, 400, err.Error())
@@ -250,16 +384,26 @@ func (a *Adapter) handleDownload(msg *InboundMessage, enc *json.Encoder) error {
 	if a.allowMockTransfers {
 		return a.handleMockDownload(msg, enc)
 	}
+	a.transferStarted(msg.OID, msg.Size)
 
 	if a.backend == nil {
 		return a.sendTransferError(enc, msg.OID, 500, "transfer backend is not configured")
 	}
 
 	normalizedOID := strings.ToLower(msg.OID)
-	stagedPath, stagedSize, err := a.backend.Download(a.session, normalizedOID)
+	progress := a.newProgressReporter(enc, normalizedOID, msg.Size)
+	var stagedPath string
+	var stagedSize int64
+	retries, err := a.retry.Do(func() error {
+		var downloadErr error
+		stagedPath, stagedSize, downloadErr = a.backend.Download(a.session, normalizedOID, progress.report)
+		return downloadErr
+	})
 	if err != nil {
-		code, message := backendErrorDetails(err)
-		return a.sendTransferError(enc, msg.OID, code, message)
+		if encErr := progress.encodeErr(); encErr != nil {
+			return encErr
+		}
+		return a.sendBackendError(enc, msg.OID, err, retries)
 	}
 
 	objectHash, objectSize, err := calculateFileSHA256(stagedPath)
@@ -279,12 +423,13 @@ func (a *Adapter) handleDownload(msg *InboundMessage, enc *json.Encoder) error {
 		stagedSize = objectSize
 	}
 
-	if err := a.sendProgressSequence(enc, normalizedOID, stagedSize); err != nil {
+	if err := progress.finish(stagedSize); err != nil {
 		_ = os.Remove(stagedPath)
 		return err
 	}
 
-	_ = config.WriteStatus(config.StatusReport{State: config.StateOK, LastOID: normalizedOID, LastOp: "download"})
+	a.logger.Info("Download complete", a.transferFinished(normalizedOID, transferOutcome{size: stagedSize, retries: retries, state: config.StateOK})...)
+	_ = config.WriteStatus(config.StatusReport{State: config.StateOK, LastOID: normalizedOID, LastOp: "download", RetryCount: retries})
 	return enc.Encode(OutboundMessage{
 		Event: EventComplete,
 		OID:   normalizedOID,
@@ -294,9 +439,15 @@ func (a *Adapter) handleDownload(msg *InboundMessage, enc *json.Encoder) error {
 
 // handleTerminate closes the transfer session
 func (a *Adapter) handleTerminate(_ *InboundMessage, _ *json.Encoder) error {
-	a.logger.Println("Terminating adapter")
+	a.logger.Info("Terminating adapter")
 	a.session = nil
-	_ = config.WriteStatus(config.StatusReport{State: config.StateIdle, LastOp: "terminate"})
+	if closer, ok := a.backend.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			a.logger.Warn("Failed to close backend", "error", err)
+		}
+	}
+	a.status.close()
+	a.metrics.close()
 	return nil
 }
 
@@ -389,10 +540,22 @@ func classifyError(code int, message string) (state string, errorCode string, er
 }
 
 func (a *Adapter) sendTransferError(enc *json.Encoder, oid string, code int, message string) error {
-	a.logger.Printf("Error [%d]: %s", code, message)
+	return a.sendTransferErrorWithRetries(enc, oid, code, message, 0)
+}
+
+// sendBackendError reports a failed backend call, recording how many retries
+// were attempted before giving up.
+func (a *Adapter) sendBackendError(enc *json.Encoder, oid string, err error, retries int) error {
+	code, message := backendErrorDetails(err)
+	return a.sendTransferErrorWithRetries(enc, oid, code, message, retries)
+}
 
+func (a *Adapter) sendTransferErrorWithRetries(enc *json.Encoder, oid string, code int, message string, retries int) error {
 	// Classify error to determine appropriate status state and metadata
 	state, errorCode, errorDetail := classifyError(code, message)
+	a.logger.Error("Transfer failed", a.transferFinished(oid, transferOutcome{
+		retries: retries, state: state, code: code, errorCode: errorCode, message: message,
+	})...)
 
 	_ = config.WriteStatus(config.StatusReport{
 		State:       state,
@@ -400,6 +563,7 @@ func (a *Adapter) sendTransferError(enc *json.Encoder, oid string, code int, mes
 		Error:       message,
 		ErrorCode:   errorCode,
 		ErrorDetail: errorDetail,
+		RetryCount:  retries,
 	})
 
 	return enc.Encode(OutboundMessage{
@@ -413,7 +577,7 @@ func (a *Adapter) sendTransferError(enc *json.Encoder, oid string, code int, mes
 }
 
 func (a *Adapter) sendProtocolError(enc *json.Encoder, code int, message string) error {
-	a.logger.Printf("Protocol error [%d]: %s", code, message)
+	a.logger.Error("Protocol error", "code", code, "error", message)
 	return enc.Encode(OutboundMessage{
 		Error: &ErrorInfo{
 			Code:    code,
@@ -435,8 +599,11 @@ func (a *Adapter) sendProgressSequence(enc *json.Encoder, oid string, totalSize
 	if totalSize <= 0 {
 		return a.sendProgress(enc, oid, 0)
 	}
+	return a.sendProgressFrom(enc, oid, 0, totalSize)
+}
 
-	var bytesSoFar int64
+// sendProgressFrom emits chunked progress events covering (bytesSoFar, totalSize].
+func (a *Adapter) sendProgressFrom(enc *json.Encoder, oid string, bytesSoFar, totalSize int64) error {
 	for bytesSoFar < totalSize {
 		nextBytes := bytesSoFar + progressChunkSize
 		if nextBytes > totalSize {
@@ -455,6 +622,72 @@ func (a *Adapter) sendProgressSequence(enc *json.Encoder, oid string, totalSize
 	return nil
 }
 
+// progressReporter relays backend progress to git-lfs while a transfer is in
+// flight. Reported byte counts are kept monotonic and capped at the expected
+// object size so bytesSinceLast is never negative.
+type progressReporter struct {
+	adapter *Adapter
+	enc     *json.Encoder
+	oid     string
+	limit   int64
+
+	mu   sync.Mutex
+	sent int64
+	err  error
+}
+
+func (a *Adapter) newProgressReporter(enc *json.Encoder, oid string, limit int64) *progressReporter {
+	return &progressReporter{adapter: a, enc: enc, oid: oid, limit: limit}
+}
+
+// report is a ProgressFunc. Encoder failures are remembered and surfaced by
+// finish, since the backend cannot act on them.
+func (p *progressReporter) report(bytesSoFar int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return
+	}
+	if p.limit > 0 && bytesSoFar > p.limit {
+		bytesSoFar = p.limit
+	}
+	if bytesSoFar <= p.sent {
+		return
+	}
+	p.err = p.enc.Encode(OutboundMessage{
+		Event:      EventProgress,
+		OID:        p.oid,
+		BytesSoFar: bytesSoFar,
+		BytesSince: bytesSoFar - p.sent,
+	})
+	p.sent = bytesSoFar
+	p.adapter.status.progress(p.oid, bytesSoFar)
+}
+
+// encodeErr returns the first encoder failure seen while streaming progress.
+func (p *progressReporter) encodeErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// finish tops up progress to totalSize once the backend has returned, so the
+// last progress event always carries the full object size. Backends that did
+// not stream anything get the usual chunked sequence.
+func (p *progressReporter) finish(totalSize int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	if p.sent == 0 {
+		return p.adapter.sendProgressSequence(p.enc, p.oid, totalSize)
+	}
+	return p.adapter.sendProgressFrom(p.enc, p.oid, p.sent, totalSize)
+}
+
 func (a *Adapter) localObjectPath(oid string) string {
 	if len(oid) < 4 {
 		return filepath.Join(a.localStoreDir, oid)
@@ -632,15 +865,30 @@ PROTOCOL COMPLIANCE (submodules/git-lfs/docs/custom-transfers.md)
       - init (upload/download operation)
       - upload with SHA-256 integrity verification
       - download with temp file path return
-      - progress reporting (64KB chunks)
+      - progress reporting (streamed from proton-drive-cli, 64KB chunks otherwise)
       - complete with per-object error handling
       - terminate with credential zeroing
       - standalone mode (action: null, no batch API)
       - concurrent instances (git-lfs spawns multiple adapter processes)
+      - in-process worker pool sized from concurrenttransfers when
+        lfs.customtransfer.proton.concurrent=false
+
+    Retry:
+      - 5xx, timeout and concurrency-limit failures are retried with
+        exponential backoff and jitter (401, 407 and 409 never are)
+
+    Resume:
+      - chunked sdk transfers record finished chunks in a journal
+        (~/.proton-lfs/resume); rerunning an interrupted git-lfs command
+        continues from the last chunk, and the reassembled download is
+        SHA-256 verified before completion
+      - whole-object downloads continue from the staged bytes when
+        proton-drive-cli reports the range capability
+      - a lock per object and target keeps two adapters from sharing
+        a journal entry or staged file
 
     Not implemented:
-      - Real-time streaming progress (progress is post-transfer)
-      - Resume/retry on transient failure
+      - Resume within uploads below the chunk threshold
       - Verify action (not required per spec)
 
 BACKENDS
@@ -648,7 +896,20 @@ BACKENDS
             Objects stored at: <store-dir>/<oid[0:2]>/<oid[2:4]>/<oid>
     sdk     Proton Drive via proton-drive-cli subprocess.
             Objects stored at: /LFS/<oid[0:2]>/<oid[2:4]>/<oid>
-            Upload deduplication via existence check before transfer.
+            Upload deduplication via existence check before transfer;
+            queued uploads are checked together with one batch-exists call.
+            With --cache, downloads are served from a SHA-256 verified,
+            size-bounded LRU cache (~/.proton-lfs/cache) that transfers fill.
+            Objects over 2 GiB are stored as 256 MiB chunks plus a manifest
+            in the object's folder, transferred 4 chunks at a time; an
+            interrupted upload only re-sends the chunks that are missing.
+
+BRIDGE MODES (sdk backend only)
+    subprocess (default)
+            One proton-drive-cli process per bridge command.
+    daemon  One long-lived "proton-drive-cli bridge serve" process per
+            session, exchanging line-framed JSON requests tagged with IDs.
+            Health-checked on start and restarted if it crashes.
 
 CREDENTIAL PROVIDERS (sdk backend only)
     pass-cli (default)
@@ -658,6 +919,59 @@ CREDENTIAL PROVIDERS (sdk backend only)
             Credentials resolved by proton-drive-cli via git credential fill.
             Setup: proton-drive credential store -u <email>
 
+CONFIGURATION
+    Each setting is taken from the first of: a flag, its environment
+    variable, git config lfs.proton.<flag> (any scope), the repository's
+    .lfsconfig, preferences (~/.proton-lfs/config.json, as written by
+    "proton-lfs-cli config set"), then the default. backend, local-store-dir,
+    storage-base, credential-provider, profile, timeout, concurrency,
+    queue-timeout, pause-mode and log-level can be set in git config, e.g.
+    git config lfs.proton.storage-base LFS/work. A committed .lfsconfig can
+    only set timeout and log-level.
+    --print-config shows every effective value and where it came from.
+
+PAUSE
+    "proton-lfs-cli pause" (or the tray menu) pauses all transfers until
+    "proton-lfs-cli resume". While paused, init fails at once with error 423
+    and no request reaches Proton, or with --pause-mode wait blocks until
+    transfers are resumed.
+
+LOGGING
+    Every run appends JSON lines to ~/.proton-lfs/logs/adapter-<date>.log
+    at --log-level (default: info). Each record carries the process's
+    session_id; transfer records add op, oid, duration_ms, and on failure
+    code and error_code. A file is rotated when it would exceed 10 MiB and
+    logs older than 14 days are removed. Tokens and session IDs are
+    redacted. --debug also writes every record to stderr as text.
+
+ROUTING
+    Routes in ~/.proton-lfs/config.json map the git remote announced at
+    init (by name or URL pattern) to its own backend, storage base,
+    credential provider or local store directory. The first matching
+    route wins; other remotes use the flags below.
+
+PROFILES
+    The sdk backend uses the account profile named by the route, then
+    --profile (or lfs.proton.profile), then the active profile
+    in ~/.proton-lfs/config.json. A named profile has its own proton-drive-cli
+    session, credential provider, account and storage base.
+
+COMMANDS
+    prune [flags] [repo...]
+            Delete stored objects that no LFS pointer in the given
+            repositories references (all refs plus a reflog retention
+            window). Supports --dry-run and asks before deleting.
+            See "git-lfs-proton-adapter prune --help".
+    fsck [flags] [repo...]
+            Report referenced objects that are missing from the store,
+            optionally download and re-hash them (--verify sample|all),
+            and list orphaned objects. --all checks the whole storage
+            base; --json prints a machine-readable report.
+    doctor [flags]
+            Check the Node.js version and proton-drive-cli (sdk backend),
+            then write, read back and delete a scratch object in the
+            store. --json prints a machine-readable report.
+
 SECURITY
     - SHA-256 verification on upload and download
     - OID validation: /^[a-f0-9]{64}$/i
@@ -665,7 +979,9 @@ SECURITY
     - Credentials passed via stdin JSON (not visible in ps)
     - Credential buffers zeroed on terminate
     - Subprocess environment filtered via allowlist
-    - Subprocess concurrency limit: 10 max, 5-min timeout
+    - Concurrency limit: 10 proton-drive-cli commands across all adapters on
+      the machine, 5-min timeout (--concurrency, --timeout). Commands over
+      the limit wait up to 10 min for a slot (--queue-timeout).
 
 FLAGS
 `)
@@ -676,6 +992,24 @@ ENVIRONMENT VARIABLES
     PROTON_LFS_BACKEND             Backend: local or sdk (default: local)
     PROTON_LFS_LOCAL_STORE_DIR     Local store directory
     PROTON_CREDENTIAL_PROVIDER     Credential provider: pass-cli or git-credential
+    PROTON_LFS_BRIDGE_MODE         Bridge mode: subprocess or daemon (default: subprocess)
+    PROTON_LFS_RETRY_ATTEMPTS      Attempts per transfer (default: 3, 1 disables retries)
+    PROTON_LFS_RETRY_MAX_ELAPSED   Maximum retry time per transfer (default: 2m)
+    PROTON_LFS_CACHE               Enable the local object cache (default: false)
+    PROTON_LFS_CACHE_DIR           Object cache directory (default: ~/.proton-lfs/cache)
+    PROTON_LFS_CACHE_MAX_MB        Object cache size limit in MiB (default: 10240)
+    PROTON_LFS_CHUNK_THRESHOLD_MB  Chunk sdk objects larger than this (default: 2048)
+    PROTON_LFS_CHUNK_SIZE_MB       Chunk size in MiB (default: 256)
+    PROTON_LFS_PROFILE             Account profile for the sdk backend
+    PROTON_LFS_TIMEOUT             proton-drive-cli command timeout (default: 5m)
+    PROTON_LFS_CONCURRENCY         Concurrent proton-drive-cli commands (default: 10)
+    PROTON_LFS_QUEUE_TIMEOUT       Wait for a free concurrency slot (default: 10m)
+    PROTON_LFS_PAUSE_MODE          While paused: fail or wait (default: fail)
+    PROTON_LFS_LOG_LEVEL           Log file level: debug, info, warn, error (default: info)
+    PROTON_LFS_LOG_DIR             Log directory (default: ~/.proton-lfs/logs)
+    PROTON_LFS_LOG_MAX_MB          Rotate a log file beyond this size in MiB (default: 10)
+    PROTON_LFS_LOG_MAX_AGE_DAYS    Remove logs older than this (default: 14)
+    PROTON_LFS_METRICS_SOCKET      Metrics collector socket (default: ~/.proton-lfs/metrics.sock)
     PROTON_DRIVE_CLI_BIN           proton-drive-cli path
     NODE_BIN                       Node.js binary path
     LFS_STORAGE_BASE               Remote storage base folder (default: LFS)
@@ -700,20 +1034,189 @@ EXAMPLES
 `)
 }
 
-func main() {
-	defaultDriveCLIBin := envOrDefault(EnvDriveCLIBin, DefaultDriveCLIBin)
-	driveCLIBin := flag.String("drive-cli-bin", defaultDriveCLIBin, "Path to proton-drive-cli dist/index.js")
+// backendOptions holds the flags that select and configure the transfer
+// backend. The transfer loop and the maintenance subcommands share them so
+// that the args registered in lfs.customtransfer.proton.args work for both.
+type backendOptions struct {
+	driveCLIBin        *string
+	backend            *string
+	localStoreDir      *string
+	credentialProvider *string
+	bridgeMode         *string
+	cache              *bool
+	cacheDir           *string
+	cacheMaxMB         *int
+	chunkThresholdMB   *int
+	chunkSizeMB        *int
+	profile            *string
+	storageBase        *string
+	timeout            *time.Duration
+	concurrency        *int
+	queueTimeout       *time.Duration
+}
+
+func addBackendFlags(fs *flag.FlagSet) *backendOptions {
 	defaultBackend := envTrim(EnvBackend)
 	if defaultBackend == "" {
 		defaultBackend = BackendLocal
 	}
-	backend := flag.String("backend", defaultBackend, "Transfer backend to use: local or sdk")
+	return &backendOptions{
+		driveCLIBin:        fs.String("drive-cli-bin", envOrDefault(EnvDriveCLIBin, DefaultDriveCLIBin), "Path to proton-drive-cli dist/index.js"),
+		backend:            fs.String("backend", defaultBackend, "Transfer backend to use: local or sdk"),
+		localStoreDir:      fs.String("local-store-dir", envTrim(EnvLocalStoreDir), "Local object store directory used for standalone transfers"),
+		credentialProvider: fs.String("credential-provider", envOrDefault(EnvCredentialProvider, DefaultCredentialProvider), "Credential provider: pass-cli (default) or git-credential"),
+		bridgeMode:         fs.String("bridge-mode", envOrDefault(EnvBridgeMode, DefaultBridgeMode), "proton-drive-cli process model: subprocess (one per command) or daemon (one per session)"),
+		cache:              fs.Bool("cache", envBoolOrDefault(EnvCache, false), "Serve sdk downloads from a local object cache shared by all repositories"),
+		cacheDir:           fs.String("cache-dir", envOrDefault(EnvCacheDir, config.CacheDirPath()), "Object cache directory"),
+		cacheMaxMB:         fs.Int("cache-max-mb", envIntOrDefault(EnvCacheMaxMB, DefaultCacheMaxMB), "Object cache size limit in MiB; least recently used objects are evicted"),
+		chunkThresholdMB:   fs.Int("chunk-threshold-mb", envIntOrDefault(EnvChunkThresholdMB, DefaultChunkThresholdMB), "Upload sdk objects larger than this many MiB as parallel chunks"),
+		chunkSizeMB:        fs.Int("chunk-size-mb", envIntOrDefault(EnvChunkSizeMB, DefaultChunkSizeMB), "Chunk size in MiB for chunked sdk uploads"),
+		profile:            fs.String("profile", envTrim(EnvProfile), "Proton account profile for the sdk backend (default: the active profile)"),
+		storageBase:        fs.String("storage-base", envOrDefault(EnvStorageBase, DefaultStorageBase), "Proton Drive folder holding LFS objects"),
+		timeout:            fs.Duration("timeout", envDurationOrDefault(EnvTimeout, DefaultBridgeTimeout), "Timeout for a single proton-drive-cli command"),
+		concurrency:        fs.Int("concurrency", envIntOrDefault(EnvConcurrency, DefaultBridgeConcurrency), "Maximum concurrent proton-drive-cli commands across all adapters on the machine (raised to git-lfs concurrenttransfers)"),
+		queueTimeout:       fs.Duration("queue-timeout", envDurationOrDefault(EnvQueueTimeout, DefaultBridgeQueueTimeout), "How long a proton-drive-cli command waits for a free concurrency slot"),
+	}
+}
+
+func (o *backendOptions) kind() string {
+	if kind := strings.ToLower(strings.TrimSpace(*o.backend)); kind != "" {
+		return kind
+	}
+	return BackendLocal
+}
+
+// profileFor resolves the account profile for route: the route's profile,
+// then --profile (which resolveSettings fills from lfs.proton.profile), then
+// the active profile in preferences.
+func (o *backendOptions) profileFor(route *config.Route) (config.Profile, error) {
+	name := strings.TrimSpace(*o.profile)
+	if route != nil && strings.TrimSpace(route.Profile) != "" {
+		name = strings.TrimSpace(route.Profile)
+	}
+	return config.LoadPrefs().ResolveProfile(name)
+}
+
+// kindFor is the backend kind used for route.
+func (o *backendOptions) kindFor(route *config.Route) string {
+	if route != nil {
+		if kind := strings.ToLower(strings.TrimSpace(route.Backend)); kind != "" {
+			return kind
+		}
+	}
+	return o.kind()
+}
+
+func (o *backendOptions) provider() string {
+	if provider := strings.ToLower(strings.TrimSpace(*o.credentialProvider)); provider != "" {
+		return provider
+	}
+	return DefaultCredentialProvider
+}
+
+// newBackend builds the configured TransferBackend.
+func (o *backendOptions) newBackend() (TransferBackend, error) {
+	return o.newBackendFor(nil)
+}
+
+// newBackendFor builds the TransferBackend for a route. The route's non-empty
+// fields override the flags; a nil route uses the flags alone.
+func (o *backendOptions) newBackendFor(route *config.Route) (TransferBackend, error) {
+	kind := o.kindFor(route)
+	provider := o.provider()
+	localStoreDir := strings.TrimSpace(*o.localStoreDir)
+	storageBase := strings.TrimSpace(*o.storageBase)
+	var profile config.Profile
+	if kind == BackendSDK {
+		var err error
+		if profile, err = o.profileFor(route); err != nil {
+			return nil, err
+		}
+		if profile.Name != config.DefaultProfileName {
+			if profile.CredentialProvider != "" {
+				provider = profile.CredentialProvider
+			}
+			if profile.StorageBase != "" {
+				storageBase = profile.StorageBase
+			}
+		}
+	}
+	if route != nil {
+		if v := strings.ToLower(strings.TrimSpace(route.CredentialProvider)); v != "" {
+			provider = v
+		}
+		if v := strings.TrimSpace(route.LocalStoreDir); v != "" {
+			localStoreDir = v
+		}
+		if v := strings.TrimSpace(route.StorageBase); v != "" {
+			storageBase = v
+		}
+	}
+
+	switch kind {
+	case BackendLocal:
+		return NewLocalStoreBackend(localStoreDir), nil
+	case BackendSDK:
+		mode := strings.ToLower(strings.TrimSpace(*o.bridgeMode))
+		if mode != BridgeModeSubprocess && mode != BridgeModeDaemon {
+			return nil, fmt.Errorf("invalid bridge mode %q (supported: subprocess, daemon)", mode)
+		}
+		bridgeCfg := BridgeClientConfig{
+			CLIBin:        strings.TrimSpace(*o.driveCLIBin),
+			Timeout:       *o.timeout,
+			MaxConcurrent: *o.concurrency,
+			QueueTimeout:  *o.queueTimeout,
+			SlotDir:       config.BridgeSlotsDirPath(),
+			StorageBase:   storageBase,
+			AppVersion:    envTrim(EnvAppVersion),
+			Persistent:    mode == BridgeModeDaemon,
+		}
+		if profile.Name != config.DefaultProfileName {
+			bridgeCfg.SessionDir = profile.SessionDirPath()
+		}
+		backend := NewDriveCLIBackend(NewBridgeClient(bridgeCfg), provider)
+		if profile.Name != config.DefaultProfileName {
+			backend.SetAccount(profile.Name, profile.Account)
+		}
+		backend.SetChunking(int64(*o.chunkThresholdMB)<<20, int64(*o.chunkSizeMB)<<20)
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("invalid backend %q (supported: local, sdk)", kind)
+	}
+}
+
+// attachCache enables the object cache on backends that support it. It is
+// only used for transfers: maintenance commands must see the remote itself.
+func (o *backendOptions) attachCache(backend TransferBackend) {
+	if !*o.cache || *o.cacheMaxMB <= 0 {
+		return
+	}
+	if b, ok := backend.(*DriveCLIBackend); ok {
+		b.SetCache(newObjectCache(strings.TrimSpace(*o.cacheDir), int64(*o.cacheMaxMB)<<20))
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case pruneCommand:
+			os.Exit(runPrune(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case fsckCommand:
+			os.Exit(runFsck(os.Args[2:], os.Stdout, os.Stderr))
+		case doctorCommand:
+			os.Exit(runDoctor(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
+	backendOpts := addBackendFlags(flag.CommandLine)
 	allowMockTransfers := flag.Bool("allow-mock-transfers", envBoolOrDefault(EnvAllowMockTransfers, false), "Allow mock upload/download behavior (simulation only)")
-	localStoreDir := flag.String("local-store-dir", envTrim(EnvLocalStoreDir), "Local object store directory used for standalone transfers")
-	defaultCredProvider := envOrDefault(EnvCredentialProvider, DefaultCredentialProvider)
-	credentialProvider := flag.String("credential-provider", defaultCredProvider, "Credential provider: pass-cli (default) or git-credential")
-	debug := flag.Bool("debug", false, "Enable debug logging")
+	retryAttempts := flag.Int("retry-attempts", envIntOrDefault(EnvRetryAttempts, DefaultRetryAttempts), "Attempts per transfer for retryable failures (1 disables retries)")
+	retryMaxElapsed := flag.Duration("retry-max-elapsed", envDurationOrDefault(EnvRetryMaxElapsed, DefaultRetryMaxElapsed), "Maximum time spent retrying a single transfer")
+	debug := flag.Bool("debug", false, "Enable debug logging to stderr")
+	logLevel := flag.String("log-level", envOrDefault(EnvLogLevel, DefaultLogLevel), "Minimum level written to the adapter log file: debug, info, warn or error")
 	showVersion := flag.Bool("version", false, "Print version information")
+	pauseMode := flag.String("pause-mode", envOrDefault(EnvPauseMode, DefaultPauseMode), "While transfers are paused, fail init (fail) or wait until resumed (wait)")
+	printConfig := flag.Bool("print-config", false, "Print each effective setting and where it came from, then exit")
 	flag.Usage = func() { printUsage(os.Stderr) }
 	flag.Parse()
 
@@ -721,47 +1224,84 @@ func main() {
 		fmt.Printf("%s %s (commit=%s build_time=%s)\n", Name, Version, GitCommit, BuildTime)
 		return
 	}
-
-	adapter := NewAdapter()
-	adapter.driveCLIBin = strings.TrimSpace(*driveCLIBin)
-	adapter.allowMockTransfers = *allowMockTransfers
-	adapter.localStoreDir = strings.TrimSpace(*localStoreDir)
-	adapter.backendKind = strings.ToLower(strings.TrimSpace(*backend))
-	if adapter.backendKind == "" {
-		adapter.backendKind = BackendLocal
+	sources, err := resolveSettings(flag.CommandLine, ".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	adapter.credentialProvider = strings.ToLower(strings.TrimSpace(*credentialProvider))
-	if adapter.credentialProvider == "" {
-		adapter.credentialProvider = DefaultCredentialProvider
+	if *printConfig {
+		printSettings(os.Stdout, flag.CommandLine, sources)
+		return
 	}
 
-	switch adapter.backendKind {
-	case BackendLocal:
-		adapter.backend = NewLocalStoreBackend(adapter.localStoreDir)
-	case BackendSDK:
-		bridgeCfg := BridgeClientConfig{
-			CLIBin:      adapter.driveCLIBin,
-			StorageBase: envOrDefault(EnvStorageBase, DefaultStorageBase),
-			AppVersion:  envTrim(EnvAppVersion),
-		}
-		bridge := NewBridgeClient(bridgeCfg)
-		adapter.backend = NewDriveCLIBackend(bridge, adapter.credentialProvider)
-	default:
-		fmt.Fprintf(os.Stderr, "invalid backend %q (supported: local, sdk)\n", adapter.backendKind)
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	adapter := NewAdapter()
+	adapter.configureLogging(level, *debug)
+	adapter.pauseMode, err = parsePauseMode(*pauseMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
 	}
+	adapter.paused = transfersPaused
+	adapter.driveCLIBin = strings.TrimSpace(*backendOpts.driveCLIBin)
+	adapter.allowMockTransfers = *allowMockTransfers
+	adapter.localStoreDir = strings.TrimSpace(*backendOpts.localStoreDir)
+	adapter.backendKind = backendOpts.kind()
+	adapter.credentialProvider = backendOpts.provider()
+	adapter.retry.MaxAttempts = *retryAttempts
+	if adapter.retry.MaxAttempts < 1 {
+		adapter.retry.MaxAttempts = 1
+	}
+	adapter.retry.MaxElapsed = *retryMaxElapsed
+
+	adapter.status = newProcessStatus()
+	adapter.history = config.AppendHistory
+	adapter.metrics = newMetricsPusher()
+
+	journal := newResumeJournal(config.ResumeDirPath())
+	adapter.newBackend = func(route *config.Route) (TransferBackend, error) {
+		backend, err := backendOpts.newBackendFor(route)
+		if err != nil {
+			return nil, err
+		}
+		backendOpts.attachCache(backend)
+		if b, ok := backend.(*DriveCLIBackend); ok {
+			b.SetResumeJournal(journal.forTarget(b.profile + ":" + b.bridge.storageBase))
+			b.bridge.SetMetrics(adapter.metrics.registry)
+		}
+		return backend, nil
+	}
+	adapter.routes = config.LoadPrefs().Routes
 
-	if !*debug {
-		adapter.logger.SetOutput(io.Discard)
+	backend, err := adapter.newBackend(nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	adapter.backend = backend
 
 	// Remove stale temp files from previous adapter runs
 	if removed := cleanupStaleTempFiles(10 * time.Minute); removed > 0 {
-		adapter.logger.Printf("Cleaned up %d stale temp files", removed)
+		adapter.logger.Info("Cleaned up stale temp files", "count", removed)
+	}
+	if removed := journal.expire(resumeMaxAge); removed > 0 {
+		adapter.logger.Info("Cleaned up abandoned resume journal files", "count", removed)
 	}
 
 	// Read from stdin, write to stdout
-	if err := adapter.Run(os.Stdin, os.Stdout); err != nil && err != io.EOF {
-		adapter.logger.Fatalf("Adapter error: %v", err)
+	err = adapter.Run(os.Stdin, os.Stdout)
+	// Stop a persistent bridge daemon even if git-lfs closed stdin without terminate
+	if closer, ok := adapter.backend.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	adapter.metrics.close()
+	if err != nil && err != io.EOF {
+		adapter.logger.Error("Adapter error", "error", err)
+		os.Exit(1)
 	}
 }
@@ -5,13 +5,35 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"proton-lfs-cli/internal/config"
 )
 
+// TestMain points the status files and the history journal at a temporary
+// directory, so that the tests never record fake transfers in the user's
+// ~/.proton-lfs.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_TEST_HELPER_PROCESS") == "1" {
+		os.Exit(m.Run())
+	}
+	dir, err := os.MkdirTemp("", "proton-lfs-adapter-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv(config.EnvStatusFile, filepath.Join(dir, config.StatusFileName))
+	_ = os.Setenv(config.EnvHistoryFile, filepath.Join(dir, config.HistoryFileName))
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 const validOID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
 
 func decodeAllMessages(t *testing.T, data []byte) []OutboundMessage {
@@ -36,6 +58,8 @@ func configureLocalBackend(adapter *Adapter, storeDir string) {
 	adapter.localStoreDir = storeDir
 	adapter.backendKind = BackendLocal
 	adapter.backend = NewLocalStoreBackend(storeDir)
+	// Local store failures are deterministic; don't wait between retries.
+	adapter.retry.sleep = func(time.Duration) {}
 }
 
 func TestAdapterInit(t *testing.T) {
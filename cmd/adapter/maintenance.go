@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// objectStore is implemented by backends that can enumerate, check and delete
+// the objects they hold. The maintenance subcommands (prune, fsck) need it.
+type objectStore interface {
+	ListObjects(session *Session) ([]RemoteObject, error)
+	ExistsObjects(session *Session, oids []string) (map[string]bool, error)
+	DeleteObjects(session *Session, oids []string) (map[string]bool, error)
+}
+
+// storeSession is an initialized backend opened by a maintenance subcommand.
+type storeSession struct {
+	kind    string
+	backend TransferBackend
+	store   objectStore
+	session *Session
+}
+
+// openStoreSession builds the backend for route (nil for the configured one)
+// and initializes it, which authenticates the sdk backend. Callers must close
+// the returned session.
+func openStoreSession(opts *backendOptions, route *config.Route) (*storeSession, error) {
+	backend, err := opts.newBackendFor(route)
+	if err != nil {
+		return nil, err
+	}
+	s := &storeSession{
+		kind:    opts.kindFor(route),
+		backend: backend,
+		session: &Session{Initialized: true, CreatedAt: time.Now()},
+	}
+	store, ok := backend.(objectStore)
+	if !ok {
+		s.close()
+		return nil, fmt.Errorf("backend %q cannot list stored objects", s.kind)
+	}
+	s.store = store
+	if err := backend.Initialize(s.session); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *storeSession) close() {
+	if closer, ok := s.backend.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// referencedObjects merges the LFS objects reachable in every repo.
+func referencedObjects(repos []string, retainSince time.Time) (map[string]int64, error) {
+	referenced := make(map[string]int64)
+	for _, repo := range repos {
+		objects, err := reachableLFSObjects(repo, retainSince)
+		if err != nil {
+			return nil, err
+		}
+		for oid, size := range objects {
+			referenced[oid] = size
+		}
+	}
+	return referenced, nil
+}
+
+func totalSize(objects []RemoteObject) int64 {
+	var total int64
+	for _, obj := range objects {
+		total += obj.Size
+	}
+	return total
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	return config.FormatBytes(n)
+}
+
+// confirm asks a yes/no question on out and reads the answer from in.
+// Anything other than y/yes is a no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
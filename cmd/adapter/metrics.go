@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+	"proton-lfs-cli/internal/metrics"
+)
+
+// metricsPushInterval limits how often finished transfers push samples to the
+// metrics collector; closing the session always pushes.
+const metricsPushInterval = 10 * time.Second
+
+// metricsPusher records this adapter process's samples and pushes them to
+// the collector run by the tray or `proton-lfs-cli metrics serve`. Samples
+// stay queued while no collector answers. A nil metricsPusher records
+// nothing.
+type metricsPusher struct {
+	registry *metrics.Registry
+	socket   string
+	interval time.Duration
+
+	mu     sync.Mutex
+	pushed time.Time
+}
+
+func newMetricsPusher() *metricsPusher {
+	return &metricsPusher{
+		registry: metrics.NewRegistry(),
+		socket:   config.MetricsSocketPath(),
+		interval: metricsPushInterval,
+		pushed:   time.Now(),
+	}
+}
+
+// recordTransfer counts a finished transfer.
+func (p *metricsPusher) recordTransfer(entry config.HistoryEntry) {
+	if p == nil {
+		return
+	}
+	r, op := p.registry, entry.Direction
+	r.Inc(metrics.Transfers, "op", op, "state", entry.State)
+	if entry.Retries > 0 {
+		r.Add(metrics.TransferRetries, float64(entry.Retries), "op", op)
+	}
+	switch {
+	case entry.State != config.StateOK:
+		r.Inc(metrics.TransferErrors, "op", op, "error_code", entry.ErrorCode)
+	case entry.Deduplicated:
+		r.Inc(metrics.DedupHits)
+	default:
+		r.Add(metrics.TransferBytes, float64(entry.Size), "op", op)
+		r.Observe(metrics.TransferDuration, float64(entry.DurationMS)/1000, "op", op)
+	}
+	p.maybePush()
+}
+
+// maybePush pushes when the interval has passed since the last push and no
+// other push is running.
+func (p *metricsPusher) maybePush() {
+	if !p.mu.TryLock() {
+		return
+	}
+	defer p.mu.Unlock()
+	if time.Since(p.pushed) >= p.interval {
+		p.push()
+	}
+}
+
+// close pushes the remaining samples.
+func (p *metricsPusher) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.push()
+}
+
+// push sends the queued samples, putting them back when the collector does
+// not take them. The caller holds p.mu.
+func (p *metricsPusher) push() {
+	p.pushed = time.Now()
+	snapshot := p.registry.Take()
+	if len(snapshot.Counters) == 0 && len(snapshot.Histograms) == 0 {
+		return
+	}
+	if err := metrics.Push(p.socket, snapshot); err != nil {
+		p.registry.Merge(snapshot)
+	}
+}
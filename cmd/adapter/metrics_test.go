@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+	"proton-lfs-cli/internal/metrics"
+)
+
+// scrape returns the text exposition of r.
+func scrape(t *testing.T, r *metrics.Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestBridgeRecordsSpawnsAndExits(t *testing.T) {
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_ERROR=unauthorized", "MOCK_BRIDGE_ERROR_CODE=401")
+	registry := metrics.NewRegistry()
+	bc.SetMetrics(registry)
+	_ = bc.Authenticate(OperationCredentials{CredentialProvider: CredentialProviderPassCLI})
+
+	out := scrape(t, registry)
+	for _, want := range []string{
+		`proton_lfs_bridge_spawns_total{mode="subprocess"} 1`,
+		`proton_lfs_bridge_exits_total{command="auth",exit_code="1"} 1`,
+		`proton_lfs_bridge_command_duration_seconds_count{command="auth"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestTransfersPushMetricsToCollector(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	t.Setenv(config.EnvHistoryFile, filepath.Join(t.TempDir(), "history.jsonl"))
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	t.Setenv(config.EnvMetricsSocket, socket)
+
+	adapter := NewAdapter()
+	adapter.metrics = newMetricsPusher()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.session = &Session{Initialized: true}
+	adapter.currentOperation = DirectionUpload
+
+	payload := []byte("metrics payload")
+	msg := InboundMessage{Event: EventUpload, OID: oidOf(payload), Size: int64(len(payload)), Path: writeTempObject(t, payload)}
+	enc := json.NewEncoder(new(bytes.Buffer))
+	if err := adapter.handleUpload(&msg, enc); err != nil {
+		t.Fatalf("handleUpload returned error: %v", err)
+	}
+	_ = adapter.sendTransferError(enc, strings.Repeat("f", 64), 429, "Rate limit exceeded")
+
+	// Without a collector the samples stay queued for the next push.
+	adapter.metrics.close()
+
+	srv, err := metrics.Listen(socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = srv.Close() }()
+	adapter.metrics.close()
+
+	out := scrape(t, srv.Registry)
+	for _, want := range []string{
+		`proton_lfs_transfers_total{op="upload",state="ok"} 1`,
+		`proton_lfs_transfers_total{op="upload",state="rate_limited"} 1`,
+		`proton_lfs_transfer_errors_total{error_code="rate_limited",op="upload"} 1`,
+		`proton_lfs_transfer_bytes_total{op="upload"} 15`,
+		`proton_lfs_transfer_duration_seconds_count{op="upload"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestNewAdapterPushesNoMetrics(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	t.Setenv(config.EnvMetricsSocket, socket)
+	srv, err := metrics.Listen(socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = srv.Close() }()
+
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.session = &Session{Initialized: true}
+	adapter.currentOperation = DirectionUpload
+	payload := []byte("unpushed payload")
+	msg := InboundMessage{Event: EventUpload, OID: oidOf(payload), Size: int64(len(payload)), Path: writeTempObject(t, payload)}
+	if err := adapter.handleUpload(&msg, json.NewEncoder(new(bytes.Buffer))); err != nil {
+		t.Fatalf("handleUpload returned error: %v", err)
+	}
+	if err := adapter.handleTerminate(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := scrape(t, srv.Registry); strings.Contains(out, "proton_lfs_transfers_total") {
+		t.Fatalf("an Adapter without a pusher must not reach the collector:\n%s", out)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// pausePollInterval is how often a waiting init checks whether transfers
+// were resumed.
+const pausePollInterval = 2 * time.Second
+
+// pausedMessage tells the user how to continue; git-lfs prints it as is.
+const pausedMessage = "Proton LFS transfers are paused; run 'proton-lfs-cli resume' or use the tray menu to continue"
+
+// transfersPaused reports whether the user paused transfers from the tray or
+// with proton-lfs-cli pause.
+func transfersPaused() bool {
+	return !config.LoadPrefs().Enabled
+}
+
+// parsePauseMode validates a --pause-mode value.
+func parsePauseMode(mode string) (string, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case PauseModeFail, PauseModeWait:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid pause mode %q (supported: fail, wait)", mode)
+	}
+}
+
+// waitUntilResumed returns false if transfers are paused and the pause mode
+// is fail. In wait mode it blocks until transfers are resumed. Either way no
+// request reaches Proton while paused.
+func (a *Adapter) waitUntilResumed() bool {
+	if a.paused == nil || !a.paused() {
+		return true
+	}
+	_ = config.WriteStatus(config.StatusReport{State: config.StatePaused, LastOp: "init", Error: pausedMessage})
+	if a.pauseMode != PauseModeWait {
+		a.logger.Warn("Transfers are paused; failing init")
+		return false
+	}
+	a.logger.Info("Transfers are paused; waiting until resumed")
+	interval := a.pausePoll
+	if interval <= 0 {
+		interval = pausePollInterval
+	}
+	for a.paused() {
+		time.Sleep(interval)
+	}
+	a.logger.Info("Transfers resumed")
+	return true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const initUpload = `{"event":"init","operation":"upload","concurrent":false,"concurrenttransfers":1}` + "\n"
+
+func TestHandleInitFailsWhilePaused(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "status.json")
+	t.Setenv(config.EnvStatusFile, statusFile)
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.paused = func() bool { return true }
+	adapter.pauseMode = PauseModeFail
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(initUpload), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	msgs := decodeAllMessages(t, out.Bytes())
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != 423 {
+		t.Fatalf("expected a 423 init error, got %+v", msgs)
+	}
+	if !strings.Contains(msgs[0].Error.Message, "proton-lfs-cli resume") {
+		t.Errorf("error should say how to resume: %q", msgs[0].Error.Message)
+	}
+	if adapter.session != nil {
+		t.Fatal("a paused init must not start a session")
+	}
+	report, err := config.ReadStatus()
+	if err != nil || report.State != config.StatePaused {
+		t.Fatalf("expected the paused state in the status file, got %+v (%v)", report, err)
+	}
+}
+
+func TestHandleInitWaitsUntilResumed(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	var checks atomic.Int32
+	adapter.paused = func() bool { return checks.Add(1) < 4 }
+	adapter.pauseMode = PauseModeWait
+	adapter.pausePoll = time.Millisecond
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(initUpload), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	msgs := decodeAllMessages(t, out.Bytes())
+	if len(msgs) != 1 || msgs[0].Error != nil {
+		t.Fatalf("expected init to succeed once resumed, got %+v", msgs)
+	}
+	if got := checks.Load(); got < 4 {
+		t.Fatalf("expected init to poll until resumed, checked %d times", got)
+	}
+}
+
+func TestParsePauseMode(t *testing.T) {
+	if mode, err := parsePauseMode(" Wait "); err != nil || mode != PauseModeWait {
+		t.Fatalf("parsePauseMode(Wait) = %q, %v", mode, err)
+	}
+	if _, err := parsePauseMode("sometimes"); err == nil {
+		t.Fatal("expected an invalid pause mode to be rejected")
+	}
+}
+
+func TestTransfersPausedReadsPrefs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if transfersPaused() {
+		t.Fatal("transfers must not be paused without a preferences file")
+	}
+	prefs := config.DefaultPreferences()
+	prefs.Enabled = false
+	if err := config.SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+	if !transfersPaused() {
+		t.Fatal("expected Enabled=false to pause transfers")
+	}
+}
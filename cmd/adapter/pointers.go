@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPointerSize matches git-lfs: blobs larger than this are never pointers.
+const maxPointerSize = 1024
+
+const lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// parseLFSPointer extracts the OID and size from an LFS pointer blob.
+func parseLFSPointer(data []byte) (string, int64, bool) {
+	if len(data) > maxPointerSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion)) {
+		return "", 0, false
+	}
+	var oid string
+	size := int64(-1)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch key {
+		case "oid":
+			oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				size = n
+			}
+		}
+	}
+	if !oidPattern.MatchString(oid) || size < 0 {
+		return "", 0, false
+	}
+	return oid, size, true
+}
+
+// reachableLFSObjects returns the OIDs (and sizes) of every LFS pointer in the
+// history of all refs of repo, plus commits that a reflog entry made since
+// retainSince points to. A zero retainSince skips the reflog.
+func reachableLFSObjects(repo string, retainSince time.Time) (map[string]int64, error) {
+	objects := make(map[string]int64)
+	if err := scanPointers(repo, objects, nil, "--all"); err != nil {
+		return nil, err
+	}
+	if !retainSince.IsZero() {
+		commits, err := reflogCommits(repo, retainSince)
+		if err != nil {
+			return nil, err
+		}
+		// History reachable from the refs has been scanned already.
+		if len(commits) > 0 {
+			if err := scanPointers(repo, objects, commits, "--stdin", "--not", "--all"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return objects, nil
+}
+
+// reflogCommits returns the commits recorded by reflog entries of repo made
+// at or after since. It filters on when the ref moved, not on the commit
+// date that rev-list --since would use: a rebase or a deleted branch records
+// old commits with new entries.
+func reflogCommits(repo string, since time.Time) ([]string, error) {
+	out, err := exec.Command("git", "-C", repo, "reflog", "show", "--all", "--date=unix", "--format=%H %gd").Output()
+	if err != nil {
+		return nil, gitCommandError(repo, "reflog", err)
+	}
+	seen := make(map[string]bool)
+	var commits []string
+	for _, line := range strings.Split(string(out), "\n") {
+		// Each line is "<commit> <ref>@{<unix time>}".
+		commit, selector, ok := strings.Cut(strings.TrimSpace(line), " ")
+		at := strings.LastIndex(selector, "@{")
+		if !ok || at < 0 || !strings.HasSuffix(selector, "}") || strings.Trim(commit, "0") == "" {
+			continue
+		}
+		unix, err := strconv.ParseInt(selector[at+2:len(selector)-1], 10, 64)
+		if err != nil || unix < since.Unix() || seen[commit] {
+			continue
+		}
+		seen[commit] = true
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// requireHistory fails when repo has no commits reachable from a ref or HEAD.
+// Prune must not treat such a repo as referencing nothing.
+func requireHistory(repo string) error {
+	out, err := exec.Command("git", "-C", repo, "rev-list", "--all", "--max-count=1").Output()
+	if err != nil {
+		return gitCommandError(repo, "rev-list", err)
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return fmt.Errorf("repository %s has no refs; refusing to prune, every stored object would look unreferenced", repo)
+	}
+	return nil
+}
+
+// scanPointers lists the objects reachable from revArgs and the revisions in
+// stdin (one per line, with --stdin), then reads every blob small enough to
+// be a pointer and records the OIDs it references.
+func scanPointers(repo string, objects map[string]int64, stdin []string, revArgs ...string) error {
+	args := append([]string{"-C", repo, "rev-list", "--objects"}, revArgs...)
+	revCmd := exec.Command("git", args...)
+	if len(stdin) > 0 {
+		revCmd.Stdin = strings.NewReader(strings.Join(stdin, "\n") + "\n")
+	}
+	revList, err := revCmd.Output()
+	if err != nil {
+		return gitCommandError(repo, "rev-list", err)
+	}
+	var names bytes.Buffer
+	for _, line := range strings.Split(string(revList), "\n") {
+		if name, _, _ := strings.Cut(line, " "); name != "" {
+			names.WriteString(name + "\n")
+		}
+	}
+	if names.Len() == 0 {
+		return nil
+	}
+
+	check := exec.Command("git", "-C", repo, "cat-file", "--batch-check=%(objectname) %(objecttype) %(objectsize)")
+	check.Stdin = &names
+	checked, err := check.Output()
+	if err != nil {
+		return gitCommandError(repo, "cat-file --batch-check", err)
+	}
+	var candidates bytes.Buffer
+	for _, line := range strings.Split(string(checked), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		if size, err := strconv.Atoi(fields[2]); err == nil && size <= maxPointerSize {
+			candidates.WriteString(fields[0] + "\n")
+		}
+	}
+	if candidates.Len() == 0 {
+		return nil
+	}
+
+	batch := exec.Command("git", "-C", repo, "cat-file", "--batch")
+	batch.Stdin = &candidates
+	contents, err := batch.Output()
+	if err != nil {
+		return gitCommandError(repo, "cat-file --batch", err)
+	}
+	r := bufio.NewReader(bytes.NewReader(contents))
+	for {
+		header, err := r.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected cat-file header %q in %s", strings.TrimSpace(header), repo)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("unexpected cat-file header %q in %s", strings.TrimSpace(header), repo)
+		}
+		// Each object is followed by a newline separator.
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("short cat-file output in %s: %w", repo, err)
+		}
+		if oid, objSize, ok := parseLFSPointer(data[:size]); ok {
+			objects[oid] = objSize
+		}
+	}
+}
+
+func gitCommandError(repo, command string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("git %s in %s failed: %s", command, repo, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return fmt.Errorf("git %s in %s failed: %w", command, repo, err)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// maxExistsBatch caps how many upload OIDs are resolved per batch-exists call.
+const maxExistsBatch = 100
+
+// inboundBuffer is how many decoded messages the reader may hold ahead of the
+// message loop, which is what lets queued uploads be batched.
+const inboundBuffer = 256
+
+// existenceCache is implemented by backends that can resolve remote existence
+// for many OIDs at once and remember the answer for later uploads.
+type existenceCache interface {
+	PrefetchExists(oids []string) error
+	KnownToExist(oid string) bool
+}
+
+// dedupReporter is implemented by backends whose Upload skips objects that
+// are already stored, so the history can count the skip.
+type dedupReporter interface {
+	TakeDeduplicated(oid string) bool
+}
+
+// inboundResult is one decoded message, or the decode error that ended input.
+type inboundResult struct {
+	msg InboundMessage
+	err error
+}
+
+// readMessages decodes messages from r on a separate goroutine so the message
+// loop can see which requests are already queued. It stops at the first
+// decode error (including io.EOF) or when done is closed.
+func readMessages(r io.Reader, done <-chan struct{}) <-chan inboundResult {
+	ch := make(chan inboundResult, inboundBuffer)
+	go func() {
+		defer close(ch)
+		decoder := json.NewDecoder(r)
+		for {
+			var in inboundResult
+			in.err = decoder.Decode(&in.msg)
+			select {
+			case ch <- in:
+			case <-done:
+				return
+			}
+			if in.err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// collectUploadBatch gathers first plus any uploads already queued behind it,
+// without blocking. The first non-upload result is returned as leftover so
+// the caller handles it next.
+func collectUploadBatch(first InboundMessage, msgs <-chan inboundResult) ([]InboundMessage, *inboundResult) {
+	batch := []InboundMessage{first}
+	for len(batch) < maxExistsBatch {
+		select {
+		case in, ok := <-msgs:
+			if !ok {
+				return batch, nil
+			}
+			if in.err != nil || in.msg.Event != EventUpload {
+				return batch, &in
+			}
+			batch = append(batch, in.msg)
+		default:
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+// prefetchExists resolves remote existence for a batch of uploads in a single
+// backend call. Failures are logged and ignored: uploads then fall back to
+// per-object existence checks.
+func (a *Adapter) prefetchExists(batch []InboundMessage) {
+	cache, ok := a.backend.(existenceCache)
+	if !ok || a.allowMockTransfers || a.session == nil {
+		return
+	}
+	oids := make([]string, 0, len(batch))
+	seen := make(map[string]bool, len(batch))
+	for _, msg := range batch {
+		oid := strings.ToLower(msg.OID)
+		if !oidPattern.MatchString(oid) || seen[oid] || cache.KnownToExist(oid) {
+			continue
+		}
+		seen[oid] = true
+		oids = append(oids, oid)
+	}
+	if len(oids) == 0 {
+		return
+	}
+	if err := cache.PrefetchExists(oids); err != nil {
+		a.logger.Warn("Batch existence check failed", "count", len(oids), "error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// spawnedCommands returns the bridge commands recorded in a spawn log.
+func spawnedCommands(t *testing.T, spawnLog string) []string {
+	t.Helper()
+	data, err := os.ReadFile(spawnLog)
+	if err != nil {
+		t.Fatalf("failed to read spawn log: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestCollectUploadBatchStopsAtNonUpload(t *testing.T) {
+	msgs := make(chan inboundResult, 4)
+	msgs <- inboundResult{msg: InboundMessage{Event: EventUpload, OID: "b"}}
+	msgs <- inboundResult{msg: InboundMessage{Event: EventUpload, OID: "c"}}
+	msgs <- inboundResult{msg: InboundMessage{Event: EventTerminate}}
+	msgs <- inboundResult{msg: InboundMessage{Event: EventUpload, OID: "d"}}
+
+	batch, leftover := collectUploadBatch(InboundMessage{Event: EventUpload, OID: "a"}, msgs)
+	if len(batch) != 3 || batch[0].OID != "a" || batch[2].OID != "c" {
+		t.Fatalf("unexpected batch: %+v", batch)
+	}
+	if leftover == nil || leftover.msg.Event != EventTerminate {
+		t.Fatalf("expected terminate as leftover, got %+v", leftover)
+	}
+}
+
+func TestCollectUploadBatchDoesNotBlock(t *testing.T) {
+	msgs := make(chan inboundResult)
+	batch, leftover := collectUploadBatch(InboundMessage{Event: EventUpload, OID: "a"}, msgs)
+	if len(batch) != 1 || leftover != nil {
+		t.Fatalf("expected single-item batch without leftover, got %+v %+v", batch, leftover)
+	}
+}
+
+func TestDriveCLIBackendPrefetchExistsSkipsPerObjectCheck(t *testing.T) {
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	backend.authenticated = true
+
+	if err := backend.PrefetchExists([]string{validOID}); err != nil {
+		t.Fatalf("PrefetchExists failed: %v", err)
+	}
+	if !backend.KnownToExist(validOID) {
+		t.Fatal("expected prefetched oid to be known")
+	}
+
+	uploadPath := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(uploadPath, []byte("payload"), 0o600); err != nil {
+		t.Fatalf("failed to create upload source: %v", err)
+	}
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if _, err := backend.Upload(session, validOID, uploadPath, 7, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	cmds := spawnedCommands(t, spawnLog)
+	if len(cmds) != 1 || cmds[0] != "batch-exists" {
+		t.Fatalf("expected only one batch-exists subprocess, got %v", cmds)
+	}
+}
+
+func TestRunBatchesQueuedUploadExistence(t *testing.T) {
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	adapter := NewAdapter()
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	adapter.backend = NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+
+	tmpDir := t.TempDir()
+	lines := []string{`{"event":"init","operation":"upload","remote":"origin","concurrent":false,"concurrenttransfers":1}`}
+	var oids []string
+	for i := 0; i < 3; i++ {
+		payload := []byte(fmt.Sprintf("already-stored-%d", i))
+		sum := sha256.Sum256(payload)
+		oid := hex.EncodeToString(sum[:])
+		path := filepath.Join(tmpDir, oid)
+		if err := os.WriteFile(path, payload, 0o600); err != nil {
+			t.Fatalf("failed to create upload file: %v", err)
+		}
+		oids = append(oids, oid)
+		lines = append(lines, fmt.Sprintf(`{"event":"upload","oid":"%s","size":%d,"path":%q,"action":null}`, oid, len(payload), path))
+	}
+	lines = append(lines, `{"event":"terminate"}`)
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(strings.Join(lines, "\n")+"\n"), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	msgs := decodeAllMessages(t, out.Bytes())
+	if len(msgs) != 1+2*len(oids) {
+		t.Fatalf("expected init ack plus progress+complete per upload, got %d: %+v", len(msgs), msgs)
+	}
+	for i, oid := range oids {
+		progress, complete := msgs[1+2*i], msgs[2+2*i]
+		if progress.Event != EventProgress || progress.OID != oid {
+			t.Fatalf("expected progress for %s, got %+v", oid, progress)
+		}
+		if complete.Event != EventComplete || complete.OID != oid || complete.Error != nil {
+			t.Fatalf("expected successful complete for %s, got %+v", oid, complete)
+		}
+	}
+
+	var batchExists int
+	for _, cmd := range spawnedCommands(t, spawnLog) {
+		switch cmd {
+		case "batch-exists":
+			batchExists++
+		case "exists", "upload":
+			t.Fatalf("known objects must not spawn %q", cmd)
+		}
+	}
+	if batchExists != 1 {
+		t.Fatalf("expected a single batch-exists call, got %d", batchExists)
+	}
+}
+
+func TestKnownUploadVerifiesSourceHash(t *testing.T) {
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	adapter := NewAdapter()
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	adapter.backend = NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+
+	// The remote has the object, but the local source is different content.
+	oid := oidOf([]byte("stored content"))
+	path := writeTempObject(t, []byte("other content!"))
+	input := strings.Join([]string{
+		`{"event":"init","operation":"upload","remote":"origin","concurrent":false,"concurrenttransfers":1}`,
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":14,"path":%q,"action":null}`, oid, path),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(input), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	msgs := decodeAllMessages(t, out.Bytes())
+	if len(msgs) != 2 || msgs[1].Error == nil || msgs[1].Error.Code != 409 {
+		t.Fatalf("expected a 409 for the mismatched source, got %+v", msgs)
+	}
+}
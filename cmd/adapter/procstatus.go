@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// processStatusInterval limits how often progress rewrites the process
+// status record; starting and finishing a transfer always write it.
+const processStatusInterval = time.Second
+
+// processStatus publishes this adapter process's in-flight transfers to its
+// own status record, so that concurrent adapters do not overwrite each
+// other. A nil processStatus publishes nothing.
+type processStatus struct {
+	mu       sync.Mutex
+	record   config.ProcessStatus
+	interval time.Duration
+	written  time.Time
+}
+
+func newProcessStatus() *processStatus {
+	return &processStatus{
+		record:   config.ProcessStatus{PID: os.Getpid(), StartedAt: time.Now()},
+		interval: processStatusInterval,
+	}
+}
+
+// begin adds a transfer of oid.
+func (p *processStatus) begin(op, oid string, size int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.record.Transfers = append(p.record.Transfers, config.TransferProgress{
+		OID: oid, Op: op, Size: size, StartedAt: time.Now(),
+	})
+	p.write()
+}
+
+// progress records bytesDone for the transfer of oid.
+func (p *processStatus) progress(oid string, bytesDone int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.record.Transfers {
+		if p.record.Transfers[i].OID == oid {
+			p.record.Transfers[i].BytesDone = bytesDone
+		}
+	}
+	if time.Since(p.written) >= p.interval {
+		p.write()
+	}
+}
+
+// end removes the transfer of oid.
+func (p *processStatus) end(oid string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	transfers := p.record.Transfers[:0]
+	for _, t := range p.record.Transfers {
+		if t.OID != oid {
+			transfers = append(transfers, t)
+		}
+	}
+	p.record.Transfers = transfers
+	p.write()
+}
+
+// close deletes the record when the adapter stops.
+func (p *processStatus) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.record.Transfers = nil
+	_ = config.RemoveProcessStatus(p.record.PID)
+}
+
+func (p *processStatus) write() {
+	p.record.UpdatedAt = time.Now()
+	p.written = p.record.UpdatedAt
+	_ = config.WriteProcessStatus(p.record)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// observingBackend reports progress and captures the aggregate status while
+// an upload is in flight.
+type observingBackend struct {
+	TransferBackend
+	seen config.AggregateStatus
+}
+
+func (b *observingBackend) Upload(session *Session, oid, sourcePath string, expectedSize int64, progress ProgressFunc) (int64, error) {
+	progress(expectedSize / 2)
+	b.seen, _ = config.ReadAggregateStatus()
+	return b.TransferBackend.Upload(session, oid, sourcePath, expectedSize, progress)
+}
+
+func TestUploadPublishesProcessStatus(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+
+	adapter := NewAdapter()
+	adapter.status = newProcessStatus()
+	adapter.status.interval = 0
+	configureLocalBackend(adapter, t.TempDir())
+	observer := &observingBackend{TransferBackend: adapter.backend}
+	adapter.backend = observer
+	adapter.session = &Session{Initialized: true}
+	adapter.currentOperation = DirectionUpload
+
+	payload := []byte("status payload")
+	oid := oidOf(payload)
+	msg := InboundMessage{Event: EventUpload, OID: oid, Size: int64(len(payload)), Path: writeTempObject(t, payload)}
+	if err := adapter.handleUpload(&msg, json.NewEncoder(new(bytes.Buffer))); err != nil {
+		t.Fatalf("handleUpload returned error: %v", err)
+	}
+
+	seen := observer.seen
+	if seen.State != config.StateTransferring || len(seen.Active) != 1 {
+		t.Fatalf("expected one transfer in flight, got %+v", seen)
+	}
+	if active := seen.Active[0]; active.OID != oid || active.Op != "upload" || active.BytesDone != int64(len(payload)/2) || active.StartedAt.IsZero() {
+		t.Fatalf("unexpected in-flight transfer: %+v", active)
+	}
+
+	after, err := config.ReadAggregateStatus()
+	if err != nil || after.State != config.StateOK || len(after.Active) != 0 {
+		t.Fatalf("expected the finished upload's outcome, got %+v, %v", after, err)
+	}
+
+	adapter.status.close()
+	if got := config.ReadProcessStatuses(); len(got) != 0 {
+		t.Fatalf("expected the record to be removed on close, got %+v", got)
+	}
+}
+
+func TestRunRemovesProcessStatusWithoutTerminate(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+
+	adapter := NewAdapter()
+	adapter.status = newProcessStatus()
+	adapter.status.begin("upload", validOID, 1)
+	if got := config.ReadProcessStatuses(); len(got) != 1 {
+		t.Fatalf("expected the record to be written, got %+v", got)
+	}
+
+	// git-lfs closing stdin without terminate still removes the record.
+	if err := adapter.Run(strings.NewReader(""), new(bytes.Buffer)); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := config.ReadProcessStatuses(); len(got) != 0 {
+		t.Fatalf("expected the record to be removed when Run returns, got %+v", got)
+	}
+}
+
+func TestNewAdapterPublishesNoProcessStatus(t *testing.T) {
+	t.Setenv(config.EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.session = &Session{Initialized: true}
+	adapter.currentOperation = DirectionUpload
+	payload := []byte("no status")
+	msg := InboundMessage{Event: EventUpload, OID: oidOf(payload), Size: int64(len(payload)), Path: writeTempObject(t, payload)}
+	if err := adapter.handleUpload(&msg, json.NewEncoder(new(bytes.Buffer))); err != nil {
+		t.Fatalf("handleUpload returned error: %v", err)
+	}
+	if entries, _ := os.ReadDir(config.ProcessStatusDirPath()); len(entries) != 0 {
+		t.Fatalf("an Adapter without a status writer must not publish records, got %d", len(entries))
+	}
+}
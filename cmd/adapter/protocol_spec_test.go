@@ -268,6 +268,45 @@ func TestRunDownloadProgressOrderingAndByteSemantics(t *testing.T) {
 	_ = os.Remove(msgs[2].Path)
 }
 
+// TestRunDownloadStreamsBridgeProgress asserts progress streamed by the bridge
+// reaches git-lfs as separate, monotonic progress events before complete.
+func TestRunDownloadStreamsBridgeProgress(t *testing.T) {
+	payload := []byte("streamed-download-payload")
+	oid := sha256.Sum256(payload)
+	oidHex := hex.EncodeToString(oid[:])
+
+	adapter := NewAdapter()
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_DOWNLOAD_CONTENT="+string(payload))
+	adapter.backend = NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+
+	input := strings.Join([]string{
+		`{"event":"init","operation":"download","remote":"origin","concurrent":false,"concurrenttransfers":1}`,
+		fmt.Sprintf(`{"event":"download","oid":"%s","size":%d,"action":null}`, oidHex, len(payload)),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(input), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	msgs := decodeAllMessages(t, out.Bytes())
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 responses (init + 2 streamed progress + complete), got %d: %+v", len(msgs), msgs)
+	}
+	half := int64(len(payload)) / 2
+	if msgs[1].Event != EventProgress || msgs[1].BytesSoFar != half || msgs[1].BytesSince != half {
+		t.Fatalf("expected first streamed progress at %d bytes, got %+v", half, msgs[1])
+	}
+	if msgs[2].Event != EventProgress || msgs[2].BytesSoFar != int64(len(payload)) || msgs[2].BytesSince != int64(len(payload))-half {
+		t.Fatalf("expected final streamed progress at full size, got %+v", msgs[2])
+	}
+	if msgs[3].Event != EventComplete || msgs[3].Error != nil || msgs[3].Path == "" {
+		t.Fatalf("expected successful completion, got %+v", msgs[3])
+	}
+	_ = os.Remove(msgs[3].Path)
+}
+
 type failAfterNWriter struct {
 	successfulWrites int
 	failAt           int
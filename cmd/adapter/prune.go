@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const pruneCommand = "prune"
+
+// DefaultPruneRetainDays keeps objects referenced from reflog entries of the
+// last week, so recently rewritten or deleted branches can still be recovered.
+const DefaultPruneRetainDays = 7
+
+// DefaultPruneMinAge keeps unreferenced objects stored within the last week.
+// They may belong to a push still in flight, or to a teammate's push that has
+// not been fetched into the scanned repositories yet.
+const DefaultPruneMinAge = 7 * 24 * time.Hour
+
+// pruneCandidates returns the stored objects that are not referenced, sorted
+// by OID. Unreferenced objects modified after cutoff, or whose modification
+// time is unknown, are returned as recent instead; a zero cutoff disables the
+// age check.
+func pruneCandidates(stored []RemoteObject, referenced map[string]int64, cutoff time.Time) (candidates, recent []RemoteObject) {
+	for _, obj := range stored {
+		if _, ok := referenced[obj.OID]; ok {
+			continue
+		}
+		if !cutoff.IsZero() && (obj.ModTime.IsZero() || obj.ModTime.After(cutoff)) {
+			recent = append(recent, obj)
+			continue
+		}
+		candidates = append(candidates, obj)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].OID < candidates[j].OID })
+	sort.Slice(recent, func(i, j int) bool { return recent[i].OID < recent[j].OID })
+	return candidates, recent
+}
+
+func printPruneUsage(w io.Writer, fs *flag.FlagSet) {
+	_, _ = fmt.Fprint(w, `Usage: git-lfs-proton-adapter prune [flags] [repo...]
+
+Delete stored objects that no LFS pointer in the given repositories (default:
+the current directory) references. Pointers are collected from the history of
+all refs plus commits that reflog entries within the retention window point
+to. Every repository that shares the storage base must be listed, or its
+objects will be deleted. A repository without any refs is refused.
+
+Run "git fetch --all" in each repository first: objects pushed by others are
+only referenced once their commits have been fetched. Objects stored within
+--min-age are kept either way.
+
+Flags:
+`)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// runPrune implements the prune subcommand and returns the process exit code.
+func runPrune(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(pruneCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	backendOpts := addBackendFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "List unreferenced objects without deleting them")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+	retainDays := fs.Int("retain-days", DefaultPruneRetainDays, "Keep objects referenced from reflog entries this many days old (0 disables)")
+	minAge := fs.Duration("min-age", DefaultPruneMinAge, "Keep unreferenced objects stored more recently than this (0 disables)")
+	verbose := fs.Bool("verbose", false, "List every unreferenced object")
+	remote := fs.String("remote", "", "Use the storage route configured for this git remote")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			printPruneUsage(stdout, fs)
+			return 0
+		}
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		printPruneUsage(stderr, fs)
+		return 2
+	}
+
+	repos := fs.Args()
+	if len(repos) == 0 {
+		repos = []string{"."}
+	}
+	if _, err := resolveSettings(fs, repos[0]); err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	var retainSince time.Time
+	if *retainDays > 0 {
+		retainSince = time.Now().AddDate(0, 0, -*retainDays)
+	}
+	var storedBefore time.Time
+	if *minAge > 0 {
+		storedBefore = time.Now().Add(-*minAge)
+	}
+
+	for _, repo := range repos {
+		if err := requireHistory(repo); err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	referenced, err := referencedObjects(repos, retainSince)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	route, err := remoteRoute(repos[0], *remote)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	store, err := openStoreSession(backendOpts, route)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	defer store.close()
+	stored, err := store.store.ListObjects(store.session)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	candidates, recent := pruneCandidates(stored, referenced, storedBefore)
+	reclaim := totalSize(candidates)
+	_, _ = fmt.Fprintln(stderr, `note: objects of commits not yet fetched here count as unreferenced; run "git fetch --all" in each repository first`)
+	_, _ = fmt.Fprintf(stdout, "Referenced: %d objects in %d repositories\n", len(referenced), len(repos))
+	_, _ = fmt.Fprintf(stdout, "Stored:     %d objects (%s)\n", len(stored), formatBytes(totalSize(stored)))
+	if len(recent) > 0 {
+		_, _ = fmt.Fprintf(stdout, "Recent:     %d objects (%s) kept by --min-age\n", len(recent), formatBytes(totalSize(recent)))
+	}
+	_, _ = fmt.Fprintf(stdout, "Prunable:   %d objects (%s)\n", len(candidates), formatBytes(reclaim))
+	if len(candidates) == 0 {
+		return 0
+	}
+	if *dryRun || *verbose {
+		for _, obj := range candidates {
+			_, _ = fmt.Fprintf(stdout, "  %s  %s\n", obj.OID, formatBytes(obj.Size))
+		}
+	}
+	if *dryRun {
+		_, _ = fmt.Fprintln(stdout, "Dry run: nothing deleted")
+		return 0
+	}
+	if !*yes && !confirm(stdin, stdout, fmt.Sprintf("Delete %d objects (%s)?", len(candidates), formatBytes(reclaim))) {
+		_, _ = fmt.Fprintln(stdout, "Aborted: nothing deleted")
+		return 1
+	}
+
+	oids := make([]string, len(candidates))
+	for i, obj := range candidates {
+		oids[i] = obj.OID
+	}
+	deleted, err := store.store.DeleteObjects(store.session, oids)
+	var freed int64
+	var count int
+	for _, obj := range candidates {
+		if deleted[obj.OID] {
+			freed += obj.Size
+			count++
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "Deleted %d of %d objects (%s)\n", count, len(candidates), formatBytes(freed))
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if count < len(candidates) {
+		_, _ = fmt.Fprintf(stderr, "error: %d objects could not be deleted\n", len(candidates)-count)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lfsPointer(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, hex.EncodeToString(sum[:]), len(payload))
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+// commitPointer commits an LFS pointer for payload at name in repo.
+func commitPointer(t *testing.T, repo, name string, payload []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo, name), []byte(lfsPointer(payload)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", name)
+	runGit(t, repo, "commit", "-q", "-m", "add "+name)
+}
+
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	return repo
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	oid, size, ok := parseLFSPointer([]byte(lfsPointer([]byte("hello"))))
+	if !ok || size != 5 || !oidPattern.MatchString(oid) {
+		t.Fatalf("parseLFSPointer = %q, %d, %v", oid, size, ok)
+	}
+	for _, data := range []string{
+		"plain file contents",
+		lfsPointerVersion + "\noid sha256:abc\nsize 5\n",
+		lfsPointerVersion + "\noid sha256:" + validOID + "\n",
+	} {
+		if _, _, ok := parseLFSPointer([]byte(data)); ok {
+			t.Errorf("expected %q not to parse as a pointer", data)
+		}
+	}
+}
+
+func TestReachableLFSObjectsCoversAllRefs(t *testing.T) {
+	repo := newTestRepo(t)
+	commitPointer(t, repo, "a.bin", []byte("on main"))
+	runGit(t, repo, "checkout", "-q", "-b", "feature")
+	commitPointer(t, repo, "b.bin", []byte("on feature"))
+	runGit(t, repo, "checkout", "-q", "main")
+	if err := os.WriteFile(filepath.Join(repo, "README"), []byte("not a pointer\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "README")
+	runGit(t, repo, "commit", "-q", "-m", "readme")
+
+	objects, err := reachableLFSObjects(repo, time.Time{})
+	if err != nil {
+		t.Fatalf("reachableLFSObjects failed: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected pointers from both branches, got %v", objects)
+	}
+}
+
+func TestReachableLFSObjectsRetentionWindow(t *testing.T) {
+	repo := newTestRepo(t)
+	commitPointer(t, repo, "keep.bin", []byte("kept"))
+	runGit(t, repo, "checkout", "-q", "-b", "doomed")
+	commitPointer(t, repo, "gone.bin", []byte("deleted branch"))
+	runGit(t, repo, "checkout", "-q", "main")
+	runGit(t, repo, "branch", "-q", "-D", "doomed")
+
+	withoutReflog, err := reachableLFSObjects(repo, time.Time{})
+	if err != nil {
+		t.Fatalf("reachableLFSObjects failed: %v", err)
+	}
+	if len(withoutReflog) != 1 {
+		t.Fatalf("expected only the main pointer without retention, got %v", withoutReflog)
+	}
+	withReflog, err := reachableLFSObjects(repo, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("reachableLFSObjects failed: %v", err)
+	}
+	if len(withReflog) != 2 {
+		t.Fatalf("expected the HEAD reflog to retain the deleted branch, got %v", withReflog)
+	}
+}
+
+func TestReachableLFSObjectsRetainsOldCommitsWithRecentReflogEntries(t *testing.T) {
+	repo := newTestRepo(t)
+	commitPointer(t, repo, "keep.bin", []byte("kept"))
+	runGit(t, repo, "checkout", "-q", "-b", "doomed")
+	// A month-old commit, with month-old reflog entries...
+	t.Setenv("GIT_COMMITTER_DATE", time.Now().AddDate(0, -1, 0).Format(time.RFC3339))
+	commitPointer(t, repo, "old.bin", []byte("old commit"))
+	runGit(t, repo, "checkout", "-q", "main")
+	// ...that a checkout recorded again just now.
+	_ = os.Unsetenv("GIT_COMMITTER_DATE")
+	runGit(t, repo, "checkout", "-q", "doomed")
+	runGit(t, repo, "checkout", "-q", "main")
+	runGit(t, repo, "branch", "-q", "-D", "doomed")
+
+	if objects, err := reachableLFSObjects(repo, time.Now().AddDate(0, 0, -7)); err != nil || len(objects) != 2 {
+		t.Fatalf("expected the recent checkout to retain the old commit, got %v, %v", objects, err)
+	}
+	// The commit date alone does not retain it.
+	runGit(t, repo, "reflog", "expire", "--expire=now", "--all")
+
+	if objects, err := reachableLFSObjects(repo, time.Now().AddDate(0, 0, -7)); err != nil || len(objects) != 1 {
+		t.Fatalf("expected only the main pointer once the reflog expired, got %v, %v", objects, err)
+	}
+}
+
+func TestPruneRefusesRepoWithoutRefs(t *testing.T) {
+	repo, storeDir, _, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, "--yes", repo, newTestRepo(t)},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "has no refs") {
+		t.Fatalf("expected prune to refuse a repo without refs, got %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); err != nil {
+		t.Fatalf("nothing may be deleted: %v", err)
+	}
+}
+
+func TestReachableLFSObjectsRejectsNonRepo(t *testing.T) {
+	if _, err := reachableLFSObjects(t.TempDir(), time.Time{}); err == nil {
+		t.Fatal("expected an error for a directory that is not a git repository")
+	}
+}
+
+// setupPrune returns a repo referencing one object and a local store holding
+// that object plus an unreferenced one, both stored past the default
+// --min-age.
+func setupPrune(t *testing.T) (repo, storeDir, keptOID, orphanOID string) {
+	t.Helper()
+	repo = newTestRepo(t)
+	commitPointer(t, repo, "kept.bin", []byte("kept payload"))
+
+	storeDir = t.TempDir()
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, storeDir)
+	keptOID = seedLocalObject(t, adapter, []byte("kept payload"))
+	orphanOID = seedLocalObject(t, adapter, []byte("orphaned payload"))
+	store := NewLocalStoreBackend(storeDir)
+	old := time.Now().Add(-2 * DefaultPruneMinAge)
+	for _, oid := range []string{keptOID, orphanOID} {
+		if err := os.Chtimes(store.objectPath(oid), old, old); err != nil {
+			t.Fatalf("failed to back-date %s: %v", oid, err)
+		}
+	}
+	return repo, storeDir, keptOID, orphanOID
+}
+
+func TestPruneCandidatesKeepsRecentObjects(t *testing.T) {
+	cutoff := time.Now().Add(-DefaultPruneMinAge)
+	oldOID, newOID, unknownOID, refOID := strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64), strings.Repeat("d", 64)
+	stored := []RemoteObject{
+		{OID: newOID, Size: 2, ModTime: time.Now()},
+		{OID: oldOID, Size: 1, ModTime: cutoff.Add(-time.Hour)},
+		{OID: unknownOID, Size: 3},
+		{OID: refOID, Size: 4, ModTime: cutoff.Add(-time.Hour)},
+	}
+	referenced := map[string]int64{refOID: 4}
+
+	candidates, recent := pruneCandidates(stored, referenced, cutoff)
+	if len(candidates) != 1 || candidates[0].OID != oldOID {
+		t.Fatalf("expected only the old object as a candidate, got %+v", candidates)
+	}
+	if len(recent) != 2 || recent[0].OID != newOID || recent[1].OID != unknownOID {
+		t.Fatalf("expected the new and unknown-age objects kept, got %+v", recent)
+	}
+
+	candidates, recent = pruneCandidates(stored, referenced, time.Time{})
+	if len(candidates) != 3 || len(recent) != 0 {
+		t.Fatalf("a zero cutoff must disable the age check, got %+v / %+v", candidates, recent)
+	}
+}
+
+func TestRunPruneDryRun(t *testing.T) {
+	repo, storeDir, keptOID, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, "--dry-run", repo},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit %d; stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"Prunable:   1 objects (16 B)", orphanOID, "Dry run: nothing deleted"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, oid := range []string{keptOID, orphanOID} {
+		if _, err := os.Stat(store.objectPath(oid)); err != nil {
+			t.Fatalf("dry run must not delete %s: %v", oid, err)
+		}
+	}
+}
+
+func TestRunPruneDeletesAfterConfirmation(t *testing.T) {
+	repo, storeDir, keptOID, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, repo},
+		strings.NewReader("y\n"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit %d; stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Delete 1 objects (16 B)? [y/N]") {
+		t.Fatalf("expected a confirmation prompt:\n%s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "Deleted 1 of 1 objects (16 B)") {
+		t.Fatalf("expected a deletion summary:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); !os.IsNotExist(err) {
+		t.Fatalf("expected orphan to be deleted, stat err = %v", err)
+	}
+	if _, err := os.Stat(store.objectPath(keptOID)); err != nil {
+		t.Fatalf("referenced object must be kept: %v", err)
+	}
+}
+
+func TestRunPruneDeclinedConfirmation(t *testing.T) {
+	repo, storeDir, _, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, repo},
+		strings.NewReader("\n"), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 when declined, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "Aborted: nothing deleted") {
+		t.Fatalf("expected abort message:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); err != nil {
+		t.Fatalf("declined prune must not delete: %v", err)
+	}
+}
+
+func TestRunPruneKeepsRecentlyStoredObjects(t *testing.T) {
+	repo, storeDir, _, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+	now := time.Now()
+	if err := os.Chtimes(store.objectPath(orphanOID), now, now); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, "--yes", repo},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit %d; stderr: %s", code, stderr.String())
+	}
+	for _, want := range []string{"Recent:     1 objects (16 B) kept by --min-age", "Prunable:   0 objects"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stdout.String())
+		}
+	}
+	if !strings.Contains(stderr.String(), "git fetch --all") {
+		t.Errorf("expected a fetch warning on stderr:\n%s", stderr.String())
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); err != nil {
+		t.Fatalf("recently stored object must be kept: %v", err)
+	}
+
+	stdout.Reset()
+	code = runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, "--yes", "--min-age", "0", repo},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit %d with --min-age 0; stderr: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); !os.IsNotExist(err) {
+		t.Fatalf("--min-age 0 must delete the orphan, stat err = %v", err)
+	}
+}
+
+func TestRunPruneFailsOnBadRepo(t *testing.T) {
+	_, storeDir, _, orphanOID := setupPrune(t)
+	store := NewLocalStoreBackend(storeDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runPrune([]string{"--backend", "local", "--local-store-dir", storeDir, "--yes", t.TempDir()},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1 for an unreadable repo, got %d", code)
+	}
+	if _, err := os.Stat(store.objectPath(orphanOID)); err != nil {
+		t.Fatalf("prune must not delete anything when a repo cannot be scanned: %v", err)
+	}
+}
+
+func TestDriveCLIBackendListAndDeleteObjects(t *testing.T) {
+	other := strings.Repeat("b", 64)
+	bc := helperBridgeClient(t, "MOCK_BRIDGE_LIST="+validOID+":10:1700000000,"+strings.ToUpper(other)+":20,not-an-oid:5")
+	backend := NewDriveCLIBackend(bc, CredentialProviderPassCLI)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	objects, err := backend.ListObjects(session)
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+	if len(objects) != 2 || objects[0].OID != validOID || objects[1].OID != other || objects[1].Size != 20 {
+		t.Fatalf("unexpected objects: %+v", objects)
+	}
+	if !objects[0].ModTime.Equal(time.Unix(1700000000, 0)) || !objects[1].ModTime.IsZero() {
+		t.Fatalf("unexpected modification times: %+v", objects)
+	}
+
+	deleted, err := backend.DeleteObjects(session, []string{validOID, other})
+	if err != nil {
+		t.Fatalf("DeleteObjects failed: %v", err)
+	}
+	if !deleted[validOID] || !deleted[other] {
+		t.Fatalf("expected both objects deleted, got %v", deleted)
+	}
+	if backend.KnownToExist(validOID) {
+		t.Fatal("deleted objects must not be remembered as existing")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
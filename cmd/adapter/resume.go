@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"proton-lfs-cli/internal/slots"
+)
+
+// Operations recorded in the resume journal.
+const (
+	resumeUpload   = "upload"
+	resumeDownload = "download"
+)
+
+// resumeMaxAge is how long an abandoned journal entry and its staged file are
+// kept before startup cleanup removes them.
+const resumeMaxAge = 7 * 24 * time.Hour
+
+// ResumeEntry is the persisted progress of one transfer. A whole-object
+// download has no chunk size or chunks; its staged file holds the bytes done.
+type ResumeEntry struct {
+	OID        string    `json:"oid"`
+	Operation  string    `json:"operation"`
+	Target     string    `json:"target,omitempty"`
+	Size       int64     `json:"size"`
+	ChunkSize  int64     `json:"chunkSize"`
+	BytesDone  int64     `json:"bytesDone"`
+	Chunks     []string  `json:"chunks"`
+	StagedPath string    `json:"stagedPath,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// resumeJournal persists the progress of transfers so that restarting an
+// interrupted git-lfs command continues where it stopped. Each entry is a JSON
+// file keyed by operation, OID and target, and a download keeps its partially
+// written file next to its entry. A lock file next to the entry is held while
+// the transfer runs, so two adapters moving the same object never share them.
+// A nil journal keeps no state.
+type resumeJournal struct {
+	dir string
+	// target names the storage an entry belongs to; entries recorded for
+	// another target are ignored, since their uploaded chunks are elsewhere.
+	target string
+}
+
+func newResumeJournal(dir string) *resumeJournal {
+	return &resumeJournal{dir: dir}
+}
+
+// forTarget returns a view of the journal for transfers to target.
+func (j *resumeJournal) forTarget(target string) *resumeJournal {
+	return &resumeJournal{dir: j.dir, target: target}
+}
+
+// name is the file name stem of op on oid. It carries a hash of the target,
+// so transfers of one object to two storage bases use separate files.
+func (j *resumeJournal) name(op, oid string) string {
+	if j.target == "" {
+		return op + "-" + oid
+	}
+	sum := sha256.Sum256([]byte(j.target))
+	return op + "-" + oid + "-" + hex.EncodeToString(sum[:8])
+}
+
+func (j *resumeJournal) entryPath(op, oid string) string {
+	return filepath.Join(j.dir, j.name(op, oid)+".json")
+}
+
+// stagedPath is where a download of oid is written.
+func (j *resumeJournal) stagedPath(oid string) string {
+	return filepath.Join(j.dir, j.name(resumeDownload, oid)+".download")
+}
+
+func (j *resumeJournal) lockPath(op, oid string) string {
+	return filepath.Join(j.dir, j.name(op, oid)+".lock")
+}
+
+// lock takes the lock of op on oid. It returns nil when another process
+// holds it or it cannot be taken; the caller then transfers without the
+// journal.
+func (j *resumeJournal) lock(op, oid string) *slots.Slot {
+	if j == nil || os.MkdirAll(j.dir, 0o700) != nil {
+		return nil
+	}
+	lock, _ := slots.TryLock(j.lockPath(op, oid))
+	return lock
+}
+
+// load returns the entry for op and oid, or nil if there is none.
+func (j *resumeJournal) load(op, oid string) *ResumeEntry {
+	if j == nil {
+		return nil
+	}
+	data, err := os.ReadFile(j.entryPath(op, oid))
+	if err != nil {
+		return nil
+	}
+	var entry ResumeEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.OID != oid || entry.Operation != op || entry.Target != j.target {
+		return nil
+	}
+	return &entry
+}
+
+// save writes entry atomically through a temp file and rename.
+func (j *resumeJournal) save(entry *ResumeEntry) error {
+	if j == nil {
+		return nil
+	}
+	entry.Target = j.target
+	entry.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(j.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, j.entryPath(entry.Operation, entry.OID))
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+// remove drops the entry for op and oid. A staged download file is left to
+// the caller, which has handed it on or discards it itself.
+func (j *resumeJournal) remove(op, oid string) {
+	if j != nil {
+		_ = os.Remove(j.entryPath(op, oid))
+	}
+}
+
+// expire removes entries, staged files, temp files and unheld lock files not
+// updated within maxAge, and returns how many files it removed.
+func (j *resumeJournal) expire(maxAge time.Duration) int {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return 0
+	}
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		isLock := strings.HasSuffix(name, ".lock")
+		if entry.IsDir() || !(isLock || strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".download") || strings.HasPrefix(name, ".tmp-")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(j.dir, name)
+		if isLock {
+			lock, _ := slots.TryLock(path)
+			if lock == nil {
+				continue
+			}
+			lock.Release()
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// chunkResume tracks the completed chunks of one transfer and records each
+// of them in the journal as it finishes.
+type chunkResume struct {
+	journal *resumeJournal
+	mu      sync.Mutex
+	entry   ResumeEntry
+	done    map[string]bool
+	resumed bool
+}
+
+// resume picks up the journal entry for op on the manifest's object. Chunks
+// recorded for a different split of the object are ignored.
+func (j *resumeJournal) resume(op string, manifest *ChunkManifest) *chunkResume {
+	r := &chunkResume{
+		journal: j,
+		entry:   ResumeEntry{OID: manifest.OID, Operation: op, Size: manifest.Size, ChunkSize: manifest.ChunkSize, Chunks: []string{}},
+		done:    make(map[string]bool),
+	}
+	prev := j.load(op, manifest.OID)
+	if prev == nil || prev.Size != manifest.Size || prev.ChunkSize != manifest.ChunkSize {
+		return r
+	}
+	recorded := make(map[string]bool, len(prev.Chunks))
+	for _, name := range prev.Chunks {
+		recorded[name] = true
+	}
+	for _, c := range manifest.Chunks {
+		if recorded[c.Name] {
+			r.done[c.Name] = true
+			r.entry.Chunks = append(r.entry.Chunks, c.Name)
+			r.entry.BytesDone += c.Size
+		}
+	}
+	r.entry.StagedPath = prev.StagedPath
+	r.resumed = len(r.done) > 0
+	return r
+}
+
+// reset forgets every recorded chunk, e.g. when the staged file is gone.
+func (r *chunkResume) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = make(map[string]bool)
+	r.entry.Chunks = []string{}
+	r.entry.BytesDone = 0
+	r.resumed = false
+}
+
+func (r *chunkResume) isDone(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done[name]
+}
+
+// complete records a finished chunk. Journal write failures only cost the
+// ability to resume, so they are not reported.
+func (r *chunkResume) complete(c ChunkInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done[c.Name] {
+		return
+	}
+	r.done[c.Name] = true
+	r.entry.Chunks = append(r.entry.Chunks, c.Name)
+	r.entry.BytesDone += c.Size
+	_ = r.journal.save(&r.entry)
+}
+
+// finish drops the journal entry once the transfer is complete or abandoned.
+func (r *chunkResume) finish() {
+	r.journal.remove(r.entry.Operation, r.entry.OID)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeJournalSaveLoadExpire(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	if journal.load(resumeUpload, validOID) != nil {
+		t.Fatal("expected no entry in an empty journal")
+	}
+	entry := &ResumeEntry{OID: validOID, Operation: resumeUpload, Size: 8, ChunkSize: 4, Chunks: []string{"a"}, BytesDone: 4}
+	if err := journal.save(entry); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	got := journal.load(resumeUpload, validOID)
+	if got == nil || got.BytesDone != 4 || len(got.Chunks) != 1 || got.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+	if journal.load(resumeDownload, validOID) != nil {
+		t.Fatal("entries must be keyed by operation")
+	}
+
+	if removed := journal.expire(time.Hour); removed != 0 {
+		t.Fatalf("expected a fresh entry to survive, removed %d", removed)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(journal.entryPath(resumeUpload, validOID), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if removed := journal.expire(time.Hour); removed != 1 {
+		t.Fatalf("expected the old entry to expire, removed %d", removed)
+	}
+	if journal.load(resumeUpload, validOID) != nil {
+		t.Fatal("expected the expired entry to be gone")
+	}
+}
+
+// interruptedDownload stages the first two chunks of payload in journal as an
+// interrupted chunked download would have left them.
+func interruptedDownload(t *testing.T, journal *resumeJournal, payload []byte, staged []byte) *ChunkManifest {
+	t.Helper()
+	oid := oidOf(payload)
+	manifest, err := buildChunkManifest(oid, writeTempObject(t, payload), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(journal.dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal.stagedPath(oid), staged, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entry := &ResumeEntry{
+		OID:        oid,
+		Operation:  resumeDownload,
+		Size:       manifest.Size,
+		ChunkSize:  manifest.ChunkSize,
+		BytesDone:  8,
+		Chunks:     []string{manifest.Chunks[0].Name, manifest.Chunks[1].Name},
+		StagedPath: journal.stagedPath(oid),
+	}
+	if err := journal.save(entry); err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func TestDriveCLIBackendResumesChunkedDownload(t *testing.T) {
+	partDir := t.TempDir()
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	backend, session := chunkedBackend(t, partDir, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	payload := []byte("0123456789abcdef")
+	oid := oidOf(payload)
+	if _, err := backend.Upload(session, oid, writeTempObject(t, payload), 0, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	journal := newResumeJournal(t.TempDir())
+	backend.SetResumeJournal(journal)
+	staged := append([]byte("01234567"), make([]byte, 8)...)
+	interruptedDownload(t, journal, payload, staged)
+	if err := os.Remove(spawnLog); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported int64
+	path, size, err := backend.Download(session, oid, func(n int64) { reported = n })
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer os.Remove(path)
+	data, _ := os.ReadFile(path)
+	if string(data) != string(payload) || size != 16 || reported != 16 {
+		t.Fatalf("Download returned %q (size %d, progress %d)", data, size, reported)
+	}
+	logged, _ := os.ReadFile(spawnLog)
+	// The whole-object attempt, the manifest and the two missing chunks.
+	if got := strings.Count(string(logged), "download"); got != 4 {
+		t.Fatalf("expected only the missing chunks to be downloaded, got %d downloads", got)
+	}
+	if journal.load(resumeDownload, oid) != nil {
+		t.Fatal("expected the journal entry to be removed after completion")
+	}
+}
+
+func TestDriveCLIBackendResumedDownloadIsVerified(t *testing.T) {
+	partDir := t.TempDir()
+	backend, session := chunkedBackend(t, partDir)
+	payload := []byte("0123456789abcdef")
+	oid := oidOf(payload)
+	if _, err := backend.Upload(session, oid, writeTempObject(t, payload), 0, nil); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	journal := newResumeJournal(t.TempDir())
+	backend.SetResumeJournal(journal)
+	// The staged bytes of the recorded chunks were damaged after they were written.
+	interruptedDownload(t, journal, payload, []byte("XXXXXXXXXXXXXXXX"))
+
+	if _, _, err := backend.Download(session, oid, nil); !errors.Is(err, errHashMismatch) {
+		t.Fatalf("expected the resumed download to fail verification, got %v", err)
+	}
+	if journal.load(resumeDownload, oid) != nil {
+		t.Fatal("expected the bad journal entry to be dropped")
+	}
+
+	path, _, err := backend.Download(session, oid, nil)
+	if err != nil {
+		t.Fatalf("expected the next attempt to start over and succeed: %v", err)
+	}
+	defer os.Remove(path)
+	if data, _ := os.ReadFile(path); string(data) != string(payload) {
+		t.Fatalf("unexpected content %q", data)
+	}
+}
+
+func TestDriveCLIBackendResumesChunkedUpload(t *testing.T) {
+	partDir := t.TempDir()
+	spawnLog := filepath.Join(t.TempDir(), "spawns.log")
+	backend, session := chunkedBackend(t, partDir, "MOCK_BRIDGE_SPAWN_LOG="+spawnLog)
+	journal := newResumeJournal(t.TempDir())
+	backend.SetResumeJournal(journal)
+	payload := []byte("0123456789abcdef")
+	oid := oidOf(payload)
+	source := writeTempObject(t, payload)
+	manifest, err := buildChunkManifest(oid, source, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := []string{manifest.Chunks[0].Name, manifest.Chunks[1].Name}
+	if err := journal.save(&ResumeEntry{OID: oid, Operation: resumeUpload, Size: 16, ChunkSize: 4, BytesDone: 8, Chunks: done}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(spawnLog); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported int64
+	if _, err := backend.Upload(session, oid, source, 16, func(n int64) { reported = n }); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if reported != 16 {
+		t.Fatalf("expected progress to cover the recorded chunks, got %d", reported)
+	}
+	logged, _ := os.ReadFile(spawnLog)
+	// Two missing chunks plus the manifest.
+	if got := strings.Count(string(logged), "upload"); got != 3 {
+		t.Fatalf("expected recorded chunks to be skipped, got %d uploads", got)
+	}
+	for _, name := range done {
+		if _, err := os.Stat(filepath.Join(partDir, oid, name)); !os.IsNotExist(err) {
+			t.Fatalf("recorded chunk %s must not be re-sent", name)
+		}
+	}
+	if journal.load(resumeUpload, oid) != nil {
+		t.Fatal("expected the journal entry to be removed after completion")
+	}
+}
+
+func TestResumeJournalIgnoresOtherTargets(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	entry := &ResumeEntry{OID: validOID, Operation: resumeUpload, Chunks: []string{"a"}}
+	if err := journal.forTarget("LFS/personal").save(entry); err != nil {
+		t.Fatal(err)
+	}
+	if journal.forTarget("LFS/work").load(resumeUpload, validOID) != nil {
+		t.Fatal("chunks uploaded to another storage base must not be resumed")
+	}
+	if journal.forTarget("LFS/personal").load(resumeUpload, validOID) == nil {
+		t.Fatal("expected the entry for its own target")
+	}
+}
+
+// wholeObjectBackend returns a backend whose mock bridge serves payload as a
+// whole object, with journal enabled.
+func wholeObjectBackend(t *testing.T, journal *resumeJournal, payload []byte, env ...string) (*DriveCLIBackend, *Session) {
+	t.Helper()
+	env = append(env, "MOCK_BRIDGE_DOWNLOAD_CONTENT="+string(payload))
+	backend := NewDriveCLIBackend(helperBridgeClient(t, env...), CredentialProviderPassCLI)
+	backend.SetResumeJournal(journal)
+	session := &Session{Initialized: true, Token: "direct-bridge"}
+	if err := backend.Initialize(session); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return backend, session
+}
+
+func TestDriveCLIBackendResumesWholeObjectDownload(t *testing.T) {
+	journal := newResumeJournal(t.TempDir()).forTarget("default:LFS")
+	payload := []byte("a small object below the chunk threshold")
+	oid := oidOf(payload)
+
+	interrupted, session := wholeObjectBackend(t, journal, payload, "MOCK_BRIDGE_DOWNLOAD_FAIL_AT=10")
+	if _, _, err := interrupted.Download(session, oid, nil); err == nil {
+		t.Fatal("expected the interrupted download to fail")
+	}
+	entry := journal.load(resumeDownload, oid)
+	if entry == nil || entry.StagedPath != journal.stagedPath(oid) {
+		t.Fatalf("expected a journal entry for the staged file, got %+v", entry)
+	}
+	if data, _ := os.ReadFile(journal.stagedPath(oid)); string(data) != string(payload[:10]) {
+		t.Fatalf("expected the first 10 bytes to be staged, got %q", data)
+	}
+
+	backend, session := wholeObjectBackend(t, journal, payload)
+	var reported int64
+	path, size, err := backend.Download(session, oid, func(n int64) { reported = n })
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer os.Remove(path)
+	if data, _ := os.ReadFile(path); string(data) != string(payload) || size != int64(len(payload)) {
+		t.Fatalf("Download returned %q (size %d)", data, size)
+	}
+	if reported != int64(len(payload)) {
+		t.Fatalf("expected progress to count the staged bytes, got %d", reported)
+	}
+	if journal.load(resumeDownload, oid) != nil {
+		t.Fatal("expected the journal entry to be removed after completion")
+	}
+}
+
+func TestDriveCLIBackendResumedWholeObjectIsVerified(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	payload := []byte("a small object below the chunk threshold")
+	oid := oidOf(payload)
+	if err := os.MkdirAll(journal.dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// The staged prefix was damaged after the interrupted attempt wrote it.
+	if err := os.WriteFile(journal.stagedPath(oid), []byte("XXXXXXXXXX"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.save(&ResumeEntry{OID: oid, Operation: resumeDownload, BytesDone: 10, Chunks: []string{}, StagedPath: journal.stagedPath(oid)}); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, session := wholeObjectBackend(t, journal, payload)
+	if _, _, err := backend.Download(session, oid, nil); !errors.Is(err, errHashMismatch) {
+		t.Fatalf("expected the resumed download to fail verification, got %v", err)
+	}
+	path, _, err := backend.Download(session, oid, nil)
+	if err != nil {
+		t.Fatalf("expected the next attempt to start over and succeed: %v", err)
+	}
+	defer os.Remove(path)
+	if data, _ := os.ReadFile(path); string(data) != string(payload) {
+		t.Fatalf("unexpected content %q", data)
+	}
+}
+
+func TestDriveCLIBackendSkipsJournalLockedByAnotherTransfer(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	payload := []byte("pulled by two adapters at once")
+	oid := oidOf(payload)
+	held := journal.lock(resumeDownload, oid)
+	if held == nil {
+		t.Fatal("expected to take the lock")
+	}
+	defer held.Release()
+	if err := os.WriteFile(journal.stagedPath(oid), []byte("other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, session := wholeObjectBackend(t, journal, payload)
+	path, _, err := backend.Download(session, oid, nil)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer os.Remove(path)
+	if path == journal.stagedPath(oid) {
+		t.Fatal("a locked staged file must not be used")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(payload) {
+		t.Fatalf("unexpected content %q", data)
+	}
+	if data, _ := os.ReadFile(journal.stagedPath(oid)); string(data) != "other" {
+		t.Fatalf("the other transfer's staged file was touched: %q", data)
+	}
+}
+
+func TestResumeJournalFilesAreKeyedByTarget(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	personal, work := journal.forTarget("default:LFS"), journal.forTarget("work:LFS")
+	if personal.stagedPath(validOID) == work.stagedPath(validOID) || personal.entryPath(resumeUpload, validOID) == work.entryPath(resumeUpload, validOID) {
+		t.Fatal("transfers to different targets must not share journal files")
+	}
+	first := personal.lock(resumeDownload, validOID)
+	if first == nil {
+		t.Fatal("expected to take the lock")
+	}
+	defer first.Release()
+	if other := work.lock(resumeDownload, validOID); other == nil {
+		t.Fatal("a transfer to another target must not wait for this lock")
+	} else {
+		other.Release()
+	}
+	if again := personal.lock(resumeDownload, validOID); again != nil {
+		again.Release()
+		t.Fatal("a second transfer to the same target must not take the lock")
+	}
+}
+
+func TestResumeJournalExpireKeepsHeldLocks(t *testing.T) {
+	journal := newResumeJournal(t.TempDir())
+	held := journal.lock(resumeDownload, validOID)
+	if held == nil {
+		t.Fatal("expected to take the lock")
+	}
+	defer held.Release()
+	free := journal.lock(resumeUpload, validOID)
+	free.Release()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, op := range []string{resumeDownload, resumeUpload} {
+		if err := os.Chtimes(journal.lockPath(op, validOID), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if removed := journal.expire(time.Hour); removed != 1 {
+		t.Fatalf("expected only the unheld lock to expire, removed %d", removed)
+	}
+	if _, err := os.Stat(journal.lockPath(resumeDownload, validOID)); err != nil {
+		t.Fatalf("a held lock must be kept: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Default retry policy values.
+const (
+	DefaultRetryAttempts       = 3
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	DefaultRetryMaxBackoff     = 10 * time.Second
+	DefaultRetryMaxElapsed     = 2 * time.Minute
+)
+
+// RetryPolicy controls how transient backend failures are retried.
+// MaxAttempts counts the first try, so 1 disables retries.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxElapsed     time.Duration
+
+	sleep func(time.Duration) // overridable for tests
+	now   func() time.Time
+	rand  func() float64
+}
+
+// DefaultRetryPolicy returns the retry policy used when nothing is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultRetryAttempts,
+		InitialBackoff: DefaultRetryInitialBackoff,
+		MaxBackoff:     DefaultRetryMaxBackoff,
+		MaxElapsed:     DefaultRetryMaxElapsed,
+	}
+}
+
+// isRetryableError reports whether err is a BackendError classified as
+// retryable. Authentication (401), CAPTCHA (407), conflict (409) and
+// not-configured (501) errors are never retried regardless of classification.
+func isRetryableError(err error) bool {
+	var backendErr *BackendError
+	if !errors.As(err, &backendErr) {
+		return false
+	}
+	switch backendErr.Code {
+	case 401, 407, 409, 501:
+		return false
+	}
+	return backendErr.Retryable
+}
+
+// backoff returns the delay before retry number n (1-based): exponential
+// growth from InitialBackoff, capped at MaxBackoff, with full jitter in the
+// upper half so concurrent adapters do not retry in lockstep.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < n && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	random := rand.Float64
+	if p.rand != nil {
+		random = p.rand
+	}
+	half := delay / 2
+	return half + time.Duration(random()*float64(delay-half))
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, or the policy
+// is exhausted. It returns the number of retries performed (attempts - 1).
+func (p RetryPolicy) Do(fn func() error) (int, error) {
+	sleep, now := time.Sleep, time.Now
+	if p.sleep != nil {
+		sleep = p.sleep
+	}
+	if p.now != nil {
+		now = p.now
+	}
+
+	start := now()
+	retries := 0
+	for {
+		err := fn()
+		if err == nil || !isRetryableError(err) || retries+1 >= p.MaxAttempts {
+			return retries, err
+		}
+		delay := p.backoff(retries + 1)
+		if p.MaxElapsed > 0 && now().Add(delay).Sub(start) > p.MaxElapsed {
+			return retries, err
+		}
+		sleep(delay)
+		retries++
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// testRetryPolicy returns a policy that records sleeps instead of blocking.
+func testRetryPolicy(attempts int, slept *[]time.Duration) RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.MaxAttempts = attempts
+	p.sleep = func(d time.Duration) { *slept = append(*slept, d) }
+	p.rand = func() float64 { return 1 }
+	return p
+}
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"500", newBackendError(500, "server", nil), true},
+		{"502", newBackendError(502, "bad gateway", nil), true},
+		{"503 concurrency limit", mapBridgeError(errors.New("bridge concurrency limit reached (10)"), "x"), true},
+		{"timeout", mapBridgeError(errors.New("request timed out"), "x"), true},
+		{"401", newBackendError(401, "auth", nil), false},
+		{"407", newBackendError(407, "captcha", nil), false},
+		{"409", newBackendError(409, "conflict", nil), false},
+		{"429", newBackendError(429, "rate limited", nil), false},
+		{"501", newBackendError(501, "not configured", nil), false},
+		{"wrapped 503", fmt.Errorf("wrapped: %w", newBackendError(503, "unavailable", nil)), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryableError(tc.err); got != tc.want {
+				t.Fatalf("isRetryableError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffIsExponentialAndCapped(t *testing.T) {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = 100 * time.Millisecond
+	p.MaxBackoff = 1 * time.Second
+
+	p.rand = func() float64 { return 1 }
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Fatalf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+
+	p.rand = func() float64 { return 0 }
+	if got := p.backoff(3); got != 200*time.Millisecond {
+		t.Fatalf("minimum jittered backoff(3) = %s, want 200ms", got)
+	}
+}
+
+func TestRetryPolicyDoRetriesRetryableErrors(t *testing.T) {
+	var slept []time.Duration
+	p := testRetryPolicy(3, &slept)
+
+	calls := 0
+	retries, err := p.Do(func() error {
+		calls++
+		if calls < 3 {
+			return newBackendError(503, "unavailable", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 || retries != 2 || len(slept) != 2 {
+		t.Fatalf("calls=%d retries=%d sleeps=%d, want 3/2/2", calls, retries, len(slept))
+	}
+}
+
+func TestRetryPolicyDoStopsAtMaxAttempts(t *testing.T) {
+	var slept []time.Duration
+	p := testRetryPolicy(2, &slept)
+
+	calls := 0
+	retries, err := p.Do(func() error {
+		calls++
+		return newBackendError(502, "bad gateway", nil)
+	})
+	if err == nil {
+		t.Fatal("expected error after exhausting attempts")
+	}
+	if calls != 2 || retries != 1 {
+		t.Fatalf("calls=%d retries=%d, want 2/1", calls, retries)
+	}
+}
+
+func TestRetryPolicyDoDoesNotRetryAuthErrors(t *testing.T) {
+	var slept []time.Duration
+	p := testRetryPolicy(5, &slept)
+
+	calls := 0
+	retries, err := p.Do(func() error {
+		calls++
+		return newBackendError(401, "session is invalid or expired", nil)
+	})
+	if err == nil || calls != 1 || retries != 0 || len(slept) != 0 {
+		t.Fatalf("expected a single attempt for 401, got calls=%d retries=%d err=%v", calls, retries, err)
+	}
+}
+
+func TestRetryPolicyDoHonoursMaxElapsed(t *testing.T) {
+	var slept []time.Duration
+	p := testRetryPolicy(10, &slept)
+	p.InitialBackoff = time.Second
+	p.MaxElapsed = 2500 * time.Millisecond
+
+	clock := time.Unix(0, 0)
+	p.now = func() time.Time { return clock }
+	p.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		clock = clock.Add(d)
+	}
+
+	calls := 0
+	_, err := p.Do(func() error {
+		calls++
+		return newBackendError(503, "unavailable", nil)
+	})
+	if err == nil {
+		t.Fatal("expected error once max elapsed is reached")
+	}
+	// 1s + 2s would exceed 2.5s, so only the first retry happens.
+	if calls != 2 || len(slept) != 1 {
+		t.Fatalf("calls=%d sleeps=%d, want 2/1", calls, len(slept))
+	}
+}
+
+// flakyBackend fails the first failures calls with err, then delegates.
+type flakyBackend struct {
+	TransferBackend
+	failures int
+	err      error
+	calls    int
+}
+
+func (b *flakyBackend) Download(session *Session, oid string, progress ProgressFunc) (string, int64, error) {
+	b.calls++
+	if b.calls <= b.failures {
+		return "", 0, b.err
+	}
+	return b.TransferBackend.Download(session, oid, progress)
+}
+
+func TestDownloadRetriesTransientFailureAndRecordsRetryCount(t *testing.T) {
+	statusPath := filepath.Join(t.TempDir(), "status.json")
+	t.Setenv(config.EnvStatusFile, statusPath)
+
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.session = &Session{Initialized: true}
+
+	payload := []byte("retry-me")
+	oid := seedLocalObject(t, adapter, payload)
+	flaky := &flakyBackend{TransferBackend: adapter.backend, failures: 2, err: newBackendError(503, "drive service is unavailable", nil)}
+	adapter.backend = flaky
+
+	msg := InboundMessage{Event: EventDownload, OID: oid, Size: int64(len(payload))}
+	buf := new(bytes.Buffer)
+	if err := adapter.handleDownload(&msg, json.NewEncoder(buf)); err != nil {
+		t.Fatalf("handleDownload returned error: %v", err)
+	}
+
+	msgs := decodeAllMessages(t, buf.Bytes())
+	last := msgs[len(msgs)-1]
+	if last.Event != EventComplete || last.Error != nil {
+		t.Fatalf("expected successful completion after retries, got %+v", last)
+	}
+	if flaky.calls != 3 {
+		t.Fatalf("expected 3 backend calls, got %d", flaky.calls)
+	}
+
+	report, err := config.ReadStatus()
+	if err != nil {
+		t.Fatalf("ReadStatus: %v", err)
+	}
+	if report.State != config.StateOK || report.RetryCount != 2 {
+		t.Fatalf("expected ok status with retryCount=2, got %+v", report)
+	}
+}
+
+func TestDownloadDoesNotRetryAuthFailure(t *testing.T) {
+	statusPath := filepath.Join(t.TempDir(), "status.json")
+	t.Setenv(config.EnvStatusFile, statusPath)
+
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	adapter.session = &Session{Initialized: true}
+	flaky := &flakyBackend{TransferBackend: adapter.backend, failures: 5, err: newBackendError(401, "session is invalid or expired", nil)}
+	adapter.backend = flaky
+
+	msg := InboundMessage{Event: EventDownload, OID: validOID}
+	buf := new(bytes.Buffer)
+	if err := adapter.handleDownload(&msg, json.NewEncoder(buf)); err != nil {
+		t.Fatalf("handleDownload returned error: %v", err)
+	}
+
+	msgs := decodeAllMessages(t, buf.Bytes())
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != 401 {
+		t.Fatalf("expected a single 401 completion, got %+v", msgs)
+	}
+	if flaky.calls != 1 {
+		t.Fatalf("expected 1 backend call for 401, got %d", flaky.calls)
+	}
+	report, err := config.ReadStatus()
+	if err != nil {
+		t.Fatalf("ReadStatus: %v", err)
+	}
+	if report.RetryCount != 0 || report.State != config.StateAuthRequired {
+		t.Fatalf("unexpected status for 401: %+v", report)
+	}
+}
+
+// seedLocalObject stores payload in the adapter's local store and returns its OID.
+func seedLocalObject(t *testing.T, adapter *Adapter, payload []byte) string {
+	t.Helper()
+	sum := sha256.Sum256(payload)
+	oid := hex.EncodeToString(sum[:])
+	objectPath := adapter.localObjectPath(oid)
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o700); err != nil {
+		t.Fatalf("failed to create object dir: %v", err)
+	}
+	if err := os.WriteFile(objectPath, payload, 0o600); err != nil {
+		t.Fatalf("failed to seed object: %v", err)
+	}
+	return oid
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// routeBackend switches to the backend of the first route matching the git
+// remote announced in init. Without a matching route the configured backend
+// is kept.
+func (a *Adapter) routeBackend(remote string) error {
+	if len(a.routes) == 0 || a.newBackend == nil {
+		return nil
+	}
+	remoteURL := a.remoteURL
+	if remoteURL == nil {
+		remoteURL = func(remote string) string { return gitRemoteURL(".", remote) }
+	}
+	route := config.MatchRoute(a.routes, remote, remoteURL(remote))
+	if route == nil {
+		return nil
+	}
+	backend, err := a.newBackend(route)
+	if err != nil {
+		return err
+	}
+	if closer, ok := a.backend.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	a.backend = backend
+	if kind := strings.ToLower(strings.TrimSpace(route.Backend)); kind != "" {
+		a.backendKind = kind
+	}
+	if provider := strings.ToLower(strings.TrimSpace(route.CredentialProvider)); provider != "" {
+		a.credentialProvider = provider
+	}
+	if dir := strings.TrimSpace(route.LocalStoreDir); dir != "" {
+		a.localStoreDir = dir
+	}
+	a.logger.Info("Remote routed", "remote", remote, "backend", a.backendKind, "storage_base", route.StorageBase)
+	return nil
+}
+
+// gitRemoteURL returns the URL of the named remote in the repository at dir.
+// git-lfs passes a URL instead of a name for anonymous remotes, so a remote
+// that is not configured is returned as is.
+func gitRemoteURL(dir, remote string) string {
+	if remote == "" {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dir, "config", "--get", "remote."+remote+".url").Output()
+	if err != nil {
+		return remote
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// remoteRoute finds the route for a remote of the repository at dir, for the
+// maintenance commands' --remote flag. An empty remote selects no route; a
+// remote without a route is an error, so that prune never falls back to
+// another storage base by accident.
+func remoteRoute(dir, remote string) (*config.Route, error) {
+	if remote == "" {
+		return nil, nil
+	}
+	route := config.MatchRoute(config.LoadPrefs().Routes, remote, gitRemoteURL(dir, remote))
+	if route == nil {
+		return nil, fmt.Errorf("no route configured for remote %q", remote)
+	}
+	return route, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// routedAdapter returns an adapter whose default backend is a local store in
+// defaultDir and whose routes are built from the same flags as main.
+func routedAdapter(t *testing.T, defaultDir string, routes []config.Route) *Adapter {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addBackendFlags(fs)
+	if err := fs.Parse([]string{"--backend", "local", "--local-store-dir", defaultDir}); err != nil {
+		t.Fatal(err)
+	}
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, defaultDir)
+	adapter.routes = routes
+	adapter.newBackend = opts.newBackendFor
+	adapter.remoteURL = func(string) string { return "https://github.com/acme/app.git" }
+	return adapter
+}
+
+func uploadThroughRemote(t *testing.T, adapter *Adapter, remote string, payload []byte) string {
+	t.Helper()
+	oid := oidOf(payload)
+	input := strings.Join([]string{
+		fmt.Sprintf(`{"event":"init","operation":"upload","remote":%q,"concurrent":false,"concurrenttransfers":1}`, remote),
+		fmt.Sprintf(`{"event":"upload","oid":"%s","size":%d,"path":%q,"action":null}`, oid, len(payload), writeTempObject(t, payload)),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(input), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	msgs := decodeAllMessages(t, out.Bytes())
+	if last := msgs[len(msgs)-1]; last.Event != EventComplete || last.Error != nil {
+		t.Fatalf("upload did not complete: %+v", last)
+	}
+	return oid
+}
+
+func TestHandleInitRoutesRemoteToBackend(t *testing.T) {
+	defaultDir, routedDir := t.TempDir(), t.TempDir()
+	adapter := routedAdapter(t, defaultDir, []config.Route{
+		{Remote: "backup", LocalStoreDir: t.TempDir()},
+		{URL: "https://github.com/acme/*", LocalStoreDir: routedDir},
+	})
+
+	oid := uploadThroughRemote(t, adapter, "origin", []byte("client data"))
+	if _, err := os.Stat(fanoutPath(routedDir, oid)); err != nil {
+		t.Fatalf("expected the object in the routed store: %v", err)
+	}
+	if _, err := os.Stat(fanoutPath(defaultDir, oid)); !os.IsNotExist(err) {
+		t.Fatalf("the default store must not receive routed objects, stat err = %v", err)
+	}
+}
+
+func TestHandleInitWithoutMatchingRouteKeepsBackend(t *testing.T) {
+	defaultDir := t.TempDir()
+	adapter := routedAdapter(t, defaultDir, []config.Route{{Remote: "backup", LocalStoreDir: t.TempDir()}})
+
+	oid := uploadThroughRemote(t, adapter, "origin", []byte("default data"))
+	if _, err := os.Stat(fanoutPath(defaultDir, oid)); err != nil {
+		t.Fatalf("expected the object in the default store: %v", err)
+	}
+}
+
+func TestNewBackendForAppliesRoute(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addBackendFlags(fs)
+	if err := fs.Parse([]string{"--backend", "local"}); err != nil {
+		t.Fatal(err)
+	}
+	route := &config.Route{Backend: "SDK", StorageBase: "LFS/client", CredentialProvider: CredentialProviderGitCredential}
+
+	backend, err := opts.newBackendFor(route)
+	if err != nil {
+		t.Fatalf("newBackendFor failed: %v", err)
+	}
+	drive, ok := backend.(*DriveCLIBackend)
+	if !ok {
+		t.Fatalf("expected the route to select the sdk backend, got %T", backend)
+	}
+	if drive.bridge.storageBase != "LFS/client" || drive.credentialProvider != CredentialProviderGitCredential {
+		t.Fatalf("route not applied: storageBase=%q provider=%q", drive.bridge.storageBase, drive.credentialProvider)
+	}
+	if opts.kindFor(route) != BackendSDK || opts.kindFor(nil) != BackendLocal {
+		t.Fatal("kindFor must prefer the route's backend")
+	}
+}
+
+func TestNewBackendForAppliesProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prefs := config.DefaultPreferences()
+	prefs.Profiles = []config.Profile{
+		{Name: "work", CredentialProvider: CredentialProviderGitCredential, Account: "me@work.example", StorageBase: "LFS/work", SessionDir: "/tmp/work-session"},
+		{Name: "personal", Account: "me@example.com"},
+	}
+	prefs.ActiveProfile = "personal"
+	if err := config.SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+	repo := newTestRepo(t)
+	runGit(t, repo, "config", config.GitConfigProfileKey, "work")
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv(EnvProfile, "")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addBackendFlags(fs)
+	if err := fs.Parse([]string{"--backend", "sdk"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveSettings(fs, repo); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := opts.newBackendFor(nil)
+	if err != nil {
+		t.Fatalf("newBackendFor failed: %v", err)
+	}
+	drive := backend.(*DriveCLIBackend)
+	if drive.profile != "work" || drive.account != "me@work.example" || drive.credentialProvider != CredentialProviderGitCredential {
+		t.Fatalf("repo profile not applied: %+v", drive.operationCredentials())
+	}
+	if drive.bridge.storageBase != "LFS/work" || drive.bridge.sessionDir != "/tmp/work-session" {
+		t.Fatalf("profile storage not applied: storageBase=%q sessionDir=%q", drive.bridge.storageBase, drive.bridge.sessionDir)
+	}
+
+	// A route's profile wins over the repository setting.
+	backend, err = opts.newBackendFor(&config.Route{Profile: "personal", StorageBase: "LFS/home"})
+	if err != nil {
+		t.Fatalf("newBackendFor failed: %v", err)
+	}
+	drive = backend.(*DriveCLIBackend)
+	if drive.profile != "personal" || drive.bridge.storageBase != "LFS/home" {
+		t.Fatalf("route profile not applied: profile=%q storageBase=%q", drive.profile, drive.bridge.storageBase)
+	}
+	if _, err := opts.newBackendFor(&config.Route{Profile: "missing"}); err == nil {
+		t.Fatal("expected an unknown profile to be an error")
+	}
+}
+
+func TestGitRemoteURL(t *testing.T) {
+	repo := newTestRepo(t)
+	runGit(t, repo, "remote", "add", "origin", "git@github.com:acme/app.git")
+
+	if got := gitRemoteURL(repo, "origin"); got != "git@github.com:acme/app.git" {
+		t.Fatalf("gitRemoteURL(origin) = %q", got)
+	}
+	if got := gitRemoteURL(repo, "https://example.com/app.git"); got != "https://example.com/app.git" {
+		t.Fatalf("an anonymous remote URL must be returned as is, got %q", got)
+	}
+}
+
+func TestRemoteRouteRequiresMatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prefs := config.DefaultPreferences()
+	prefs.Routes = []config.Route{{Remote: "origin", StorageBase: "LFS/work"}}
+	if err := config.SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+	repo := newTestRepo(t)
+
+	if route, err := remoteRoute(repo, ""); route != nil || err != nil {
+		t.Fatalf("expected no route without --remote, got %+v, %v", route, err)
+	}
+	if route, err := remoteRoute(repo, "origin"); err != nil || route.StorageBase != "LFS/work" {
+		t.Fatalf("expected the origin route, got %+v, %v", route, err)
+	}
+	if _, err := remoteRoute(repo, "upstream"); err == nil {
+		t.Fatal("expected an error for a remote without a route")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// Setting sources reported by --print-config, from highest to lowest
+// precedence.
+const (
+	sourceFlag      = config.SourceFlag
+	sourceEnv       = config.SourceEnv
+	sourceGitConfig = config.SourceGitConfig
+	sourceLFSConfig = config.SourceLFSConfig
+	sourcePrefs     = config.SourcePrefs
+	sourceDefault   = config.SourceDefault
+)
+
+// adapterSetting describes an adapter flag and the environment variable that
+// provides its default. gitConfig marks the settings that can also be set
+// with git config lfs.proton.<flag>. lfsConfig marks the few tuning settings
+// that a repository's .lfsconfig may set as well: it is committed by whoever
+// controls the repository, so it must not choose the backend, the storage
+// location, the credentials, any executable or the machine-wide limits.
+type adapterSetting struct {
+	flag      string
+	env       string
+	gitConfig bool
+	lfsConfig bool
+}
+
+// adapterSettings lists the persisted settings of config.Settings, which
+// decides which of them git config and .lfsconfig may set, followed by the
+// settings that exist only as adapter flags. Settings the adapter has no
+// flag for, such as notifications, are skipped where the flags are looked up.
+var adapterSettings = append(persistedSettings(), []adapterSetting{
+	{flag: "drive-cli-bin", env: EnvDriveCLIBin},
+	{flag: "bridge-mode", env: EnvBridgeMode},
+	{flag: "cache", env: EnvCache},
+	{flag: "cache-dir", env: EnvCacheDir},
+	{flag: "chunk-threshold-mb", env: EnvChunkThresholdMB},
+	{flag: "chunk-size-mb", env: EnvChunkSizeMB},
+	{flag: "retry-attempts", env: EnvRetryAttempts},
+	{flag: "retry-max-elapsed", env: EnvRetryMaxElapsed},
+	{flag: "allow-mock-transfers", env: EnvAllowMockTransfers},
+}...)
+
+func persistedSettings() []adapterSetting {
+	settings := make([]adapterSetting, 0, len(config.Settings))
+	for _, s := range config.Settings {
+		settings = append(settings, adapterSetting{flag: s.Key, env: s.Env, gitConfig: s.GitConfig, lfsConfig: s.LFSConfig})
+	}
+	return settings
+}
+
+// gitConfigKey returns the git config key of a setting, e.g. lfs.proton.backend.
+func gitConfigKey(flagName string) string {
+	return config.GitConfigSection + "." + flagName
+}
+
+// settingSources maps each setting's flag name to where its effective value
+// came from.
+type settingSources map[string]string
+
+// resolveSettings fills the settings that were set neither by a flag nor by
+// the environment from git config, then from .lfsconfig, as seen from the
+// repository at dir, then from preferences. It records the source of every
+// setting defined in fs.
+func resolveSettings(fs *flag.FlagSet, dir string) (settingSources, error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var layers []configLayer
+	loaded := false
+	sources := make(settingSources)
+	for _, s := range adapterSettings {
+		if fs.Lookup(s.flag) == nil {
+			continue
+		}
+		switch {
+		case explicit[s.flag]:
+			sources[s.flag] = sourceFlag
+			continue
+		case s.env != "" && envTrim(s.env) != "":
+			sources[s.flag] = sourceEnv
+			continue
+		}
+		sources[s.flag] = sourceDefault
+		if !loaded {
+			layers = append(gitConfigLayers(dir), prefsLayer())
+			loaded = true
+		}
+		for _, layer := range layers {
+			switch {
+			case layer.source == sourceGitConfig && !s.gitConfig,
+				layer.source == sourceLFSConfig && !s.lfsConfig:
+				continue
+			}
+			value, ok := layer.values[s.flag]
+			if !ok {
+				continue
+			}
+			if err := fs.Set(s.flag, value); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", layer.source, layer.key(s.flag), err)
+			}
+			sources[s.flag] = layer.source
+			break
+		}
+	}
+	return sources, nil
+}
+
+// configLayer holds the setting values of one config source, by flag name.
+type configLayer struct {
+	source string
+	values map[string]string
+}
+
+// key names a setting the way the layer's source does.
+func (l configLayer) key(flagName string) string {
+	if l.source == sourcePrefs {
+		return config.PrefsFilePath()
+	}
+	return gitConfigKey(flagName)
+}
+
+// prefsLayer returns the settings that the preferences file sets, as
+// written by 'proton-lfs-cli config set'. A missing or corrupt file sets
+// none.
+func prefsLayer() configLayer {
+	layer := configLayer{source: sourcePrefs, values: make(map[string]string)}
+	prefs, err := config.ReadPrefs()
+	if err != nil {
+		return layer
+	}
+	for _, s := range config.Settings {
+		if value := s.Get(prefs); value != "" {
+			layer.values[s.Key] = value
+		}
+	}
+	return layer
+}
+
+// gitConfigLayers reads lfs.proton.* from git config (all scopes) and from
+// the .lfsconfig at the top of the repository at dir. git config comes first,
+// matching git-lfs, where local settings override the committed file.
+func gitConfigLayers(dir string) []configLayer {
+	layers := []configLayer{{source: sourceGitConfig, values: readGitConfigSection(dir, "")}}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err == nil {
+		file := filepath.Join(strings.TrimSpace(string(out)), config.LFSConfigFileName)
+		layers = append(layers, configLayer{source: sourceLFSConfig, values: readGitConfigSection(dir, file)})
+	}
+	return layers
+}
+
+// readGitConfigSection returns the lfs.proton.* values by flag name, from git
+// config or from file when it is set. A key set more than once keeps its last
+// value, as with git config --get.
+func readGitConfigSection(dir, file string) map[string]string {
+	args := []string{"-C", dir, "config"}
+	if file != "" {
+		args = append(args, "--file", file)
+	}
+	args = append(args, "--get-regexp", "^"+regexp.QuoteMeta(config.GitConfigSection)+`\.`)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil
+	}
+	values := make(map[string]string)
+	prefix := config.GitConfigSection + "."
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if name, ok := strings.CutPrefix(strings.ToLower(key), prefix); ok && name != "" {
+			values[name] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// printSettings writes each setting's effective value and its source, for
+// --print-config.
+func printSettings(w io.Writer, fs *flag.FlagSet, sources settingSources) {
+	for _, s := range adapterSettings {
+		f := fs.Lookup(s.flag)
+		if f == nil {
+			continue
+		}
+		source := sources[s.flag]
+		switch source {
+		case sourceEnv:
+			source += " (" + s.env + ")"
+		case sourceGitConfig, sourceLFSConfig:
+			source += " (" + gitConfigKey(s.flag) + ")"
+		case sourcePrefs:
+			source += " (" + config.PrefsFilePath() + ")"
+		}
+		value := f.Value.String()
+		if value == "" {
+			value = `""`
+		}
+		_, _ = fmt.Fprintf(w, "%-22s %-40s %s\n", s.flag, value, source)
+	}
+}
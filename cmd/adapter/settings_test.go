@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// settingsRepo returns a repository whose git config and .lfsconfig set
+// lfs.proton.* keys, isolated from the user's global config.
+func settingsRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("HOME", t.TempDir())
+	for _, s := range adapterSettings {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+	repo := newTestRepo(t)
+	lfsconfig := "[lfs \"proton\"]\n\tbackend = sdk\n\tstorage-base = LFS/committed\n\ttimeout = 90s\n\tdrive-cli-bin = /tmp/evil.js\n\tqueue-timeout = 1h\n"
+	if err := os.WriteFile(filepath.Join(repo, ".lfsconfig"), []byte(lfsconfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "config", "lfs.proton.storage-base", "LFS/local")
+	runGit(t, repo, "config", "lfs.proton.concurrency", "4")
+	return repo
+}
+
+func TestApplyGitConfigPrecedence(t *testing.T) {
+	repo := settingsRepo(t)
+	t.Setenv(EnvConcurrency, "6")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addBackendFlags(fs)
+	if err := fs.Parse([]string{"--credential-provider", "git-credential"}); err != nil {
+		t.Fatal(err)
+	}
+	// From a subdirectory, .lfsconfig is still read from the top level.
+	subdir := filepath.Join(repo, "sub")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := resolveSettings(fs, subdir)
+	if err != nil {
+		t.Fatalf("resolveSettings failed: %v", err)
+	}
+
+	checks := []struct {
+		name, got, want, source string
+	}{
+		{"credential-provider", opts.provider(), CredentialProviderGitCredential, sourceFlag},
+		{"concurrency", fs.Lookup("concurrency").Value.String(), "6", sourceEnv},
+		{"storage-base", *opts.storageBase, "LFS/local", sourceGitConfig},
+		{"timeout", opts.timeout.String(), (90 * time.Second).String(), sourceLFSConfig},
+		// .lfsconfig may only tune; it cannot choose the backend, where
+		// objects go, or an executable.
+		{"backend", opts.kind(), BackendLocal, sourceDefault},
+		{"drive-cli-bin", *opts.driveCLIBin, DefaultDriveCLIBin, sourceDefault},
+		// Nor the limits that every repository on the machine shares.
+		{"queue-timeout", fs.Lookup("queue-timeout").Value.String(), DefaultBridgeQueueTimeout.String(), sourceDefault},
+	}
+	for _, c := range checks {
+		if c.got != c.want || sources[c.name] != c.source {
+			t.Errorf("%s = %q from %q, want %q from %q", c.name, c.got, sources[c.name], c.want, c.source)
+		}
+	}
+}
+
+func TestApplyGitConfigRejectsInvalidValue(t *testing.T) {
+	repo := settingsRepo(t)
+	runGit(t, repo, "config", "lfs.proton.timeout", "soon")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	addBackendFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := resolveSettings(fs, repo)
+	if err == nil || !strings.Contains(err.Error(), "lfs.proton.timeout") {
+		t.Fatalf("expected an error naming the key, got %v", err)
+	}
+}
+
+func TestPrintSettings(t *testing.T) {
+	repo := settingsRepo(t)
+	t.Setenv(EnvBridgeMode, BridgeModeDaemon)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	addBackendFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := resolveSettings(fs, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printSettings(&buf, fs, sources)
+	out := buf.String()
+
+	for _, want := range []string{
+		"storage-base", "LFS/local", "git config (lfs.proton.storage-base)",
+		".lfsconfig (lfs.proton.timeout)",
+		"env (" + EnvBridgeMode + ")",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "retry-attempts") {
+		t.Errorf("settings not defined in the flag set must be skipped:\n%s", out)
+	}
+}
+
+func TestResolveSettingsFallsBackToPrefs(t *testing.T) {
+	repo := settingsRepo(t)
+	prefs := config.DefaultPreferences()
+	prefs.CredentialProvider = CredentialProviderGitCredential
+	prefs.StorageBase = "LFS/prefs"
+	prefs.CacheMaxMB = 512
+	if err := config.SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addBackendFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := resolveSettings(fs, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.provider() != CredentialProviderGitCredential || sources["credential-provider"] != sourcePrefs {
+		t.Fatalf("provider = %q from %q, want git-credential from prefs", opts.provider(), sources["credential-provider"])
+	}
+	// git config outranks preferences.
+	if *opts.storageBase != "LFS/local" || sources["storage-base"] != sourceGitConfig {
+		t.Fatalf("storage-base = %q from %q, want LFS/local from git config", *opts.storageBase, sources["storage-base"])
+	}
+	if sources["profile"] != sourceDefault {
+		t.Fatalf("an unset preference must not count as a source, got %q", sources["profile"])
+	}
+	// Preferences also fill settings that git config cannot set.
+	if *opts.cacheMaxMB != 512 || sources["cache-max-mb"] != sourcePrefs {
+		t.Fatalf("cache-max-mb = %d from %q, want 512 from prefs", *opts.cacheMaxMB, sources["cache-max-mb"])
+	}
+}
+
+func TestAdapterSettingsFollowConfigSettings(t *testing.T) {
+	byFlag := make(map[string]adapterSetting)
+	for _, s := range adapterSettings {
+		if _, dup := byFlag[s.flag]; dup {
+			t.Errorf("setting %q listed twice", s.flag)
+		}
+		byFlag[s.flag] = s
+	}
+	for _, cs := range config.Settings {
+		s, ok := byFlag[cs.Key]
+		if !ok || s.env != cs.Env || s.gitConfig != cs.GitConfig || s.lfsConfig != cs.LFSConfig {
+			t.Errorf("adapter setting %+v does not match config.Settings %q", s, cs.Key)
+		}
+	}
+}
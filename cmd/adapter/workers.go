@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxTransferWorkers caps the in-process worker pool regardless of the
+// concurrenttransfers value git-lfs announces.
+const maxTransferWorkers = 32
+
+// concurrencySetter is implemented by backends whose own concurrency limits
+// must be at least as large as the adapter's worker pool.
+type concurrencySetter interface {
+	SetConcurrency(n int)
+}
+
+// transferWorkerCount returns how many transfers to run in parallel for an
+// init message. When git-lfs already runs several adapter processes
+// (concurrent=true) each process handles one transfer at a time; otherwise a
+// single process runs up to concurrenttransfers workers.
+func transferWorkerCount(msg *InboundMessage) int {
+	if msg.Concurrent || msg.ConcurrentTransfers <= 1 {
+		return 1
+	}
+	if msg.ConcurrentTransfers > maxTransferWorkers {
+		return maxTransferWorkers
+	}
+	return msg.ConcurrentTransfers
+}
+
+// syncWriter serializes writes from several encoders. json.Encoder emits each
+// message with a single Write, so guarding Write keeps lines intact.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// transferPool runs upload/download messages on a fixed set of workers, each
+// with its own encoder over the shared syncWriter.
+type transferPool struct {
+	jobs chan InboundMessage
+	wg   sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func (a *Adapter) newTransferPool(size int, out io.Writer) *transferPool {
+	p := &transferPool{jobs: make(chan InboundMessage)}
+	for i := 0; i < size; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			enc := json.NewEncoder(out)
+			for msg := range p.jobs {
+				if err := a.handleMessage(&msg, enc); err != nil {
+					a.logger.Error("Error handling message", "event", msg.Event, "error", err)
+					p.setErr(err)
+				}
+			}
+		}()
+	}
+	return p
+}
+
+func (p *transferPool) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// firstErr returns the first fatal (encoder) error seen by any worker.
+func (p *transferPool) firstErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// submit blocks until a worker accepts msg, which provides backpressure on
+// the stdin reader.
+func (p *transferPool) submit(msg InboundMessage) {
+	p.jobs <- msg
+}
+
+// stop waits for in-flight transfers to finish and returns the first error.
+func (p *transferPool) stop() error {
+	close(p.jobs)
+	p.wg.Wait()
+	return p.firstErr()
+}
+
+// drainWorkers stops the active pool, if any, so that init and terminate are
+// handled only after every earlier transfer has completed.
+func (a *Adapter) drainWorkers() error {
+	if a.pool == nil {
+		return nil
+	}
+	err := a.pool.stop()
+	a.pool = nil
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferWorkerCount(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  InboundMessage
+		want int
+	}{
+		{"unset", InboundMessage{}, 1},
+		{"single", InboundMessage{ConcurrentTransfers: 1}, 1},
+		{"pool", InboundMessage{ConcurrentTransfers: 8}, 8},
+		{"multi-process mode", InboundMessage{Concurrent: true, ConcurrentTransfers: 8}, 1},
+		{"capped", InboundMessage{ConcurrentTransfers: 1000}, maxTransferWorkers},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := transferWorkerCount(&tc.msg); got != tc.want {
+				t.Fatalf("transferWorkerCount = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// gateBackend wraps a backend and tracks how many downloads run at once. Each
+// download waits (up to a timeout) until `gate` downloads are in flight.
+type gateBackend struct {
+	TransferBackend
+	gate    int
+	release chan struct{}
+	once    sync.Once
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	concurrency int
+}
+
+func newGateBackend(inner TransferBackend, gate int) *gateBackend {
+	return &gateBackend{TransferBackend: inner, gate: gate, release: make(chan struct{})}
+}
+
+func (b *gateBackend) SetConcurrency(n int) { b.concurrency = n }
+
+func (b *gateBackend) Download(session *Session, oid string, progress ProgressFunc) (string, int64, error) {
+	b.mu.Lock()
+	b.inFlight++
+	if b.inFlight > b.maxInFlight {
+		b.maxInFlight = b.inFlight
+	}
+	if b.inFlight >= b.gate {
+		b.once.Do(func() { close(b.release) })
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.release:
+	case <-time.After(2 * time.Second):
+	}
+	path, size, err := b.TransferBackend.Download(session, oid, progress)
+
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	return path, size, err
+}
+
+func runGatedDownloads(t *testing.T, initLine string, gate int) (*gateBackend, []OutboundMessage, []string) {
+	t.Helper()
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+
+	var oids []string
+	lines := []string{initLine}
+	for i := 0; i < 4; i++ {
+		oid := seedLocalObject(t, adapter, []byte(fmt.Sprintf("parallel-object-%d", i)))
+		oids = append(oids, oid)
+		lines = append(lines, fmt.Sprintf(`{"event":"download","oid":"%s","size":%d,"action":null}`, oid, len(fmt.Sprintf("parallel-object-%d", i))))
+	}
+	lines = append(lines, `{"event":"terminate"}`)
+
+	gated := newGateBackend(adapter.backend, gate)
+	adapter.backend = gated
+
+	out := new(bytes.Buffer)
+	if err := adapter.Run(strings.NewReader(strings.Join(lines, "\n")+"\n"), out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	return gated, decodeAllMessages(t, out.Bytes()), oids
+}
+
+func TestRunConcurrentTransfersUsesWorkerPool(t *testing.T) {
+	gated, msgs, oids := runGatedDownloads(t,
+		`{"event":"init","operation":"download","remote":"origin","concurrent":false,"concurrenttransfers":4}`, 4)
+
+	if gated.concurrency != 4 {
+		t.Fatalf("expected backend concurrency sized to 4, got %d", gated.concurrency)
+	}
+	if gated.maxInFlight != 4 {
+		t.Fatalf("expected 4 downloads in flight at once, got %d", gated.maxInFlight)
+	}
+
+	// init ack + (progress + complete) per object, each line a valid message
+	if len(msgs) != 1+2*len(oids) {
+		t.Fatalf("expected %d messages, got %d: %+v", 1+2*len(oids), len(msgs), msgs)
+	}
+	completed := map[string]bool{}
+	progressed := map[string]bool{}
+	for _, m := range msgs[1:] {
+		switch m.Event {
+		case EventProgress:
+			progressed[m.OID] = true
+		case EventComplete:
+			if m.Error != nil || m.Path == "" {
+				t.Fatalf("unexpected completion: %+v", m)
+			}
+			if !progressed[m.OID] {
+				t.Fatalf("complete before progress for %s", m.OID)
+			}
+			completed[m.OID] = true
+		}
+	}
+	for _, oid := range oids {
+		if !completed[oid] {
+			t.Fatalf("missing completion for %s", oid)
+		}
+	}
+}
+
+func TestRunMultiProcessModeStaysSerial(t *testing.T) {
+	gated, msgs, oids := runGatedDownloads(t,
+		`{"event":"init","operation":"download","remote":"origin","concurrent":true,"concurrenttransfers":4}`, 1)
+
+	if gated.maxInFlight != 1 {
+		t.Fatalf("expected serial transfers with concurrent=true, got %d in flight", gated.maxInFlight)
+	}
+	if len(msgs) != 1+2*len(oids) {
+		t.Fatalf("expected %d messages, got %d", 1+2*len(oids), len(msgs))
+	}
+}
+
+func TestRunWorkerPoolSurfacesWriteFailure(t *testing.T) {
+	adapter := NewAdapter()
+	configureLocalBackend(adapter, t.TempDir())
+	oid := seedLocalObject(t, adapter, []byte("write-failure"))
+
+	input := strings.Join([]string{
+		`{"event":"init","operation":"download","remote":"origin","concurrent":false,"concurrenttransfers":2}`,
+		fmt.Sprintf(`{"event":"download","oid":"%s","size":13,"action":null}`, oid),
+		`{"event":"terminate"}`,
+	}, "\n") + "\n"
+
+	// Allow the init ack, then fail every later write.
+	w := &failAfterNWriter{failAt: 1}
+	if err := adapter.Run(strings.NewReader(input), w); err == nil {
+		t.Fatal("expected run to return the worker's write error")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -26,13 +27,14 @@ func cliDriveLogin(driveCLI string, args ...string) error {
 	var stderr bytes.Buffer
 	cmd := exec.Command(driveCLI, cmdArgs...)
 	cmd.Stderr = &stderr
+	cmd.Env = append(cmd.Environ(), profileEnv(activeProfile())...)
 	// For pass-cli provider, set PROTON_PASS_CLI_BIN so proton-drive-cli can
 	// find pass-cli even when running from a macOS .app bundle with minimal PATH
 	// Extract provider from args (looks for "--credential-provider" or "--provider")
 	provider := extractProviderFromArgs(args)
 	if provider == "pass-cli" {
 		if passCLI := discoverPassCLIBinary(); passCLI != "" {
-			cmd.Env = append(cmd.Environ(), "PROTON_PASS_CLI_BIN="+passCLI)
+			cmd.Env = append(cmd.Env, "PROTON_PASS_CLI_BIN="+passCLI)
 		}
 	}
 	if err := cmd.Run(); err != nil {
@@ -55,41 +57,28 @@ func extractProviderFromArgs(args []string) string {
 	return ""
 }
 
-// cliStatus prints session, LFS, provider, and transfer status.
-func cliStatus(w io.Writer) int {
-	if isSessionActive() {
-		_, _ = fmt.Fprintln(w, "Session:  logged in")
-	} else {
-		_, _ = fmt.Fprintln(w, "Session:  not connected")
-	}
-
-	if isLFSEnabled() {
-		_, _ = fmt.Fprintln(w, "LFS:      enabled")
-	} else {
-		_, _ = fmt.Fprintln(w, "LFS:      not registered")
-	}
+// cliPause stops the adapter from starting transfers until cliResume.
+func cliPause(w io.Writer) int {
+	return setTransfersEnabled(w, false)
+}
 
-	prefs := config.LoadPrefs()
-	_, _ = fmt.Fprintf(w, "Provider: %s\n", prefs.CredentialProvider)
+// cliResume lets the adapter start transfers again.
+func cliResume(w io.Writer) int {
+	return setTransfersEnabled(w, true)
+}
 
-	report, err := config.ReadStatus()
-	if err != nil {
-		_, _ = fmt.Fprintln(w, "Transfer: no data")
+func setTransfersEnabled(w io.Writer, enabled bool) int {
+	if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		p.Enabled = enabled
+		return nil
+	}); err != nil {
+		_, _ = fmt.Fprintf(w, "error saving config: %v\n", err)
+		return 1
+	}
+	if enabled {
+		_, _ = fmt.Fprintln(w, "Transfers resumed")
 	} else {
-		switch report.State {
-		case config.StateTransferring:
-			_, _ = fmt.Fprintf(w, "Transfer: %s in progress\n", report.LastOp)
-		case config.StateError:
-			msg := "failed"
-			if report.Error != "" {
-				msg = report.Error
-			}
-			_, _ = fmt.Fprintf(w, "Transfer: %s %s (%s)\n", report.LastOp, relativeTime(report.Timestamp), msg)
-		case config.StateOK:
-			_, _ = fmt.Fprintf(w, "Transfer: %s %s (ok)\n", report.LastOp, relativeTime(report.Timestamp))
-		default:
-			_, _ = fmt.Fprintln(w, "Transfer: idle")
-		}
+		_, _ = fmt.Fprintln(w, "Transfers paused")
 	}
 	return 0
 }
@@ -104,6 +93,7 @@ func cliLogout(w io.Writer) int {
 
 	var stderr bytes.Buffer
 	cmd := exec.Command(driveCLI, "logout")
+	cmd.Env = append(cmd.Environ(), profileEnv(activeProfile())...)
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
 		if stderr.Len() > 0 {
@@ -117,85 +107,6 @@ func cliLogout(w io.Writer) int {
 	return 0
 }
 
-// cliConfig shows or sets the credential provider.
-func cliConfig(w io.Writer, args []string) int {
-	if len(args) == 0 {
-		prefs := config.LoadPrefs()
-		_, _ = fmt.Fprintln(w, prefs.CredentialProvider)
-		return 0
-	}
-
-	provider := args[0]
-	switch provider {
-	case "--help", "-h":
-		_, _ = fmt.Fprintln(w, "Usage: proton-git-lfs config [provider]")
-		_, _ = fmt.Fprintf(w, "\nShow or set the credential provider.\n")
-		_, _ = fmt.Fprintf(w, "With no argument, prints the current provider.\n\n")
-		_, _ = fmt.Fprintf(w, "Providers: %s, %s\n",
-			config.CredentialProviderGitCredential, config.CredentialProviderPassCLI)
-		return 0
-	case config.CredentialProviderGitCredential, config.CredentialProviderPassCLI:
-		// valid
-	default:
-		_, _ = fmt.Fprintf(w, "unknown provider: %s\n", provider)
-		_, _ = fmt.Fprintf(w, "valid providers: %s, %s\n",
-			config.CredentialProviderGitCredential, config.CredentialProviderPassCLI)
-		return 1
-	}
-
-	prefs := config.LoadPrefs()
-	prefs.CredentialProvider = provider
-	if err := config.SavePrefs(prefs); err != nil {
-		_, _ = fmt.Fprintf(w, "error saving config: %v\n", err)
-		return 1
-	}
-	_, _ = fmt.Fprintf(w, "Credential provider set to %s\n", provider)
-	return 0
-}
-
-// cliRegister enables the Proton LFS backend in git global config.
-func cliRegister(w io.Writer) int {
-	adapterPath := findAdapter()
-	if adapterPath == "" {
-		_, _ = fmt.Fprintln(w, "error: adapter binary not found")
-		return 1
-	}
-
-	if err := exec.Command("git", "config", "--global",
-		"lfs.customtransfer.proton.path", adapterPath).Run(); err != nil {
-		_, _ = fmt.Fprintf(w, "error: git config failed: %v\n", err)
-		return 1
-	}
-
-	prefs := config.LoadPrefs()
-	driveCLIPath := findDriveCLI()
-	args := "--backend sdk"
-	if prefs.CredentialProvider == config.CredentialProviderGitCredential {
-		args += " --credential-provider git-credential"
-	}
-	if driveCLIPath != "" {
-		args += " --drive-cli-bin " + driveCLIPath
-	}
-	if err := exec.Command("git", "config", "--global",
-		"lfs.customtransfer.proton.args", args).Run(); err != nil {
-		_, _ = fmt.Fprintf(w, "error: git config failed: %v\n", err)
-		return 1
-	}
-	if err := exec.Command("git", "config", "--global",
-		"lfs.standalonetransferagent", "proton").Run(); err != nil {
-		_, _ = fmt.Fprintf(w, "error: git config failed: %v\n", err)
-		return 1
-	}
-
-	_, _ = fmt.Fprintln(w, "LFS backend enabled")
-	_, _ = fmt.Fprintf(w, "  adapter: %s\n", adapterPath)
-	if driveCLIPath != "" {
-		_, _ = fmt.Fprintf(w, "  drive-cli: %s\n", driveCLIPath)
-	}
-	_, _ = fmt.Fprintf(w, "  provider: %s\n", prefs.CredentialProvider)
-	return 0
-}
-
 // cliLogin handles the unified login flow for any credential provider.
 // 1. Verify credentials exist via proton-drive-cli credential verify --provider
 // 2. If missing, start interactive credential store
@@ -207,8 +118,7 @@ func cliLogin(w io.Writer) int {
 		return 1
 	}
 
-	prefs := config.LoadPrefs()
-	provider := prefs.CredentialProvider
+	provider := activeProfile().CredentialProvider
 
 	if !verifyCredential(provider) {
 		_, _ = fmt.Fprintln(w, "No credentials stored. Starting credential setup...")
@@ -240,3 +150,41 @@ func cliLogin(w io.Writer) int {
 	_, _ = fmt.Fprintln(w, "Connected to Proton")
 	return 0
 }
+
+// cliPrune deletes stored objects that no LFS pointer references anymore.
+func cliPrune(w io.Writer, args []string) int {
+	return runAdapterCommand(w, "prune", args)
+}
+
+// cliFsck checks that referenced objects exist in the store and are intact.
+func cliFsck(w io.Writer, args []string) int {
+	return runAdapterCommand(w, "fsck", args)
+}
+
+// runAdapterCommand runs an adapter maintenance subcommand with the backend
+// args that register wrote to git config, so it targets the same storage as
+// transfers. The adapter's exit code is returned unchanged.
+func runAdapterCommand(w io.Writer, command string, args []string) int {
+	adapterPath := findAdapter()
+	if adapterPath == "" {
+		_, _ = fmt.Fprintln(w, "error: adapter binary not found")
+		return 1
+	}
+
+	cmdArgs := append([]string{command}, registeredAdapterArgs()...)
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.Command(adapterPath, cmdArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		_, _ = fmt.Fprintf(w, "error: %s failed: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
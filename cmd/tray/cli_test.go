@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -101,7 +103,7 @@ func TestCliStatusConnectedOK(t *testing.T) {
 	setupGitConfig(t, "[lfs]\n\tstandalonetransferagent = proton\n")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -125,7 +127,7 @@ func TestCliStatusDisconnected(t *testing.T) {
 	setupGitConfig(t, "")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -155,7 +157,7 @@ func TestCliStatusTransferring(t *testing.T) {
 	setupGitConfig(t, "")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -166,6 +168,26 @@ func TestCliStatusTransferring(t *testing.T) {
 	}
 }
 
+func TestCliStatusAggregatesAdapterProcesses(t *testing.T) {
+	saveFuncVars(t)
+	statusJSON, _ := json.Marshal(config.StatusReport{State: config.StateOK, LastOp: "upload", Timestamp: time.Now()})
+	setupFakeHome(t, fakeHomeOpts{statusJSON: string(statusJSON)})
+	setupGitConfig(t, "")
+	if err := config.WriteProcessStatus(config.ProcessStatus{PID: os.Getpid(), Transfers: []config.TransferProgress{
+		{OID: "a", Op: "upload", BytesDone: 10, Size: 100},
+		{OID: "b", Op: "upload", BytesDone: 50, Size: 100},
+		{OID: "c", Op: "upload", BytesDone: 75, Size: 100},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	cliStatus(&buf, nil)
+	if out := buf.String(); !strings.Contains(out, "Transfer: 3 uploads in progress, 45%") {
+		t.Errorf("output missing the aggregated transfers:\n%s", out)
+	}
+}
+
 func TestCliStatusError(t *testing.T) {
 	saveFuncVars(t)
 	ts := time.Now().Add(-5 * time.Minute).UTC()
@@ -176,7 +198,7 @@ func TestCliStatusError(t *testing.T) {
 	setupGitConfig(t, "")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -194,7 +216,7 @@ func TestCliStatusIdle(t *testing.T) {
 	setupGitConfig(t, "")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -205,6 +227,163 @@ func TestCliStatusIdle(t *testing.T) {
 	}
 }
 
+func TestCliStatusJSON(t *testing.T) {
+	saveFuncVars(t)
+	ts := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	statusJSON, _ := json.Marshal(config.StatusReport{
+		State: config.StateAuthRequired, LastOp: "download", ErrorCode: "auth_failed",
+		ErrorDetail: "run proton-lfs-cli login", Timestamp: ts,
+	})
+	home := setupFakeHome(t, fakeHomeOpts{
+		configJSON: `{"credentialProvider":"git-credential","enabled":true}`,
+		statusJSON: string(statusJSON),
+	})
+	setupGitConfig(t, "[lfs]\n\tstandalonetransferagent = proton\n")
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	sessionDir := filepath.Join(home, ".proton-drive-cli")
+	if err := os.MkdirAll(sessionDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	session := fmt.Sprintf(`{"accessToken":"secret","expiresAt":%q}`, expires.Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(sessionDir, "session.json"), []byte(session), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if code := cliStatus(&buf, []string{"--json"}); code != 0 {
+		t.Fatalf("expected exit 0, got %d:\n%s", code, buf.String())
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("status --json leaked a session token:\n%s", buf.String())
+	}
+	var snap statusSnapshot
+	if err := json.Unmarshal(buf.Bytes(), &snap); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if !snap.Session.Active || snap.Session.UpdatedAt == nil || snap.Session.ExpiresAt == nil || !snap.Session.ExpiresAt.Equal(expires) {
+		t.Errorf("session = %+v", snap.Session)
+	}
+	if len(snap.Registrations) != 1 || snap.Registrations[0] != (lfsRegistration{Scope: "global"}) {
+		t.Errorf("registrations = %+v", snap.Registrations)
+	}
+	if snap.Provider != "git-credential" || snap.Profile != config.DefaultProfileName || snap.Paused {
+		t.Errorf("snapshot = %+v", snap)
+	}
+	if tr := snap.Transfer; tr == nil || tr.State != config.StateAuthRequired || tr.ErrorCode != "auth_failed" || !tr.Timestamp.Equal(ts) {
+		t.Errorf("transfer = %+v", snap.Transfer)
+	}
+}
+
+func TestCliStatusJSONWithoutData(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, "")
+
+	var buf bytes.Buffer
+	cliStatus(&buf, []string{"--json"})
+	for _, want := range []string{`"active": false`, `"registrations": []`, `"transfer": null`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestReadSessionExpiry(t *testing.T) {
+	want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "session.json")
+	for content, expected := range map[string]*time.Time{
+		`{"expiresAt":"2026-03-01T12:00:00Z"}`:            &want,
+		fmt.Sprintf(`{"expiresAt":%d}`, want.Unix()):      &want,
+		fmt.Sprintf(`{"expiresAt":%d}`, want.UnixMilli()): &want,
+		`{"accessToken":"x"}`:                             nil,
+		`not json`:                                        nil,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got := readSessionExpiry(path)
+		if (got == nil) != (expected == nil) || (got != nil && !got.Equal(*expected)) {
+			t.Errorf("readSessionExpiry(%s) = %v, want %v", content, got, expected)
+		}
+	}
+}
+
+func TestWatchStatusPrintsChanges(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{statusJSON: `{"state":"idle"}`})
+	setupGitConfig(t, "")
+
+	r, w := io.Pipe()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watchStatus(w, true, 10*time.Millisecond, stop)
+		_ = w.Close()
+		close(done)
+	}()
+
+	lines := bufio.NewScanner(r)
+	next := func() statusSnapshot {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("watch ended early: %v", lines.Err())
+		}
+		var snap statusSnapshot
+		if err := json.Unmarshal(lines.Bytes(), &snap); err != nil {
+			t.Fatalf("invalid JSON line: %v\n%s", err, lines.Text())
+		}
+		return snap
+	}
+	if snap := next(); snap.Transfer == nil || snap.Transfer.State != config.StateIdle {
+		t.Fatalf("first line = %+v", snap.Transfer)
+	}
+	if err := config.WriteStatus(config.StatusReport{State: config.StateError, LastOp: "upload", ErrorCode: "timeout"}); err != nil {
+		t.Fatal(err)
+	}
+	if snap := next(); snap.Transfer == nil || snap.Transfer.ErrorCode != "timeout" {
+		t.Fatalf("second line = %+v", snap.Transfer)
+	}
+
+	close(stop)
+	go func() { _, _ = io.Copy(io.Discard, r) }()
+	<-done
+}
+
+func TestCliPauseAndResume(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{
+		configJSON: `{"credentialProvider":"pass-cli","enabled":true}`,
+		statusJSON: `{"state":"ok","lastOp":"upload","timestamp":"2026-01-01T00:00:00Z"}`,
+	})
+	setupGitConfig(t, "")
+
+	var buf bytes.Buffer
+	if code := cliPause(&buf); code != 0 || !strings.Contains(buf.String(), "Transfers paused") {
+		t.Fatalf("pause exit %d: %s", code, buf.String())
+	}
+	if config.LoadPrefs().Enabled {
+		t.Fatal("expected pause to clear Enabled")
+	}
+	buf.Reset()
+	cliStatus(&buf, nil)
+	if !strings.Contains(buf.String(), "Transfer: paused") {
+		t.Errorf("status should show the paused state:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if code := cliResume(&buf); code != 0 || !strings.Contains(buf.String(), "Transfers resumed") {
+		t.Fatalf("resume exit %d: %s", code, buf.String())
+	}
+	if !config.LoadPrefs().Enabled {
+		t.Fatal("expected resume to set Enabled")
+	}
+	buf.Reset()
+	cliStatus(&buf, nil)
+	if strings.Contains(buf.String(), "paused") {
+		t.Errorf("status should no longer show paused:\n%s", buf.String())
+	}
+}
+
 // --- cliConfig tests ---
 
 func TestCliConfigShowDefault(t *testing.T) {
@@ -315,7 +494,7 @@ func TestCliRegisterSuccess(t *testing.T) {
 	findDriveCLI = func() string { return "/tmp/test-drive-cli" }
 
 	var buf bytes.Buffer
-	code := cliRegister(&buf)
+	code := cliRegister(&buf, nil)
 	out := buf.String()
 
 	if code != 0 {
@@ -352,7 +531,9 @@ func TestCliRegisterSuccess(t *testing.T) {
 	}
 }
 
-func TestCliRegisterGitCredentialProvider(t *testing.T) {
+// The adapter reads the provider from preferences, so register must not pin
+// it in the args: switching provider later would otherwise have no effect.
+func TestCliRegisterLeavesProviderToPrefs(t *testing.T) {
 	saveFuncVars(t)
 	setupFakeHome(t, fakeHomeOpts{configJSON: `{"credentialProvider":"git-credential"}`})
 	gitCfg := setupGitConfig(t, "")
@@ -361,7 +542,7 @@ func TestCliRegisterGitCredentialProvider(t *testing.T) {
 	findDriveCLI = func() string { return "/tmp/test-drive-cli" }
 
 	var buf bytes.Buffer
-	code := cliRegister(&buf)
+	code := cliRegister(&buf, nil)
 
 	if code != 0 {
 		t.Fatalf("expected exit 0, got %d; output:\n%s", code, buf.String())
@@ -372,8 +553,8 @@ func TestCliRegisterGitCredentialProvider(t *testing.T) {
 		t.Fatal(err)
 	}
 	cfgStr := string(data)
-	if !strings.Contains(cfgStr, "--credential-provider git-credential") {
-		t.Errorf("git config missing --credential-provider git-credential:\n%s", cfgStr)
+	if strings.Contains(cfgStr, "--credential-provider") {
+		t.Errorf("git config should not pin the credential provider:\n%s", cfgStr)
 	}
 }
 
@@ -385,7 +566,7 @@ func TestCliRegisterNoAdapter(t *testing.T) {
 	findAdapter = func() string { return "" }
 
 	var buf bytes.Buffer
-	code := cliRegister(&buf)
+	code := cliRegister(&buf, nil)
 
 	if code != 1 {
 		t.Fatalf("expected exit 1, got %d", code)
@@ -395,6 +576,64 @@ func TestCliRegisterNoAdapter(t *testing.T) {
 	}
 }
 
+// --- adapter maintenance command tests ---
+
+func TestCliPrunePassesRegisteredArgs(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, "[lfs \"customtransfer.proton\"]\n\targs = --backend sdk --drive-cli-bin /tmp/drive\n")
+
+	script := filepath.Join(t.TempDir(), "adapter")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	findAdapter = func() string { return script }
+
+	var buf bytes.Buffer
+	code := cliPrune(&buf, []string{"--dry-run", "/repo"})
+	if code != 3 {
+		t.Fatalf("expected the adapter's exit code 3, got %d", code)
+	}
+	want := "prune --backend sdk --drive-cli-bin /tmp/drive --dry-run /repo"
+	if strings.TrimSpace(buf.String()) != want {
+		t.Fatalf("adapter args = %q, want %q", strings.TrimSpace(buf.String()), want)
+	}
+}
+
+func TestCliFsckPassesArgs(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, "[lfs \"customtransfer.proton\"]\n\targs = --backend local --local-store-dir /tmp/store\n")
+
+	script := filepath.Join(t.TempDir(), "adapter")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	findAdapter = func() string { return script }
+
+	var buf bytes.Buffer
+	if code := cliFsck(&buf, []string{"--json"}); code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	want := "fsck --backend local --local-store-dir /tmp/store --json"
+	if strings.TrimSpace(buf.String()) != want {
+		t.Fatalf("adapter args = %q, want %q", strings.TrimSpace(buf.String()), want)
+	}
+}
+
+func TestCliPruneNoAdapter(t *testing.T) {
+	saveFuncVars(t)
+	findAdapter = func() string { return "" }
+
+	var buf bytes.Buffer
+	if code := cliPrune(&buf, nil); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(buf.String(), "error: adapter binary not found") {
+		t.Fatalf("expected adapter not found error, got: %s", buf.String())
+	}
+}
+
 // --- cliLogin unified tests (both providers use the same flow) ---
 
 func TestCliLoginCredentialsAlreadyStored(t *testing.T) {
@@ -572,7 +811,7 @@ func TestCliLoginCreatesSession(t *testing.T) {
 
 	// Verify cliStatus reflects the new session
 	var statusBuf bytes.Buffer
-	cliStatus(&statusBuf)
+	cliStatus(&statusBuf, nil)
 	if !strings.Contains(statusBuf.String(), "Session:  logged in") {
 		t.Errorf("status should show logged in after connect:\n%s", statusBuf.String())
 	}
@@ -585,7 +824,7 @@ func TestCliStatusReflectsExternalLogin(t *testing.T) {
 	setupGitConfig(t, "")
 
 	var buf bytes.Buffer
-	code := cliStatus(&buf)
+	code := cliStatus(&buf, nil)
 	if code != 0 {
 		t.Fatalf("expected exit 0, got %d", code)
 	}
@@ -648,7 +887,7 @@ func TestCliLogoutClearsSession(t *testing.T) {
 
 func TestUsageContainsSubcommands(t *testing.T) {
 	for _, word := range []string{
-		"login", "logout", "register", "status", "config",
+		"login", "logout", "register", "status", "pause", "resume", "history", "stats", "metrics", "config", "profile", "prune", "fsck", "doctor",
 		"git-credential", "pass-cli",
 	} {
 		if !strings.Contains(usage, word) {
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"os/exec"
-
-	"proton-lfs-cli/internal/config"
 )
 
 // connectToProton runs the unified tray Connect flow for any credential provider:
@@ -20,9 +18,9 @@ func connectToProton() {
 	}
 	trayLog.Printf("connect: using drive-cli at %s", driveCLI)
 
-	prefs := config.LoadPrefs()
-	provider := prefs.CredentialProvider
-	trayLog.Printf("connect: credential provider = %s", provider)
+	profile := activeProfile()
+	provider := profile.CredentialProvider
+	trayLog.Printf("connect: profile = %s, credential provider = %s", profile.Name, provider)
 
 	if !credentialVerify(provider) {
 		trayLog.Print("connect: credentials not found, opening terminal for interactive store")
@@ -58,11 +56,12 @@ func protonDriveLogin(driveCLI string, provider string, args ...string) error {
 	cmdArgs = append(cmdArgs, "-q")
 	trayLog.Printf("connect: exec %s %v", driveCLI, cmdArgs)
 	cmd := exec.Command(driveCLI, cmdArgs...)
+	cmd.Env = append(cmd.Environ(), profileEnv(activeProfile())...)
 	// For pass-cli provider, set PROTON_PASS_CLI_BIN so proton-drive-cli can
 	// find pass-cli even when running from a macOS .app bundle with minimal PATH
 	if provider == "pass-cli" {
 		if passCLI := discoverPassCLIBinary(); passCLI != "" {
-			cmd.Env = append(cmd.Environ(), "PROTON_PASS_CLI_BIN="+passCLI)
+			cmd.Env = append(cmd.Env, "PROTON_PASS_CLI_BIN="+passCLI)
 			trayLog.Printf("connect: set PROTON_PASS_CLI_BIN=%s", passCLI)
 		} else {
 			trayLog.Print("connect: warning: pass-cli not found in PATH")
@@ -21,6 +21,7 @@ func credentialVerify(provider string) bool {
 		"GIT_TERMINAL_PROMPT=0",
 		"GCM_INTERACTIVE=never",
 	}
+	env = append(env, profileEnv(activeProfile())...)
 	// For pass-cli provider, set PROTON_PASS_CLI_BIN so proton-drive-cli can
 	// find pass-cli even when running from a macOS .app bundle with minimal PATH
 	if provider == "pass-cli" {
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const doctorUsage = `Usage: proton-lfs-cli doctor [flags]
+
+Check everything Proton LFS needs and explain how to fix what is wrong:
+Node.js and proton-drive-cli, pass-cli login, git-lfs, the adapter
+registration in git config, the Proton session, and the storage itself,
+by writing, reading back and deleting a scratch object with the registered
+adapter settings.
+
+Exit status is 1 when a check fails.
+
+Flags:
+  --remote <name>   Check the storage route configured for this git remote
+  --json            Print the report as JSON, e.g. to attach to a support ticket
+`
+
+var gitLFSVersionPattern = regexp.MustCompile(`git-lfs/(\d+\.\d+\.\d+)`)
+
+// adapterDoctor runs the adapter's doctor subcommand with the registered
+// args. A failing check exits 1 but still prints a report.
+func adapterDoctor(args []string) (config.DoctorReport, error) {
+	var report config.DoctorReport
+	adapterPath := findAdapter()
+	if adapterPath == "" {
+		adapterPath = gitConfigValue("lfs.customtransfer.proton.path")
+	}
+	if adapterPath == "" {
+		return report, errors.New("adapter binary not found")
+	}
+	cmdArgs := append([]string{"doctor"}, registeredAdapterArgs()...)
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, "--json")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(adapterPath, cmdArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return report, fmt.Errorf("%s doctor: %s", adapterPath, msg)
+		}
+		if runErr != nil {
+			return report, fmt.Errorf("%s doctor: %w", adapterPath, runErr)
+		}
+		return report, fmt.Errorf("%s doctor: %w", adapterPath, err)
+	}
+	return report, nil
+}
+
+// checkPassCLI checks that pass-cli is installed and logged in, when it is
+// the credential provider.
+func checkPassCLI(provider string) config.DoctorCheck {
+	check := config.DoctorCheck{Name: "pass-cli"}
+	if provider != config.CredentialProviderPassCLI {
+		check.Status, check.Detail = config.CheckSkip, "credential provider is "+provider
+		return check
+	}
+	bin := discoverPassCLIBinary()
+	if bin == "" {
+		check.Status, check.Detail = config.CheckFail, "pass-cli not found on PATH"
+		check.Fix = "Install Proton Pass CLI, or switch provider with 'proton-lfs-cli config git-credential'"
+		return check
+	}
+	if _, err := config.Probe(bin, "user", "info", "--output", "json"); err != nil {
+		check.Status, check.Detail, check.Fix = config.CheckFail, fmt.Sprintf("%s: not logged in (%v)", bin, err), "Run 'pass-cli login'"
+		return check
+	}
+	check.Status, check.Detail = config.CheckOK, "logged in ("+bin+")"
+	return check
+}
+
+// checkGitLFS checks that git-lfs is installed and reports its version.
+func checkGitLFS() config.DoctorCheck {
+	check := config.DoctorCheck{Name: "git-lfs"}
+	out, err := config.Probe("git", "lfs", "version")
+	if err != nil {
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("git lfs version: %v", err)
+		check.Fix = "Install Git LFS (https://git-lfs.com), then run 'git lfs install'"
+		return check
+	}
+	check.Status, check.Detail = config.CheckOK, parseGitLFSVersion(out)
+	return check
+}
+
+// parseGitLFSVersion returns the version in `git lfs version` output, or the
+// output itself when it has none.
+func parseGitLFSVersion(out string) string {
+	if m := gitLFSVersionPattern.FindStringSubmatch(out); m != nil {
+		return m[1]
+	}
+	return out
+}
+
+// checkGitConfig checks that git-lfs hands transfers to an adapter binary
+// that exists and can start it: lfs.standalonetransferagent must name
+// proton, lfs.customtransfer.proton.path must be executable, args must split
+// the way git-lfs splits them, and concurrent, when set, must be a boolean.
+func checkGitConfig(agent, path, args, concurrent string) config.DoctorCheck {
+	check := config.DoctorCheck{Name: "git-config", Fix: "Run 'proton-lfs-cli register'"}
+	switch {
+	case agent == "" && path == "":
+		check.Status, check.Detail = config.CheckFail, "the Proton LFS adapter is not registered"
+		return check
+	case agent != agentName:
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("lfs.standalonetransferagent is %q, not \"proton\"", agent)
+		return check
+	case path == "":
+		check.Status, check.Detail = config.CheckFail, "lfs.customtransfer.proton.path is not set"
+		return check
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil || info.IsDir():
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("lfs.customtransfer.proton.path %s does not exist", path)
+		return check
+	case runtime.GOOS != "windows" && info.Mode()&0o111 == 0:
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("lfs.customtransfer.proton.path %s is not executable", path)
+		return check
+	}
+	if _, err := splitShellArgs(args); err != nil {
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("lfs.customtransfer.proton.args %q: %v", args, err)
+		return check
+	}
+	if concurrent != "" && !isGitBool(concurrent) {
+		check.Status, check.Detail = config.CheckFail, fmt.Sprintf("lfs.customtransfer.proton.concurrent is %q, not a boolean", concurrent)
+		check.Fix = "Run 'git config --unset lfs.customtransfer.proton.concurrent'"
+		return check
+	}
+	check.Status, check.Detail, check.Fix = config.CheckOK, "adapter "+path, ""
+	return check
+}
+
+// isGitBool reports whether git config accepts value as a boolean.
+func isGitBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "false", "no", "off":
+		return true
+	}
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
+
+// checkSessionFile checks that a proton-drive-cli session exists and that
+// only its owner can read it.
+func checkSessionFile(path string) config.DoctorCheck {
+	check := config.DoctorCheck{Name: "session"}
+	if path == "" {
+		check.Status, check.Detail, check.Fix = config.CheckFail, "cannot locate the session file", "Set HOME"
+		return check
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		check.Status, check.Detail, check.Fix = config.CheckFail, "no session at "+path, "Run 'proton-lfs-cli login'"
+		return check
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		check.Status = config.CheckWarn
+		check.Detail = fmt.Sprintf("%s is readable by other users (mode %04o)", path, info.Mode().Perm())
+		check.Fix = "Run 'chmod 600 " + path + "'"
+		return check
+	}
+	check.Status, check.Detail = config.CheckOK, path
+	return check
+}
+
+// mergeDoctorChecks puts the local checks before the adapter's storage
+// round-trip, so that the report reads from prerequisites to storage.
+func mergeDoctorChecks(adapter, local []config.DoctorCheck) []config.DoctorCheck {
+	var merged, storage []config.DoctorCheck
+	for _, c := range adapter {
+		if c.Name == "storage" {
+			storage = append(storage, c)
+		} else {
+			merged = append(merged, c)
+		}
+	}
+	merged = append(merged, local...)
+	return append(merged, storage...)
+}
+
+// cliDoctor runs the environment checks and prints the report.
+func cliDoctor(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, doctorUsage) }
+	remote := fs.String("remote", "", "git remote")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	var adapterArgs []string
+	if *remote != "" {
+		adapterArgs = append(adapterArgs, "--remote", *remote)
+	}
+	adapterReport, err := adapterDoctor(adapterArgs)
+	if err != nil {
+		adapterReport.Checks = []config.DoctorCheck{{
+			Name:   "adapter",
+			Status: config.CheckFail,
+			Detail: err.Error(),
+			Fix:    "Reinstall with 'make install', then run 'proton-lfs-cli register'",
+		}}
+	}
+
+	// A registration scoped to endpoint URLs counts as well.
+	agent := gitConfigValue("lfs.standalonetransferagent")
+	if regs, _ := lfsRegistrations(""); agent != agentName && len(regs) > 0 {
+		agent = agentName
+	}
+	profile := activeProfile()
+	local := []config.DoctorCheck{
+		checkPassCLI(profile.CredentialProvider),
+		checkGitLFS(),
+		checkGitConfig(agent, gitConfigValue(keyAdapterPath), gitConfigValue(keyAdapterArgs), gitConfigValue(keyAdapterConcurrent)),
+		checkSessionFile(profile.SessionFilePath()),
+	}
+	if adapterReport.Backend == config.BackendLocal {
+		// The local backend needs neither credentials nor a session.
+		for _, i := range []int{0, 3} {
+			local[i] = config.DoctorCheck{Name: local[i].Name, Status: config.CheckSkip, Detail: "not used by the local backend"}
+		}
+	}
+
+	report := config.NewDoctorReport(mergeDoctorChecks(adapterReport.Checks, local))
+	report.Version = Version
+	report.OS = runtime.GOOS + "/" + runtime.GOARCH
+	report.Backend = adapterReport.Backend
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		_, _ = fmt.Fprintf(w, "proton-lfs-cli %s on %s", report.Version, report.OS)
+		if report.Backend != "" {
+			_, _ = fmt.Fprintf(w, ", %s backend", report.Backend)
+		}
+		_, _ = fmt.Fprintln(w)
+		report.WriteText(w)
+	}
+	if !report.Healthy {
+		return 1
+	}
+	return 0
+}
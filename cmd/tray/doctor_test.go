@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func TestCheckGitConfig(t *testing.T) {
+	dir := t.TempDir()
+	adapter := filepath.Join(dir, "git-lfs-proton-adapter")
+	if err := os.WriteFile(adapter, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := "--backend sdk --drive-cli-bin '/opt/proton drive/index.js'"
+	tests := []struct {
+		name, agent, path string
+		args, concurrent  string
+		want              string
+		detail            string
+	}{
+		{"unregistered", "", "", "", "", config.CheckFail, "not registered"},
+		{"other agent", "lfs-folderstore", adapter, args, "", config.CheckFail, `"lfs-folderstore"`},
+		{"agent without path", "proton", "", args, "", config.CheckFail, "path is not set"},
+		{"missing binary", "proton", filepath.Join(dir, "gone"), args, "", config.CheckFail, "does not exist"},
+		{"directory", "proton", dir, args, "", config.CheckFail, "does not exist"},
+		{"not executable", "proton", plain, args, "", config.CheckFail, "not executable"},
+		{"unbalanced args", "proton", adapter, "--drive-cli-bin '/opt/proton", "", config.CheckFail, "lfs.customtransfer.proton.args"},
+		{"concurrent not a boolean", "proton", adapter, args, "maybe", config.CheckFail, "not a boolean"},
+		{"registered", "proton", adapter, args, "", config.CheckOK, adapter},
+		{"registered without args", "proton", adapter, "", "false", config.CheckOK, adapter},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.name == "not executable" && runtime.GOOS == "windows" {
+				t.Skip("Windows has no executable bit")
+			}
+			got := checkGitConfig(tc.agent, tc.path, tc.args, tc.concurrent)
+			if got.Status != tc.want || !strings.Contains(got.Detail, tc.detail) {
+				t.Fatalf("got %+v, want %s containing %q", got, tc.want, tc.detail)
+			}
+			if got.Status == config.CheckFail && got.Fix == "" {
+				t.Errorf("fix = %q", got.Fix)
+			}
+		})
+	}
+}
+
+func TestCheckSessionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	if got := checkSessionFile(path); got.Status != config.CheckFail || !strings.Contains(got.Fix, "login") {
+		t.Fatalf("missing session: %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkSessionFile(path); got.Status != config.CheckOK {
+		t.Fatalf("private session: %+v", got)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkSessionFile(path); got.Status != config.CheckWarn || !strings.Contains(got.Fix, "chmod 600") {
+		t.Fatalf("readable session: %+v", got)
+	}
+}
+
+func TestParseGitLFSVersion(t *testing.T) {
+	out := "git-lfs/3.4.1 (GitHub; linux amd64; go 1.21.5)"
+	if got := parseGitLFSVersion(out); got != "3.4.1" {
+		t.Fatalf("version = %q", got)
+	}
+	if got := parseGitLFSVersion("something else"); got != "something else" {
+		t.Fatalf("unparsed version = %q", got)
+	}
+}
+
+func TestMergeDoctorChecksPutsStorageLast(t *testing.T) {
+	adapter := []config.DoctorCheck{{Name: "node"}, {Name: "drive-cli"}, {Name: "storage"}}
+	local := []config.DoctorCheck{{Name: "git-lfs"}, {Name: "session"}}
+	var names []string
+	for _, c := range mergeDoctorChecks(adapter, local) {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "node,drive-cli,git-lfs,session,storage" {
+		t.Fatalf("order = %s", got)
+	}
+}
+
+func TestCliDoctorWithoutAdapter(t *testing.T) {
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, "")
+	orig := findAdapter
+	findAdapter = func() string { return "" }
+	t.Cleanup(func() { findAdapter = orig })
+
+	var buf bytes.Buffer
+	if code := cliDoctor(&buf, []string{"--json"}); code != 1 {
+		t.Fatalf("exit code = %d, want 1\n%s", code, buf.String())
+	}
+	var report config.DoctorReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report.Healthy || report.OS == "" || len(report.Checks) == 0 || report.Checks[0].Name != "adapter" {
+		t.Fatalf("report = %+v", report)
+	}
+}
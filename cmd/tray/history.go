@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const historyUsage = `Usage: proton-lfs-cli history [flags]
+
+Show finished LFS transfers from ~/.proton-lfs/history.jsonl, newest first.
+
+Flags:
+  --repo <path>      Only transfers in this repository ("." for the current one)
+  --since <when>     Only transfers at or after <when>
+  --until <when>     Only transfers before <when>
+  --state <state>    ok, failed, or an error state such as auth_required
+  --direction <dir>  upload or download
+  --oid <prefix>     Only objects whose OID starts with <prefix>
+  --limit <n>        Show at most <n> transfers (default: 50, 0 for all)
+  --json             Print the transfers as JSON
+
+<when> is a date (2006-01-02), an RFC 3339 time, or an age such as 90m,
+36h or 7d.
+`
+
+// cliHistory lists finished transfers from the history journal.
+func cliHistory(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, historyUsage) }
+	repo := fs.String("repo", "", "repository path")
+	since := fs.String("since", "", "earliest transfer")
+	until := fs.String("until", "", "latest transfer")
+	state := fs.String("state", "", "transfer state")
+	direction := fs.String("direction", "", "upload or download")
+	oid := fs.String("oid", "", "OID prefix")
+	limit := fs.Int("limit", 50, "maximum transfers shown")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	filter := config.HistoryFilter{
+		State:     strings.ToLower(strings.TrimSpace(*state)),
+		Direction: strings.ToLower(strings.TrimSpace(*direction)),
+		OID:       strings.TrimSpace(*oid),
+	}
+	var err error
+	if *repo != "" {
+		if filter.Repo, err = resolveRepoPath(*repo); err != nil {
+			_, _ = fmt.Fprintf(w, "error: %v\n", err)
+			return 1
+		}
+	}
+	now := time.Now()
+	if filter.Since, err = parseWhen(*since, now); err != nil {
+		_, _ = fmt.Fprintf(w, "error: --since: %v\n", err)
+		return 1
+	}
+	if filter.Until, err = parseWhen(*until, now); err != nil {
+		_, _ = fmt.Fprintf(w, "error: --until: %v\n", err)
+		return 1
+	}
+
+	entries, err := config.ReadHistory(filter)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	// Newest first, then cut to the limit.
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+
+	if *asJSON {
+		if entries == nil {
+			entries = []config.HistoryEntry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			return 1
+		}
+		return 0
+	}
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(w, "No transfers recorded")
+		return 0
+	}
+	printHistory(w, entries)
+	return 0
+}
+
+func printHistory(w io.Writer, entries []config.HistoryEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tDIRECTION\tSTATE\tSIZE\tDURATION\tOID\tREPO")
+	for _, e := range entries {
+		state := e.State
+		switch {
+		case e.Deduplicated:
+			state += " (already stored)"
+		case e.ErrorCode != "":
+			state += " (" + e.ErrorCode + ")"
+		}
+		oid := e.OID
+		if len(oid) > 12 {
+			oid = oid[:12]
+		}
+		duration := (time.Duration(e.DurationMS) * time.Millisecond).Round(time.Millisecond)
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Direction, state,
+			config.FormatBytes(e.Size), duration, oid, e.Repo)
+	}
+	_ = tw.Flush()
+}
+
+// resolveRepoPath turns a --repo argument into the repository path recorded
+// in the history: the top of the work tree when path is inside one.
+func resolveRepoPath(path string) (string, error) {
+	if out, err := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel").Output(); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+	return filepath.Abs(path)
+}
+
+// parseWhen parses a date, an RFC 3339 time, or an age before now such as
+// 36h or 7d. An empty value is the zero time.
+func parseWhen(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want 2006-01-02, an RFC 3339 time, or an age such as 36h or 7d)", value)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func seedHistory(t *testing.T, entries ...config.HistoryEntry) {
+	t.Helper()
+	t.Setenv(config.EnvHistoryFile, filepath.Join(t.TempDir(), "history.jsonl"))
+	for _, e := range entries {
+		if err := config.AppendHistory(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCliHistoryFiltersAndPrints(t *testing.T) {
+	now := time.Now()
+	seedHistory(t,
+		config.HistoryEntry{Time: now.Add(-48 * time.Hour), OID: strings.Repeat("a", 64), Size: 2048, Direction: "upload", State: config.StateOK, Repo: "/work/art", DurationMS: 1500},
+		config.HistoryEntry{Time: now.Add(-time.Hour), OID: strings.Repeat("b", 64), Direction: "upload", State: config.StateAuthRequired, ErrorCode: "auth_required", Repo: "/work/art"},
+		config.HistoryEntry{Time: now.Add(-time.Minute), OID: strings.Repeat("c", 64), Direction: "download", State: config.StateOK, Repo: "/work/app"},
+	)
+
+	var buf bytes.Buffer
+	if code := cliHistory(&buf, []string{"--repo", "/work/art"}); code != 0 {
+		t.Fatalf("history exit %d: %s", code, buf.String())
+	}
+	out := buf.String()
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Fatalf("expected a header and two transfers:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "auth_required (auth_required)") || !strings.Contains(lines[2], "2.0 KiB") || !strings.Contains(lines[2], "1.5s") {
+		t.Fatalf("unexpected rows, newest first expected:\n%s", out)
+	}
+
+	buf.Reset()
+	if code := cliHistory(&buf, []string{"--since", "1d", "--state", "ok", "--json"}); code != 0 {
+		t.Fatalf("history --json exit %d: %s", code, buf.String())
+	}
+	var entries []config.HistoryEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(entries) != 1 || entries[0].Repo != "/work/app" {
+		t.Fatalf("unexpected filtered entries: %+v", entries)
+	}
+
+	buf.Reset()
+	if code := cliHistory(&buf, []string{"--state", "failed", "--limit", "1"}); code != 0 || strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("expected one failed transfer, exit %d:\n%s", code, buf.String())
+	}
+}
+
+func TestCliHistoryEmptyAndInvalid(t *testing.T) {
+	seedHistory(t)
+
+	var buf bytes.Buffer
+	if code := cliHistory(&buf, nil); code != 0 || !strings.Contains(buf.String(), "No transfers recorded") {
+		t.Fatalf("exit %d:\n%s", code, buf.String())
+	}
+	buf.Reset()
+	if code := cliHistory(&buf, []string{"--json"}); code != 0 || strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("expected an empty JSON list, exit %d: %q", code, buf.String())
+	}
+	buf.Reset()
+	if code := cliHistory(&buf, []string{"--since", "yesterday"}); code != 1 {
+		t.Fatalf("expected an invalid --since to fail, exit %d:\n%s", code, buf.String())
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.Local)
+	for input, want := range map[string]time.Time{
+		"":                     {},
+		"2026-05-01":           time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local),
+		"2026-05-01T08:00:00Z": time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+		"7d":                   now.AddDate(0, 0, -7),
+		"90m":                  now.Add(-90 * time.Minute),
+	} {
+		got, err := parseWhen(input, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseWhen(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+}
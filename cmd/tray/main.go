@@ -38,21 +38,42 @@ func main() {
 			augmentPath()
 			os.Exit(cliLogout(os.Stdout))
 		case "register":
+			augmentPath()
+			os.Exit(cliRegister(os.Stdout, os.Args[2:]))
+		case "unregister":
+			os.Exit(cliUnregister(os.Stdout, os.Args[2:]))
+		case "status":
+			augmentPath()
+			os.Exit(cliStatus(os.Stdout, os.Args[2:]))
+		case "pause":
 			if hasHelpFlag(os.Args[2:]) {
-				fmt.Println("Usage: proton-lfs-cli register\n\nEnable the Proton LFS backend in git global config.")
+				fmt.Println("Usage: proton-lfs-cli pause\n\nPause all LFS transfers until 'proton-lfs-cli resume'.")
 				return
 			}
-			augmentPath()
-			os.Exit(cliRegister(os.Stdout))
-		case "status":
+			os.Exit(cliPause(os.Stdout))
+		case "resume":
 			if hasHelpFlag(os.Args[2:]) {
-				fmt.Println("Usage: proton-lfs-cli status\n\nShow session, LFS registration, credential provider, and transfer status.")
+				fmt.Println("Usage: proton-lfs-cli resume\n\nResume LFS transfers after 'proton-lfs-cli pause'.")
 				return
 			}
-			augmentPath()
-			os.Exit(cliStatus(os.Stdout))
+			os.Exit(cliResume(os.Stdout))
+		case "history":
+			os.Exit(cliHistory(os.Stdout, os.Args[2:]))
+		case "stats":
+			os.Exit(cliStats(os.Stdout, os.Args[2:]))
+		case "metrics":
+			os.Exit(cliMetrics(os.Stdout, os.Args[2:]))
 		case "config":
 			os.Exit(cliConfig(os.Stdout, os.Args[2:]))
+		case "prune":
+			augmentPath()
+			os.Exit(cliPrune(os.Stdout, os.Args[2:]))
+		case "fsck":
+			augmentPath()
+			os.Exit(cliFsck(os.Stdout, os.Args[2:]))
+		case "doctor":
+			augmentPath()
+			os.Exit(cliDoctor(os.Stdout, os.Args[2:]))
 		default:
 			fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
 			fmt.Fprint(os.Stderr, usage)
@@ -73,9 +94,21 @@ Usage:
   proton-lfs-cli                   Launch the system tray app
   proton-lfs-cli login             Authenticate with Proton
   proton-lfs-cli logout            Log out and clear session
-  proton-lfs-cli register          Enable LFS backend (git config --global)
-  proton-lfs-cli status            Show session, LFS, and transfer status
+  proton-lfs-cli register          Enable LFS backend (--local, --global, --system)
+  proton-lfs-cli unregister        Disable LFS backend, restoring the previous agent
+  proton-lfs-cli status [--json]   Show session, LFS, and transfer status
+  proton-lfs-cli pause             Pause all LFS transfers
+  proton-lfs-cli resume            Resume paused LFS transfers
+  proton-lfs-cli history           Show finished transfers
+  proton-lfs-cli stats             Summarize transfer throughput and errors
+  proton-lfs-cli metrics [serve]   Show or serve Prometheus metrics
   proton-lfs-cli config [provider] Show or set credential provider
+  proton-lfs-cli config list       Show every setting and where it comes from
+  proton-lfs-cli config set|unset  Change a saved setting (see 'config --help')
+  proton-lfs-cli config profile    Manage Proton account profiles
+  proton-lfs-cli prune [repo...]   Delete remote objects no repo references
+  proton-lfs-cli fsck [repo...]    Verify referenced objects exist and are intact
+  proton-lfs-cli doctor            Diagnose the setup and explain fixes
   proton-lfs-cli --version         Print version and exit
   proton-lfs-cli --help            Show this help
 
@@ -85,6 +118,7 @@ Credential providers: git-credential, pass-cli
 func onReady() {
 	setupMenu()
 	startStatusWatcher()
+	startMetricsCollector()
 }
 
 // augmentPath inherits the user's full shell PATH so that binaries
@@ -104,6 +138,7 @@ func augmentPath() {
 
 func onExit() {
 	stopStatusWatcher()
+	stopMetricsCollector()
 	releaseLock()
 }
 
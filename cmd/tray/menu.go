@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -29,6 +30,8 @@ var (
 	mCredPass *systray.MenuItem
 	mConnect  *systray.MenuItem
 	mRegister *systray.MenuItem
+	mPause    *systray.MenuItem
+	mProfiles map[string]*systray.MenuItem
 )
 
 func init() {
@@ -55,10 +58,24 @@ func setupMenu() {
 	prefs := config.LoadPrefs()
 	applyCredCheckmarks(prefs.CredentialProvider)
 
+	mProfileMenu := systray.AddMenuItem("Account Profile", "Choose which Proton account is used by default")
+	mProfiles = make(map[string]*systray.MenuItem)
+	for _, name := range profileNames(prefs) {
+		item := mProfileMenu.AddSubMenuItemCheckbox(name, "", false)
+		mProfiles[name] = item
+		go func(name string) {
+			for range item.ClickedCh {
+				switchProfile(name)
+			}
+		}(name)
+	}
+	applyProfileCheckmarks(activeProfile().Name)
+
 	systray.AddSeparator()
 
 	mConnect = systray.AddMenuItemCheckbox("Connect to Proton\u2026", "Store credentials and authenticate with Proton", false)
-	mRegister = systray.AddMenuItemCheckbox("Enable LFS Backend", "Configure Git to route LFS transfers through Proton Drive", false)
+	mRegister = systray.AddMenuItemCheckbox("Enable LFS Backend", "Configure Git to route LFS transfers through Proton Drive; click again to undo", false)
+	mPause = systray.AddMenuItemCheckbox("Pause Transfers", "Stop LFS transfers from reaching Proton until resumed", !prefs.Enabled)
 
 	systray.AddSeparator()
 
@@ -79,7 +96,9 @@ func setupMenu() {
 			case <-mConnect.ClickedCh:
 				connectToProton()
 			case <-mRegister.ClickedCh:
-				registerGitLFS()
+				toggleGitLFS()
+			case <-mPause.ClickedCh:
+				togglePause()
 			case <-mAutoStart.ClickedCh:
 				toggleAutoStart(mAutoStart)
 			case <-mQuit.ClickedCh:
@@ -123,52 +142,103 @@ func applyCredCheckmarks(provider string) {
 }
 
 func switchCredentialProvider(provider string) {
-	prefs := config.LoadPrefs()
-	prefs.CredentialProvider = provider
-	_ = config.SavePrefs(prefs)
+	_, _ = config.UpdatePrefs(func(p *config.Preferences) error {
+		p.CredentialProvider = provider
+		return nil
+	})
 	applyCredCheckmarks(provider)
 }
 
-func registerGitLFS() {
-	adapterPath := discoverAdapterBinary()
-	if adapterPath == "" {
-		sendNotification("Error: adapter binary not found")
-		return
+func applyProfileCheckmarks(active string) {
+	for name, item := range mProfiles {
+		if name == active {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
 	}
-	if err := exec.Command("git", "config", "--global", "lfs.customtransfer.proton.path", adapterPath).Run(); err != nil {
-		sendNotification("Error: git config failed")
-		return
+}
+
+// switchProfile makes name the active profile and refreshes the menu, since
+// the session, and so the Connect state, belongs to the profile.
+func switchProfile(name string) {
+	_, _ = config.UpdatePrefs(func(p *config.Preferences) error {
+		p.ActiveProfile = name
+		if name == config.DefaultProfileName {
+			p.ActiveProfile = ""
+		}
+		return nil
+	})
+	applyProfileCheckmarks(name)
+	applyLoginStatus()
+}
+
+// applyPauseStatus updates the Pause menu item checkmark.
+func applyPauseStatus(paused bool) {
+	if paused {
+		mPause.Check()
+	} else {
+		mPause.Uncheck()
 	}
+}
 
-	prefs := config.LoadPrefs()
-	driveCLIPath := discoverDriveCLIBinary()
-	args := "--backend sdk"
-	if prefs.CredentialProvider == config.CredentialProviderGitCredential {
-		args += " --credential-provider git-credential"
+func togglePause() {
+	// The toggle reads the current state under the lock, in case the CLI
+	// paused or resumed since the menu was drawn.
+	prefs, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		p.Enabled = !p.Enabled
+		return nil
+	})
+	if err != nil {
+		sendNotification("Error: could not save settings")
+		return
 	}
-	if driveCLIPath != "" {
-		args += " --drive-cli-bin " + driveCLIPath
+	applyPauseStatus(!prefs.Enabled)
+	applyStatus()
+	if prefs.Enabled {
+		sendNotification("Transfers resumed")
+	} else {
+		sendNotification("Transfers paused")
 	}
-	if err := exec.Command("git", "config", "--global", "lfs.customtransfer.proton.args", args).Run(); err != nil {
-		sendNotification("Error: git config failed")
+}
+
+// toggleGitLFS registers the adapter in git global config, or unregisters it
+// when it already is.
+func toggleGitLFS() {
+	target := registerTarget{scope: scopeGlobal}
+	if isLFSEnabled() {
+		restored, err := unregister(target)
+		if err != nil && !errors.Is(err, errNotRegistered) {
+			sendNotification("Error: " + err.Error())
+			return
+		}
+		applyRegisterStatus(false)
+		if restored != "" {
+			sendNotification(fmt.Sprintf("LFS Backend Disabled, %q restored", restored))
+		} else {
+			sendNotification("LFS Backend Disabled")
+		}
 		return
 	}
-	if err := exec.Command("git", "config", "--global", "lfs.standalonetransferagent", "proton").Run(); err != nil {
-		sendNotification("Error: git config failed")
+
+	adapterPath := discoverAdapterBinary()
+	if adapterPath == "" {
+		sendNotification("Error: adapter binary not found")
+		return
+	}
+	if _, err := register(target, adapterPath, adapterArgs(discoverDriveCLIBinary())); err != nil {
+		sendNotification("Error: " + err.Error())
 		return
 	}
-
 	applyRegisterStatus(true)
 	sendNotification("LFS Backend Enabled")
 }
 
-// isLFSEnabled checks whether the Proton LFS adapter is registered in git global config.
+// isLFSEnabled checks whether the Proton LFS adapter is registered for every
+// endpoint in git global config, which is what the menu item toggles.
 func isLFSEnabled() bool {
-	out, err := exec.Command("git", "config", "--global", "lfs.standalonetransferagent").Output()
-	if err != nil {
-		return false
-	}
-	return strings.TrimSpace(string(out)) == "proton"
+	agent, _ := registerTarget{scope: scopeGlobal}.get("lfs.standalonetransferagent")
+	return agent == agentName
 }
 
 // isSessionActive checks whether a proton-drive-cli session file exists.
@@ -182,8 +252,11 @@ func isSessionActive() bool {
 }
 
 // sendNotification shows a native macOS notification banner, or falls back
-// to notify-send on Linux.
+// to notify-send on Linux, unless the notifications setting hides msg.
 func sendNotification(msg string) {
+	if !notificationShown(config.LoadPrefs().Notifications, msg) {
+		return
+	}
 	switch runtime.GOOS {
 	case "darwin":
 		_ = exec.Command("osascript", "-e",
@@ -193,6 +266,18 @@ func sendNotification(msg string) {
 	}
 }
 
+// notificationShown reports whether the notifications level shows msg.
+// Error notifications start with "Error" or report a failure.
+func notificationShown(level, msg string) bool {
+	switch level {
+	case config.NotifyOff:
+		return false
+	case config.NotifyErrors:
+		return strings.HasPrefix(msg, "Error") || strings.HasSuffix(msg, " failed")
+	}
+	return true
+}
+
 func toggleAutoStart(item *systray.MenuItem) {
 	if item.Checked() {
 		if err := setAutoStart(false); err == nil {
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"proton-lfs-cli/internal/config"
+	"proton-lfs-cli/internal/metrics"
+)
+
+const metricsUsage = `Usage: proton-lfs-cli metrics [serve [--listen <addr>]]
+
+Without a subcommand, print the metrics of the running collector in the
+Prometheus text format.
+
+serve runs the collector without the tray, for headless machines. Adapters
+push their samples to it on ~/.proton-lfs/metrics.sock, and it serves them
+at http://<addr>/metrics for Prometheus to scrape. The tray runs the same
+collector while it is open, on the socket only unless the metrics-addr
+setting names a scrape address.
+
+Flags:
+  --listen <addr>   Scrape address (default: the metrics-addr setting, or
+                    127.0.0.1:9464; "off" serves on the socket only)
+`
+
+// trayMetrics is the collector run by the tray, if it started one.
+var trayMetrics *metrics.Server
+
+// metricsListenAddr returns the scrape address, or "" when value is "off".
+func metricsListenAddr(value string) string {
+	if strings.EqualFold(strings.TrimSpace(value), "off") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// metricsAddrSetting returns the metrics-addr setting from the environment or
+// the preferences, or "" when neither sets it.
+func metricsAddrSetting() string {
+	return config.EnvOrDefault(config.EnvMetricsAddr, config.LoadPrefs().MetricsAddr)
+}
+
+// startMetricsCollector runs the metrics collector for the life of the tray,
+// unless `metrics serve` already runs one. It serves on the socket only
+// unless the metrics-addr setting opts in to a scrape address. When that
+// address is taken, the collector still takes pushes so that `metrics` can
+// show them.
+func startMetricsCollector() {
+	socket := config.MetricsSocketPath()
+	addr := metricsListenAddr(metricsAddrSetting())
+	srv, err := metrics.Listen(socket, addr)
+	if err != nil && addr != "" && !errors.Is(err, metrics.ErrCollectorRunning) {
+		trayLog.Printf("metrics: %v; serving on %s only", err, socket)
+		srv, err = metrics.Listen(socket, "")
+	}
+	if err != nil {
+		trayLog.Printf("metrics: %v", err)
+		return
+	}
+	trayMetrics = srv
+}
+
+func stopMetricsCollector() {
+	if trayMetrics != nil {
+		_ = trayMetrics.Close()
+	}
+}
+
+// cliMetrics prints the collected metrics or, with serve, runs the collector.
+func cliMetrics(w io.Writer, args []string) int {
+	if hasHelpFlag(args) {
+		_, _ = fmt.Fprint(w, metricsUsage)
+		return 0
+	}
+	if len(args) == 0 {
+		data, err := metrics.Fetch(config.MetricsSocketPath())
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "No metrics collector is running (start the tray or 'proton-lfs-cli metrics serve'): %v\n", err)
+			return 1
+		}
+		_, _ = w.Write(data)
+		return 0
+	}
+	if args[0] != "serve" {
+		_, _ = fmt.Fprintf(w, "unknown metrics command: %s\n", args[0])
+		_, _ = fmt.Fprint(w, metricsUsage)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("metrics serve", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, metricsUsage) }
+	defaultAddr := metricsAddrSetting()
+	if defaultAddr == "" {
+		defaultAddr = config.DefaultMetricsAddr
+	}
+	listen := fs.String("listen", defaultAddr, "scrape address")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	socket := config.MetricsSocketPath()
+	srv, err := metrics.Listen(socket, metricsListenAddr(*listen))
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = srv.Close() }()
+	if addr := srv.Addr(); addr != nil {
+		_, _ = fmt.Fprintf(w, "Serving metrics at http://%s/metrics (adapters push to %s)\n", addr, socket)
+	} else {
+		_, _ = fmt.Fprintf(w, "Collecting metrics on %s\n", socket)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	return 0
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+	"proton-lfs-cli/internal/metrics"
+)
+
+func TestCliMetricsShowsCollector(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	t.Setenv(config.EnvMetricsSocket, socket)
+
+	var buf bytes.Buffer
+	if code := cliMetrics(&buf, nil); code != 1 || !strings.Contains(buf.String(), "No metrics collector") {
+		t.Fatalf("expected a missing collector to fail, exit %d:\n%s", code, buf.String())
+	}
+
+	srv, err := metrics.Listen(socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = srv.Close() }()
+	srv.Registry.Inc(metrics.DedupHits)
+
+	buf.Reset()
+	if code := cliMetrics(&buf, nil); code != 0 || !strings.Contains(buf.String(), "proton_lfs_dedup_hits_total 1") {
+		t.Fatalf("exit %d:\n%s", code, buf.String())
+	}
+	if code := cliMetrics(&buf, []string{"bogus"}); code != 1 {
+		t.Fatalf("expected an unknown subcommand to fail, got exit %d", code)
+	}
+}
+
+func TestMetricsListenAddr(t *testing.T) {
+	for value, want := range map[string]string{"off": "", " OFF ": "", "127.0.0.1:9999": "127.0.0.1:9999", "": ""} {
+		if got := metricsListenAddr(value); got != want {
+			t.Errorf("metricsListenAddr(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestStartMetricsCollectorListensOnlyWhenOptedIn(t *testing.T) {
+	setupSettings(t, "")
+	t.Setenv(config.EnvMetricsSocket, filepath.Join(t.TempDir(), "metrics.sock"))
+	t.Cleanup(func() { trayMetrics = nil })
+
+	startMetricsCollector()
+	if trayMetrics == nil {
+		t.Fatal("expected the tray to run a collector")
+	}
+	if addr := trayMetrics.Addr(); addr != nil {
+		t.Fatalf("the tray must serve on the socket only by default, listening on %v", addr)
+	}
+	stopMetricsCollector()
+
+	if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		p.MetricsAddr = "127.0.0.1:0"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	startMetricsCollector()
+	defer stopMetricsCollector()
+	if trayMetrics == nil || trayMetrics.Addr() == nil {
+		t.Fatal("expected the metrics-addr setting to open the scrape address")
+	}
+}
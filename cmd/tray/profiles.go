@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// activeProfile returns the account profile selected in preferences, falling
+// back to the default profile if the active one was removed by hand.
+func activeProfile() config.Profile {
+	prefs := config.LoadPrefs()
+	profile, err := prefs.ResolveProfile("")
+	if err != nil {
+		profile, _ = prefs.FindProfile(config.DefaultProfileName)
+	}
+	return profile
+}
+
+// profileEnv returns the environment that points proton-drive-cli at the
+// profile's session. The default profile keeps proton-drive-cli's own location.
+func profileEnv(profile config.Profile) []string {
+	if profile.Name == config.DefaultProfileName {
+		return nil
+	}
+	return []string{config.EnvDriveCLISessionDir + "=" + profile.SessionDirPath()}
+}
+
+// profileNames lists the default profile followed by the configured ones.
+func profileNames(prefs config.Preferences) []string {
+	names := []string{config.DefaultProfileName}
+	for _, profile := range prefs.Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+const configProfileUsage = `Usage: proton-lfs-cli config profile [command]
+
+Manage Proton account profiles. With no command, prints the active profile.
+
+Commands:
+  list                     List profiles; * marks the active one
+  use <name> [--repo]      Make <name> the active profile, or with --repo
+                           select it for the current repository only
+  add <name> [flags]       Add or update a profile
+      --provider <name>      Credential provider (default: the global one)
+      --account <email>      Proton account used to look up credentials
+      --storage-base <dir>   Proton Drive folder for LFS objects
+      --session-dir <dir>    proton-drive-cli session directory
+  remove <name>            Delete a profile
+`
+
+// cliConfigProfile implements `config profile`.
+func cliConfigProfile(w io.Writer, args []string) int {
+	prefs := config.LoadPrefs()
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(w, activeProfile().Name)
+		return 0
+	}
+
+	switch args[0] {
+	case "--help", "-h":
+		_, _ = fmt.Fprint(w, configProfileUsage)
+		return 0
+	case "list":
+		active := activeProfile().Name
+		for _, name := range profileNames(prefs) {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			profile, _ := prefs.FindProfile(name)
+			detail := profile.CredentialProvider
+			if profile.Account != "" {
+				detail += ", " + profile.Account
+			}
+			_, _ = fmt.Fprintf(w, "%s %s (%s)\n", marker, name, detail)
+		}
+		return 0
+	case "use":
+		return cliConfigProfileUse(w, prefs, args[1:])
+	case "add":
+		return cliConfigProfileAdd(w, args[1:])
+	case "remove":
+		if len(args) != 2 {
+			_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config profile remove <name>")
+			return 1
+		}
+		if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+			return p.RemoveProfile(args[1])
+		}); err != nil {
+			_, _ = fmt.Fprintf(w, "error: %v\n", err)
+			return 1
+		}
+		_, _ = fmt.Fprintf(w, "Profile %s removed\n", args[1])
+		return 0
+	default:
+		_, _ = fmt.Fprintf(w, "unknown profile command: %s\n", args[0])
+		_, _ = fmt.Fprint(w, configProfileUsage)
+		return 1
+	}
+}
+
+func cliConfigProfileUse(w io.Writer, prefs config.Preferences, args []string) int {
+	fs := flag.NewFlagSet("config profile use", flag.ContinueOnError)
+	fs.SetOutput(w)
+	repo := fs.Bool("repo", false, "select the profile for the current repository only")
+	if err := fs.Parse(reorderFlags(args)); err != nil || fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config profile use <name> [--repo]")
+		return 1
+	}
+	name := fs.Arg(0)
+	if _, ok := prefs.FindProfile(name); !ok {
+		_, _ = fmt.Fprintf(w, "error: unknown profile %q\n", name)
+		return 1
+	}
+
+	if *repo {
+		if out, err := exec.Command("git", "config", "--local",
+			config.GitConfigProfileKey, name).CombinedOutput(); err != nil {
+			_, _ = fmt.Fprintf(w, "error: git config failed: %s\n", strings.TrimSpace(string(out)))
+			return 1
+		}
+		_, _ = fmt.Fprintf(w, "Profile %s selected for this repository\n", name)
+		return 0
+	}
+
+	if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		p.ActiveProfile = name
+		if name == config.DefaultProfileName {
+			p.ActiveProfile = ""
+		}
+		return nil
+	}); err != nil {
+		_, _ = fmt.Fprintf(w, "error saving config: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "Active profile set to %s\n", name)
+	return 0
+}
+
+func cliConfigProfileAdd(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("config profile add", flag.ContinueOnError)
+	fs.SetOutput(w)
+	provider := fs.String("provider", "", "credential provider")
+	account := fs.String("account", "", "Proton account email")
+	storageBase := fs.String("storage-base", "", "Proton Drive folder for LFS objects")
+	sessionDir := fs.String("session-dir", "", "proton-drive-cli session directory")
+	if err := fs.Parse(reorderFlags(args)); err != nil || fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config profile add <name> [flags]")
+		return 1
+	}
+
+	switch *provider {
+	case "", config.CredentialProviderGitCredential, config.CredentialProviderPassCLI:
+	default:
+		_, _ = fmt.Fprintf(w, "unknown provider: %s\n", *provider)
+		return 1
+	}
+	profile := config.Profile{
+		Name:               fs.Arg(0),
+		CredentialProvider: *provider,
+		Account:            strings.TrimSpace(*account),
+		StorageBase:        strings.TrimSpace(*storageBase),
+		SessionDir:         strings.TrimSpace(*sessionDir),
+	}
+	if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		return p.SetProfile(profile)
+	}); err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "Profile %s saved\n", profile.Name)
+	_, _ = fmt.Fprintf(w, "  session: %s\n", profile.SessionDirPath())
+	return 0
+}
+
+var boolFlags = map[string]bool{"repo": true, "h": true, "help": true}
+
+// reorderFlags moves positional arguments after the flags so that
+// `use work --repo` parses like `use --repo work`.
+func reorderFlags(args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		if !strings.Contains(arg, "=") && !boolFlags[strings.TrimLeft(arg, "-")] && i+1 < len(args) {
+			flags = append(flags, args[i+1])
+			i++
+		}
+	}
+	return append(flags, positional...)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func TestCliConfigProfileAddUseAndList(t *testing.T) {
+	saveFuncVars(t)
+	home := setupFakeHome(t, fakeHomeOpts{configJSON: `{"credentialProvider":"pass-cli"}`})
+
+	var buf bytes.Buffer
+	code := cliConfig(&buf, []string{"profile", "add", "work",
+		"--provider", "git-credential", "--account", "me@work.example", "--storage-base", "Work-LFS"})
+	if code != 0 {
+		t.Fatalf("add exit %d: %s", code, buf.String())
+	}
+	wantSession := filepath.Join(home, ".proton-drive-cli", "profiles", "work")
+	if !strings.Contains(buf.String(), wantSession) {
+		t.Errorf("add output missing session dir %q:\n%s", wantSession, buf.String())
+	}
+
+	buf.Reset()
+	if code := cliConfig(&buf, []string{"profile", "use", "work"}); code != 0 {
+		t.Fatalf("use exit %d: %s", code, buf.String())
+	}
+	if got := activeProfile(); got.Name != "work" || got.CredentialProvider != "git-credential" {
+		t.Fatalf("activeProfile() = %+v, want work with git-credential", got)
+	}
+	if got := sessionFilePath(); got != filepath.Join(wantSession, "session.json") {
+		t.Fatalf("sessionFilePath() = %q, want the work profile's session", got)
+	}
+
+	buf.Reset()
+	if code := cliConfig(&buf, []string{"profile", "list"}); code != 0 {
+		t.Fatalf("list exit %d", code)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "  default (pass-cli)") || !strings.Contains(out, "* work (git-credential, me@work.example)") {
+		t.Fatalf("unexpected list output:\n%s", out)
+	}
+
+	buf.Reset()
+	var status bytes.Buffer
+	cliStatus(&status, nil)
+	if !strings.Contains(status.String(), "Profile:  work") || !strings.Contains(status.String(), "Provider: git-credential") {
+		t.Errorf("status should show the active profile:\n%s", status.String())
+	}
+
+	if code := cliConfig(&buf, []string{"profile", "remove", "work"}); code != 0 {
+		t.Fatalf("remove exit %d: %s", code, buf.String())
+	}
+	if got := config.LoadPrefs(); len(got.Profiles) != 0 || got.ActiveProfile != "" {
+		t.Fatalf("expected the profile and its selection to be gone, got %+v", got)
+	}
+}
+
+func TestCliConfigProfileRejectsInvalid(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+
+	for _, args := range [][]string{
+		{"profile", "use", "missing"},
+		{"profile", "add", "default"},
+		{"profile", "add", "bad name"},
+		{"profile", "add", "work", "--provider", "keychain"},
+		{"profile", "remove", "missing"},
+		{"profile", "frobnicate"},
+	} {
+		var buf bytes.Buffer
+		if code := cliConfig(&buf, args); code != 1 {
+			t.Errorf("cliConfig(%v) exit %d, want 1; output:\n%s", args, code, buf.String())
+		}
+	}
+}
+
+func TestCliConfigProfileUseRepo(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{configJSON: `{"profiles":[{"name":"work"}]}`})
+	setupGitConfig(t, "")
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	t.Chdir(repo)
+
+	var buf bytes.Buffer
+	if code := cliConfig(&buf, []string{"profile", "use", "work", "--repo"}); code != 0 {
+		t.Fatalf("use --repo exit %d: %s", code, buf.String())
+	}
+	out, err := exec.Command("git", "config", "--local", "--get", config.GitConfigProfileKey).Output()
+	if err != nil || strings.TrimSpace(string(out)) != "work" {
+		t.Fatalf("expected %s=work in the repo config, got %q (%v)", config.GitConfigProfileKey, out, err)
+	}
+	if got := config.LoadPrefs().ActiveProfile; got != "" {
+		t.Fatalf("--repo must not change the global active profile, got %q", got)
+	}
+}
+
+func TestProfileEnv(t *testing.T) {
+	if env := profileEnv(config.Profile{Name: config.DefaultProfileName}); env != nil {
+		t.Fatalf("default profile should not override the session dir, got %v", env)
+	}
+	env := profileEnv(config.Profile{Name: "work", SessionDir: "/tmp/work-session"})
+	if len(env) != 1 || env[0] != config.EnvDriveCLISessionDir+"=/tmp/work-session" {
+		t.Fatalf("profileEnv() = %v", env)
+	}
+}
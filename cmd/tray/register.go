@@ -0,0 +1,461 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const registerUsage = `Usage: proton-lfs-cli register [--local|--global|--system] [--url <url>]
+       proton-lfs-cli unregister [--local|--global|--system] [--url <url>]
+
+register points git-lfs at the Proton adapter; unregister removes it again
+and restores the transfer agent that register replaced.
+
+Flags:
+  --local        Only the current repository (.git/config)
+  --global       Every repository of the current user (default)
+  --system       Every user on this machine (may need administrator rights)
+  --url <url>    Only LFS endpoints under <url>, e.g. https://github.com/acme/,
+                 using lfs.<url>.standalonetransferagent
+`
+
+// Git config scopes register can write to.
+const (
+	scopeLocal  = "local"
+	scopeGlobal = "global"
+	scopeSystem = "system"
+)
+
+const (
+	agentName      = "proton"
+	keyAdapterPath = "lfs.customtransfer.proton.path"
+	keyAdapterArgs = "lfs.customtransfer.proton.args"
+	// keyAdapterConcurrent is not written by register; doctor checks a
+	// value set by hand.
+	keyAdapterConcurrent = "lfs.customtransfer.proton.concurrent"
+	// backupSection holds, per transfer agent or adapter definition key, the
+	// value register replaced, so that unregister can put it back.
+	backupSection = "proton-lfs-backup"
+)
+
+// adapterDefinitionKeys make up the lfs.customtransfer.proton definition
+// that unregister removes, or restores when register replaced one.
+var adapterDefinitionKeys = []string{keyAdapterPath, keyAdapterArgs, keyAdapterConcurrent}
+
+var errNotRegistered = errors.New("the Proton LFS adapter is not registered")
+
+var agentKeyPattern = `^lfs\..*standalonetransferagent$`
+
+// registerTarget is where register writes: a git config scope and, when url
+// is set, only LFS endpoints under that URL.
+type registerTarget struct {
+	scope string
+	url   string
+}
+
+// agentKey returns the transfer agent key git-lfs reads for the target.
+func (t registerTarget) agentKey() string {
+	if t.url == "" {
+		return "lfs.standalonetransferagent"
+	}
+	return "lfs." + t.url + ".standalonetransferagent"
+}
+
+func (t registerTarget) String() string {
+	if t.url == "" {
+		return t.scope
+	}
+	return t.scope + ", " + t.url
+}
+
+func (t registerTarget) gitConfig(args ...string) *exec.Cmd {
+	return exec.Command("git", append([]string{"config", "--" + t.scope}, args...)...)
+}
+
+// get returns the value of key in the target's scope, and whether it is set.
+func (t registerTarget) get(key string) (string, bool) {
+	out, err := t.gitConfig("--get", key).Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+func (t registerTarget) set(key, value string) error {
+	if out, err := t.gitConfig(key, value).CombinedOutput(); err != nil {
+		return gitConfigError(err, out)
+	}
+	return nil
+}
+
+// unset removes key; a key that is not set is not an error.
+func (t registerTarget) unset(key string) error {
+	out, err := t.gitConfig("--unset-all", key).CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+		return nil
+	}
+	if err != nil {
+		return gitConfigError(err, out)
+	}
+	return nil
+}
+
+// removeSection removes section; a missing section is not an error.
+func (t registerTarget) removeSection(section string) {
+	_ = t.gitConfig("--remove-section", section).Run()
+}
+
+func gitConfigError(err error, out []byte) error {
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return fmt.Errorf("git config failed: %s", msg)
+	}
+	return fmt.Errorf("git config failed: %w", err)
+}
+
+func backupKey(agentKey string) string {
+	return backupSection + "." + agentKey + ".previous"
+}
+
+// register writes the adapter definition and makes proton the target's
+// transfer agent. A different agent already set there is saved first, once,
+// so that unregister can restore it. It returns that agent, if any. So is a
+// definition that no proton agent key in the scope uses, since register did
+// not write it.
+func register(t registerTarget, adapterPath, args string) (string, error) {
+	regs, err := lfsRegistrations(t.scope)
+	if err != nil {
+		return "", err
+	}
+	if len(regs) == 0 {
+		for _, key := range adapterDefinitionKeys {
+			value, ok := t.get(key)
+			if _, saved := t.get(backupKey(key)); ok && !saved {
+				if err := t.set(backupKey(key), value); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	agentKey := t.agentKey()
+	replaced := ""
+	if previous, ok := t.get(agentKey); ok && previous != agentName {
+		if _, saved := t.get(backupKey(agentKey)); !saved {
+			if err := t.set(backupKey(agentKey), previous); err != nil {
+				return "", err
+			}
+		}
+		replaced = previous
+	}
+	for _, kv := range [][2]string{{keyAdapterPath, adapterPath}, {keyAdapterArgs, args}, {agentKey, agentName}} {
+		if err := t.set(kv[0], kv[1]); err != nil {
+			return "", err
+		}
+	}
+	return replaced, nil
+}
+
+// unregister stops the target from using proton and restores the agent
+// register replaced, which it returns. Once no transfer agent key in the
+// scope names proton, the adapter definition is removed and the one register
+// replaced, if any, is restored.
+func unregister(t registerTarget) (string, error) {
+	agentKey := t.agentKey()
+	current, _ := t.get(agentKey)
+	previous, saved := t.get(backupKey(agentKey))
+	if current != agentName {
+		// Someone changed the agent since; their choice wins over the backup.
+		t.removeSection(backupSection + "." + agentKey)
+		return "", errNotRegistered
+	}
+	var err error
+	if saved {
+		err = t.set(agentKey, previous)
+	} else {
+		err = t.unset(agentKey)
+	}
+	if err != nil {
+		return "", err
+	}
+	t.removeSection(backupSection + "." + agentKey)
+
+	remaining, err := lfsRegistrations(t.scope)
+	if err != nil {
+		return previous, err
+	}
+	if len(remaining) == 0 {
+		t.removeSection("lfs.customtransfer.proton")
+		for _, key := range adapterDefinitionKeys {
+			if value, saved := t.get(backupKey(key)); saved {
+				if err := t.set(key, value); err != nil {
+					return previous, err
+				}
+			}
+			t.removeSection(backupSection + "." + key)
+		}
+	}
+	return previous, nil
+}
+
+// lfsRegistration is a transfer agent key that names proton.
+type lfsRegistration struct {
+	Scope string `json:"scope"`         // git config scope, e.g. global
+	URL   string `json:"url,omitempty"` // "" for the key that applies to every endpoint
+}
+
+func (r lfsRegistration) String() string {
+	if r.URL == "" {
+		return r.Scope
+	}
+	return r.Scope + " for " + r.URL
+}
+
+// lfsRegistrations lists the transfer agent keys naming proton in scope, or
+// in every scope visible from the working directory when scope is empty.
+func lfsRegistrations(scope string) ([]lfsRegistration, error) {
+	args := []string{"config"}
+	if scope != "" {
+		args = append(args, "--"+scope)
+	}
+	args = append(args, "--show-scope", "--get-regexp", agentKeyPattern)
+	out, err := exec.Command("git", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil, nil // no key matched
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git config failed: %w", err)
+	}
+	return parseLFSRegistrations(string(out)), nil
+}
+
+// parseLFSRegistrations parses `git config --show-scope --get-regexp`
+// output, one "<scope>\t<key> <value>" line per key.
+func parseLFSRegistrations(out string) []lfsRegistration {
+	var regs []lfsRegistration
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		scope, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		key, value, _ := strings.Cut(rest, " ")
+		if strings.TrimSpace(value) != agentName {
+			continue
+		}
+		u := strings.TrimSuffix(strings.TrimPrefix(key, "lfs."), "standalonetransferagent")
+		regs = append(regs, lfsRegistration{Scope: scope, URL: strings.TrimSuffix(u, ".")})
+	}
+	return regs
+}
+
+// gitConfigValue returns the effective value of key, or "" when unset.
+func gitConfigValue(key string) string {
+	out, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// registeredAdapterArgs returns the backend args that register wrote to git
+// config, split the way git-lfs's shell splits them.
+func registeredAdapterArgs() []string {
+	value := gitConfigValue(keyAdapterArgs)
+	args, err := splitShellArgs(value)
+	if err != nil {
+		return strings.Fields(value)
+	}
+	return args
+}
+
+// adapterArgs returns the lfs.customtransfer.proton.args value for the sdk
+// backend. The credential provider is left out: the adapter reads it from
+// preferences, so switching provider takes effect without re-registering.
+func adapterArgs(driveCLIPath string) string {
+	args := []string{"--backend", "sdk"}
+	if driveCLIPath != "" {
+		args = append(args, "--drive-cli-bin", driveCLIPath)
+	}
+	return shellJoin(args)
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin quotes args for a POSIX shell. git-lfs runs a custom transfer
+// adapter through sh, so paths with spaces must be quoted in the args.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// splitShellArgs splits s into words as a POSIX shell does, handling single
+// and double quotes and backslash escapes, but not expansions.
+func splitShellArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// parseRegisterTarget parses the flags shared by register and unregister.
+func parseRegisterTarget(w io.Writer, name string, args []string) (registerTarget, bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, registerUsage) }
+	local := fs.Bool("local", false, "current repository")
+	global := fs.Bool("global", false, "current user")
+	system := fs.Bool("system", false, "all users")
+	rawURL := fs.String("url", "", "LFS endpoint URL prefix")
+	if err := fs.Parse(args); err != nil {
+		return registerTarget{}, false
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return registerTarget{}, false
+	}
+
+	target := registerTarget{scope: scopeGlobal, url: strings.TrimSpace(*rawURL)}
+	scopes := 0
+	for scope, set := range map[string]bool{scopeLocal: *local, scopeGlobal: *global, scopeSystem: *system} {
+		if set {
+			target.scope = scope
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		_, _ = fmt.Fprintln(w, "error: use only one of --local, --global and --system")
+		return registerTarget{}, false
+	}
+	if target.url != "" {
+		if u, err := url.Parse(target.url); err != nil || u.Scheme == "" || u.Host == "" {
+			_, _ = fmt.Fprintf(w, "error: --url %q is not an absolute URL\n", target.url)
+			return registerTarget{}, false
+		}
+	}
+	if target.scope == scopeLocal {
+		if err := exec.Command("git", "rev-parse", "--git-dir").Run(); err != nil {
+			_, _ = fmt.Fprintln(w, "error: --local must be run inside a git repository")
+			return registerTarget{}, false
+		}
+	}
+	return target, true
+}
+
+// cliRegister enables the Proton LFS backend in git config.
+func cliRegister(w io.Writer, args []string) int {
+	if hasHelpFlag(args) {
+		_, _ = fmt.Fprint(w, registerUsage)
+		return 0
+	}
+	target, ok := parseRegisterTarget(w, "register", args)
+	if !ok {
+		return 1
+	}
+	adapterPath := findAdapter()
+	if adapterPath == "" {
+		_, _ = fmt.Fprintln(w, "error: adapter binary not found")
+		return 1
+	}
+
+	prefs := config.LoadPrefs()
+	driveCLIPath := findDriveCLI()
+	replaced, err := register(target, adapterPath, adapterArgs(driveCLIPath))
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+
+	_, _ = fmt.Fprintf(w, "LFS backend enabled (%s)\n", target)
+	_, _ = fmt.Fprintf(w, "  adapter: %s\n", adapterPath)
+	if driveCLIPath != "" {
+		_, _ = fmt.Fprintf(w, "  drive-cli: %s\n", driveCLIPath)
+	}
+	_, _ = fmt.Fprintf(w, "  provider: %s\n", prefs.CredentialProvider)
+	if replaced != "" {
+		_, _ = fmt.Fprintf(w, "  replaced transfer agent %q; 'proton-lfs-cli unregister' restores it\n", replaced)
+	}
+	return 0
+}
+
+// cliUnregister disables the Proton LFS backend in git config.
+func cliUnregister(w io.Writer, args []string) int {
+	if hasHelpFlag(args) {
+		_, _ = fmt.Fprint(w, registerUsage)
+		return 0
+	}
+	target, ok := parseRegisterTarget(w, "unregister", args)
+	if !ok {
+		return 1
+	}
+	restored, err := unregister(target)
+	if errors.Is(err, errNotRegistered) {
+		_, _ = fmt.Fprintf(w, "error: %v in %s git config\n", err, target)
+		return 1
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "LFS backend disabled (%s)\n", target)
+	if restored != "" {
+		_, _ = fmt.Fprintf(w, "  restored transfer agent %q\n", restored)
+	}
+	return 0
+}
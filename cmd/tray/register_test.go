@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShellJoinRoundTrip(t *testing.T) {
+	for _, args := range [][]string{
+		{"--backend", "sdk"},
+		{"--drive-cli-bin", "/Applications/Proton Git LFS.app/Contents/Helpers/proton-drive-cli"},
+		{"--drive-cli-bin", `C:\Program Files\Proton LFS\proton-drive-cli.exe`},
+		{"--storage-base", "it's here", ""},
+	} {
+		joined := shellJoin(args)
+		got, err := splitShellArgs(joined)
+		if err != nil {
+			t.Fatalf("splitShellArgs(%q): %v", joined, err)
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("round trip of %q = %q", args, got)
+		}
+		// git-lfs hands the args to sh, which must see the same words.
+		if _, err := exec.LookPath("sh"); err == nil {
+			out, err := exec.Command("sh", "-c", "printf '%s\\n' "+joined).Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if words := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"); !reflect.DeepEqual(words, args) {
+				t.Errorf("sh split %q into %q", joined, words)
+			}
+		}
+	}
+	if got := shellJoin([]string{"--backend", "sdk"}); got != "--backend sdk" {
+		t.Errorf("plain args were quoted: %q", got)
+	}
+}
+
+func TestSplitShellArgs(t *testing.T) {
+	got, err := splitShellArgs(`--a "b c" 'd "e"' f\ g "h\"i"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"--a", "b c", `d "e"`, "f g", `h"i`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if _, err := splitShellArgs(`--a 'b`); err == nil {
+		t.Fatal("expected an error for an unterminated quote")
+	}
+}
+
+func TestParseLFSRegistrations(t *testing.T) {
+	out := "global\tlfs.standalonetransferagent proton\n" +
+		"local\tlfs.https://github.com/acme/.standalonetransferagent proton\n" +
+		"system\tlfs.standalonetransferagent lfs-folderstore\n"
+	got := parseLFSRegistrations(out)
+	want := []lfsRegistration{{Scope: "global"}, {Scope: "local", URL: "https://github.com/acme/"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRegisterAndUnregisterRestorePreviousAgent(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	gitCfg := setupGitConfig(t, "[lfs]\n\tstandalonetransferagent = lfs-folderstore\n[lfs \"customtransfer.lfs-folderstore\"]\n\tpath = lfs-folderstore\n")
+	findAdapter = func() string { return "/tmp/test-adapter" }
+	findDriveCLI = func() string { return "/opt/Proton LFS/proton-drive-cli" }
+
+	var buf bytes.Buffer
+	if code := cliRegister(&buf, nil); code != 0 {
+		t.Fatalf("register exit %d:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), `replaced transfer agent "lfs-folderstore"`) {
+		t.Errorf("register did not report the replaced agent:\n%s", buf.String())
+	}
+	global := registerTarget{scope: scopeGlobal}
+	if agent, _ := global.get("lfs.standalonetransferagent"); agent != agentName {
+		t.Fatalf("agent = %q after register", agent)
+	}
+	if args := registeredAdapterArgs(); !reflect.DeepEqual(args, []string{"--backend", "sdk", "--drive-cli-bin", "/opt/Proton LFS/proton-drive-cli"}) {
+		t.Fatalf("registered args = %q", args)
+	}
+
+	// Registering again must not overwrite the saved agent with proton.
+	buf.Reset()
+	if code := cliRegister(&buf, nil); code != 0 {
+		t.Fatalf("second register exit %d:\n%s", code, buf.String())
+	}
+
+	buf.Reset()
+	if code := cliUnregister(&buf, nil); code != 0 {
+		t.Fatalf("unregister exit %d:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), `restored transfer agent "lfs-folderstore"`) {
+		t.Errorf("unregister did not report the restored agent:\n%s", buf.String())
+	}
+	data, err := os.ReadFile(gitCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := string(data)
+	if !strings.Contains(cfg, "standalonetransferagent = lfs-folderstore") {
+		t.Errorf("previous agent not restored:\n%s", cfg)
+	}
+	for _, gone := range []string{"proton", backupSection} {
+		if strings.Contains(cfg, gone) {
+			t.Errorf("config still mentions %q:\n%s", gone, cfg)
+		}
+	}
+
+	buf.Reset()
+	if code := cliUnregister(&buf, nil); code != 1 || !strings.Contains(buf.String(), "not registered") {
+		t.Fatalf("second unregister exit %d:\n%s", code, buf.String())
+	}
+}
+
+func TestUnregisterRestoresPreviousAdapterDefinition(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	previous := "[lfs \"customtransfer.proton\"]\n\tpath = /usr/local/bin/other-proton\n\targs = --verbose\n\tconcurrent = false\n"
+	gitCfg := setupGitConfig(t, previous)
+	findAdapter = func() string { return "/tmp/test-adapter" }
+	findDriveCLI = func() string { return "" }
+
+	var buf bytes.Buffer
+	global := registerTarget{scope: scopeGlobal}
+	for range 2 {
+		// Registering again must not back up register's own definition.
+		if code := cliRegister(&buf, nil); code != 0 {
+			t.Fatalf("register exit %d:\n%s", code, buf.String())
+		}
+		if path, _ := global.get(keyAdapterPath); path != "/tmp/test-adapter" {
+			t.Fatalf("path = %q after register", path)
+		}
+	}
+
+	if code := cliUnregister(&buf, nil); code != 0 {
+		t.Fatalf("unregister exit %d:\n%s", code, buf.String())
+	}
+	for key, want := range map[string]string{
+		keyAdapterPath:       "/usr/local/bin/other-proton",
+		keyAdapterArgs:       "--verbose",
+		keyAdapterConcurrent: "false",
+	} {
+		if got, _ := global.get(key); got != want {
+			t.Errorf("%s = %q after unregister, want %q", key, got, want)
+		}
+	}
+	data, _ := os.ReadFile(gitCfg)
+	if cfg := string(data); strings.Contains(cfg, backupSection) || strings.Contains(cfg, "test-adapter") {
+		t.Errorf("unregister left register's config behind:\n%s", cfg)
+	}
+}
+
+func TestRegisterURLKeepsAdapterForOtherURLs(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	gitCfg := setupGitConfig(t, "")
+	findAdapter = func() string { return "/tmp/test-adapter" }
+	findDriveCLI = func() string { return "" }
+
+	var buf bytes.Buffer
+	for _, u := range []string{"https://github.com/acme/", "https://gitlab.com/acme/"} {
+		if code := cliRegister(&buf, []string{"--url", u}); code != 0 {
+			t.Fatalf("register --url %s exit %d:\n%s", u, code, buf.String())
+		}
+	}
+	if agent, _ := (registerTarget{scope: scopeGlobal}).get("lfs.standalonetransferagent"); agent != "" {
+		t.Fatalf("--url must not set the global agent, got %q", agent)
+	}
+	regs, err := lfsRegistrations(scopeGlobal)
+	if err != nil || len(regs) != 2 {
+		t.Fatalf("registrations = %+v, %v", regs, err)
+	}
+
+	if code := cliUnregister(&buf, []string{"--url", "https://github.com/acme/"}); code != 0 {
+		t.Fatalf("unregister exit %d:\n%s", code, buf.String())
+	}
+	data, _ := os.ReadFile(gitCfg)
+	if !strings.Contains(string(data), "path = /tmp/test-adapter") {
+		t.Fatalf("adapter removed while another URL still uses it:\n%s", data)
+	}
+
+	if code := cliUnregister(&buf, []string{"--url", "https://gitlab.com/acme/"}); code != 0 {
+		t.Fatalf("unregister exit %d:\n%s", code, buf.String())
+	}
+	data, _ = os.ReadFile(gitCfg)
+	if strings.Contains(string(data), "test-adapter") {
+		t.Fatalf("adapter left behind after the last URL:\n%s", data)
+	}
+}
+
+func TestRegisterLocalOnlyTouchesRepository(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	gitCfg := setupGitConfig(t, "")
+	findAdapter = func() string { return "/tmp/test-adapter" }
+	findDriveCLI = func() string { return "" }
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	t.Chdir(repo)
+
+	var buf bytes.Buffer
+	if code := cliRegister(&buf, []string{"--local"}); code != 0 {
+		t.Fatalf("register --local exit %d:\n%s", code, buf.String())
+	}
+	if data, _ := os.ReadFile(gitCfg); strings.Contains(string(data), "proton") {
+		t.Fatalf("--local wrote global config:\n%s", data)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, ".git", "config"))
+	if err != nil || !strings.Contains(string(data), "standalonetransferagent = proton") {
+		t.Fatalf("repository config not written (%v):\n%s", err, data)
+	}
+	if isLFSEnabled() {
+		t.Error("isLFSEnabled reports a repository registration as global")
+	}
+
+	buf.Reset()
+	if code := cliStatus(&buf, nil); code != 0 || !strings.Contains(buf.String(), "LFS:      enabled (local)") {
+		t.Fatalf("status exit %d:\n%s", code, buf.String())
+	}
+}
+
+func TestRegisterRejectsBadFlags(t *testing.T) {
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, "")
+	findAdapter = func() string { return "/tmp/test-adapter" }
+
+	for _, args := range [][]string{
+		{"--local", "--global"},
+		{"--url", "github.com/acme"},
+		{"extra"},
+	} {
+		var buf bytes.Buffer
+		if code := cliRegister(&buf, args); code != 1 {
+			t.Errorf("register %q exit %d, want 1:\n%s", args, code, buf.String())
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const configUsage = `Usage: proton-lfs-cli config <command>
+
+Show and change the settings shared by the adapter and the tray.
+
+Commands:
+  list [--json]          Show each setting, its effective value and its source
+  get <key>              Print the effective value of a setting
+  set <key> <value>      Save a setting in ~/.proton-lfs/config.json
+  unset <key>            Remove a saved setting
+  profile <command>      Manage account profiles (see 'config profile --help')
+  <provider>             Same as 'config set credential-provider <provider>'
+
+With no command, prints the credential provider.
+
+The effective value comes from the first of: the registered adapter args
+(lfs.customtransfer.proton.args), the environment, git config
+lfs.proton.<key>, the repository's .lfsconfig, config.json, and the default.
+git config and .lfsconfig are read from the current repository. .lfsconfig
+can only set timeout and log-level. 'set' only writes config.json, so it
+warns when a source above overrides it.
+
+Keys:
+`
+
+// printConfigUsage writes the usage with one line per setting.
+func printConfigUsage(w io.Writer) {
+	_, _ = fmt.Fprint(w, configUsage)
+	for _, s := range config.Settings {
+		_, _ = fmt.Fprintf(w, "  %-20s %s\n", s.Key, s.Help)
+	}
+}
+
+// cliConfig shows or changes settings, or manages profiles.
+func cliConfig(w io.Writer, args []string) int {
+	if len(args) == 0 {
+		prefs := config.LoadPrefs()
+		_, _ = fmt.Fprintln(w, prefs.CredentialProvider)
+		return 0
+	}
+
+	switch args[0] {
+	case "--help", "-h", "help":
+		printConfigUsage(w)
+		return 0
+	case "profile":
+		return cliConfigProfile(w, args[1:])
+	case "list":
+		return cliConfigList(w, args[1:])
+	case "get":
+		if len(args) != 2 {
+			_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config get <key>")
+			return 1
+		}
+		value, ok := lookupSettingValue(w, args[1])
+		if !ok {
+			return 1
+		}
+		_, _ = fmt.Fprintln(w, value.Value)
+		return 0
+	case "set":
+		if len(args) != 3 {
+			_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config set <key> <value>")
+			return 1
+		}
+		return cliConfigSet(w, args[1], args[2])
+	case "unset":
+		if len(args) != 2 {
+			_, _ = fmt.Fprintln(w, "usage: proton-lfs-cli config unset <key>")
+			return 1
+		}
+		return cliConfigSet(w, args[1], "")
+	case config.CredentialProviderGitCredential, config.CredentialProviderPassCLI:
+		// The shorthand predates 'config set'.
+	default:
+		_, _ = fmt.Fprintf(w, "unknown provider: %s\n", args[0])
+		_, _ = fmt.Fprintf(w, "valid providers: %s, %s\n",
+			config.CredentialProviderGitCredential, config.CredentialProviderPassCLI)
+		_, _ = fmt.Fprintln(w, "See 'proton-lfs-cli config --help' for the other settings.")
+		return 1
+	}
+
+	provider := args[0]
+	if _, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		p.CredentialProvider = provider
+		return nil
+	}); err != nil {
+		_, _ = fmt.Fprintf(w, "error saving config: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "Credential provider set to %s\n", provider)
+	return 0
+}
+
+// cliConfigList prints every setting with its effective value and source.
+func cliConfigList(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("config list", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { printConfigUsage(w) }
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	values := resolveConfigSettings()
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(values)
+		return 0
+	}
+	for _, v := range values {
+		value, source := v.Value, v.Source
+		if value == "" {
+			value = `""`
+		}
+		if v.Origin != "" {
+			source += " (" + v.Origin + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%-20s %-30s %s\n", v.Key, value, source)
+	}
+	return 0
+}
+
+// cliConfigSet validates value and saves it in preferences; an empty value
+// unsets the key.
+func cliConfigSet(w io.Writer, key, value string) int {
+	setting, ok := config.LookupSetting(key)
+	if !ok {
+		_, _ = fmt.Fprintf(w, "unknown key: %s\nvalid keys: %s\n", key, strings.Join(config.SettingKeys(), ", "))
+		return 1
+	}
+	// Unlike LoadPrefs, UpdatePrefs leaves unset fields empty, so that unset
+	// does not save the default in their place. A corrupt file is left
+	// alone rather than replaced.
+	prefs, err := config.UpdatePrefs(func(p *config.Preferences) error {
+		return setting.Set(p, value)
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+
+	effective, _ := lookupSettingValue(w, setting.Key)
+	if value == "" {
+		_, _ = fmt.Fprintf(w, "Unset %s; it is now %s (%s)\n", setting.Key, displayValue(effective.Value), effective.Source)
+		return 0
+	}
+	_, _ = fmt.Fprintf(w, "Set %s to %s\n", setting.Key, setting.Get(prefs))
+	if effective.Source != config.SourcePrefs {
+		_, _ = fmt.Fprintf(w, "warning: %s (%s) overrides it with %s\n", effective.Source, effective.Origin, displayValue(effective.Value))
+	}
+	return 0
+}
+
+// lookupSettingValue returns the effective value of key, or prints an error.
+func lookupSettingValue(w io.Writer, key string) (config.SettingValue, bool) {
+	setting, ok := config.LookupSetting(key)
+	if !ok {
+		_, _ = fmt.Fprintf(w, "unknown key: %s\nvalid keys: %s\n", key, strings.Join(config.SettingKeys(), ", "))
+		return config.SettingValue{}, false
+	}
+	for _, v := range resolveConfigSettings() {
+		if v.Key == setting.Key {
+			return v, true
+		}
+	}
+	return config.SettingValue{}, false
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+// resolveConfigSettings returns the effective value of every setting as the
+// adapter would see it in the current repository, with the same precedence.
+func resolveConfigSettings() []config.SettingValue {
+	flags := adapterArgSettings(registeredAdapterArgs())
+	gitValues := readGitConfigSettings("")
+	var lfsValues map[string]string
+	if top, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		lfsValues = readGitConfigSettings(filepath.Join(strings.TrimSpace(string(top)), config.LFSConfigFileName))
+	}
+	prefs, _ := config.ReadPrefs()
+
+	values := make([]config.SettingValue, 0, len(config.Settings))
+	for _, s := range config.Settings {
+		gitKey := config.GitConfigSection + "." + s.Key
+		v := config.SettingValue{Key: s.Key, Value: s.Default, Source: config.SourceDefault}
+		switch {
+		case flags[s.Key] != "":
+			v.Value, v.Source, v.Origin = flags[s.Key], config.SourceFlag, keyAdapterArgs
+		case s.Env != "" && config.EnvTrim(s.Env) != "":
+			v.Value, v.Source, v.Origin = config.EnvTrim(s.Env), config.SourceEnv, s.Env
+		case s.GitConfig && gitValues[s.Key] != "":
+			v.Value, v.Source, v.Origin = gitValues[s.Key], config.SourceGitConfig, gitKey
+		case s.LFSConfig && lfsValues[s.Key] != "":
+			v.Value, v.Source, v.Origin = lfsValues[s.Key], config.SourceLFSConfig, gitKey
+		case s.Get(prefs) != "":
+			v.Value, v.Source, v.Origin = s.Get(prefs), config.SourcePrefs, config.PrefsFilePath()
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// adapterArgSettings returns the settings passed as flags in the registered
+// adapter args, by key. Both --key value and --key=value are recognized.
+func adapterArgSettings(args []string) map[string]string {
+	values := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		name, ok := strings.CutPrefix(args[i], "-")
+		if !ok {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(name, "-"), "=")
+		if _, known := config.LookupSetting(name); !known {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		values[name] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// readGitConfigSettings returns the lfs.proton.* values by key, from git
+// config or from file when it is set, the way the adapter reads them.
+func readGitConfigSettings(file string) map[string]string {
+	args := []string{"config"}
+	if file != "" {
+		args = append(args, "--file", file)
+	}
+	args = append(args, "--get-regexp", "^"+regexp.QuoteMeta(config.GitConfigSection)+`\.`)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil
+	}
+	values := make(map[string]string)
+	prefix := config.GitConfigSection + "."
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if name, ok := strings.CutPrefix(strings.ToLower(key), prefix); ok && name != "" {
+			values[name] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
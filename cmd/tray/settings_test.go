@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// setupSettings isolates the config command from the caller's environment,
+// repository and git config.
+func setupSettings(t *testing.T, gitConfig string) {
+	t.Helper()
+	saveFuncVars(t)
+	setupFakeHome(t, fakeHomeOpts{})
+	setupGitConfig(t, gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, s := range config.Settings {
+		if s.Env != "" {
+			t.Setenv(s.Env, "")
+		}
+	}
+	t.Chdir(t.TempDir())
+}
+
+func settingValues(t *testing.T) map[string]config.SettingValue {
+	t.Helper()
+	var buf bytes.Buffer
+	if code := cliConfig(&buf, []string{"list", "--json"}); code != 0 {
+		t.Fatalf("config list --json exit %d:\n%s", code, buf.String())
+	}
+	var values []config.SettingValue
+	if err := json.Unmarshal(buf.Bytes(), &values); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	byKey := make(map[string]config.SettingValue)
+	for _, v := range values {
+		byKey[v.Key] = v
+	}
+	if len(byKey) != len(config.Settings) {
+		t.Fatalf("listed %d settings, want %d", len(byKey), len(config.Settings))
+	}
+	return byKey
+}
+
+func TestCliConfigSetGetUnset(t *testing.T) {
+	setupSettings(t, "")
+
+	var buf bytes.Buffer
+	if code := cliConfig(&buf, []string{"set", "timeout", "90s"}); code != 0 {
+		t.Fatalf("set exit %d:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), "Set timeout to 1m30s") {
+		t.Errorf("set should report the normalised value:\n%s", buf.String())
+	}
+	buf.Reset()
+	if code := cliConfig(&buf, []string{"get", "timeout"}); code != 0 || strings.TrimSpace(buf.String()) != "1m30s" {
+		t.Fatalf("get timeout = %q (exit %d), want 1m30s", buf.String(), code)
+	}
+	if prefs, err := config.ReadPrefs(); err != nil || prefs.Timeout != "1m30s" {
+		t.Fatalf("prefs timeout = %q (%v), want 1m30s", prefs.Timeout, err)
+	}
+
+	buf.Reset()
+	if code := cliConfig(&buf, []string{"unset", "timeout"}); code != 0 {
+		t.Fatalf("unset exit %d:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), "it is now 5m0s (default)") {
+		t.Errorf("unset should report the value now in effect:\n%s", buf.String())
+	}
+	// Unsetting one key must not save defaults for the others.
+	if prefs, _ := config.ReadPrefs(); prefs.Timeout != "" || prefs.CredentialProvider != "" {
+		t.Errorf("prefs after unset = %+v, want no timeout or provider", prefs)
+	}
+}
+
+func TestCliConfigSetRejectsInvalidValues(t *testing.T) {
+	setupSettings(t, "")
+
+	for _, args := range [][]string{
+		{"set", "backend", "ftp"},
+		{"set", "timeout", "-1s"},
+		{"set", "concurrency", "0"},
+		{"set", "local-store-dir", "relative/dir"},
+		{"set", "profile", "missing"},
+		{"set", "notifications", "some"},
+		{"set", "no-such-key", "x"},
+		{"get", "no-such-key"},
+		{"set", "backend"},
+	} {
+		var buf bytes.Buffer
+		if code := cliConfig(&buf, args); code != 1 {
+			t.Errorf("config %v exit %d, want 1:\n%s", args, code, buf.String())
+		}
+	}
+	if _, err := os.Stat(config.PrefsFilePath()); err == nil {
+		t.Error("rejected values must not create the preferences file")
+	}
+}
+
+func TestCliConfigListSources(t *testing.T) {
+	setupSettings(t, "[lfs \"proton\"]\n\tstorage-base = LFS/git\n\tconcurrency = 3\n"+
+		"[lfs \"customtransfer.proton\"]\n\targs = --backend sdk --timeout=2m --drive-cli-bin /opt/cli.js\n")
+	t.Setenv(config.EnvPauseMode, "wait")
+	for _, args := range [][]string{
+		{"set", "concurrency", "8"},
+		{"set", "cache-max-mb", "512"},
+		{"set", "notifications", "errors"},
+	} {
+		var buf bytes.Buffer
+		if code := cliConfig(&buf, args); code != 0 {
+			t.Fatalf("config %v exit %d:\n%s", args, code, buf.String())
+		}
+		if args[1] == "concurrency" && !strings.Contains(buf.String(), "warning: git config (lfs.proton.concurrency) overrides it with 3") {
+			t.Errorf("set should warn about the git config override:\n%s", buf.String())
+		}
+	}
+
+	values := settingValues(t)
+	for _, want := range []config.SettingValue{
+		{Key: "backend", Value: "sdk", Source: config.SourceFlag, Origin: keyAdapterArgs},
+		{Key: "timeout", Value: "2m", Source: config.SourceFlag, Origin: keyAdapterArgs},
+		{Key: "pause-mode", Value: "wait", Source: config.SourceEnv, Origin: config.EnvPauseMode},
+		{Key: "storage-base", Value: "LFS/git", Source: config.SourceGitConfig, Origin: "lfs.proton.storage-base"},
+		{Key: "concurrency", Value: "3", Source: config.SourceGitConfig, Origin: "lfs.proton.concurrency"},
+		{Key: "cache-max-mb", Value: "512", Source: config.SourcePrefs, Origin: config.PrefsFilePath()},
+		{Key: "notifications", Value: "errors", Source: config.SourcePrefs, Origin: config.PrefsFilePath()},
+		{Key: "log-level", Value: config.DefaultLogLevel, Source: config.SourceDefault},
+	} {
+		if got := values[want.Key]; got != want {
+			t.Errorf("%s = %+v, want %+v", want.Key, got, want)
+		}
+	}
+}
+
+func TestCliConfigListReadsLFSConfig(t *testing.T) {
+	setupSettings(t, "")
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(".", ".lfsconfig"), []byte("[lfs \"proton\"]\n\ttimeout = 90s\n\tbackend = sdk\n\tcache-max-mb = 1\n\tconcurrency = 64\n\tqueue-timeout = 1h\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values := settingValues(t)
+	if got := values["timeout"]; got.Value != "90s" || got.Source != config.SourceLFSConfig {
+		t.Errorf("timeout = %+v, want 90s from .lfsconfig", got)
+	}
+	// As in the adapter, .lfsconfig can only set tuning keys.
+	for _, key := range []string{"backend", "cache-max-mb", "concurrency", "queue-timeout"} {
+		if got := values[key]; got.Source != config.SourceDefault {
+			t.Errorf("%s = %+v, want the default", key, got)
+		}
+	}
+}
+
+func TestNotificationShown(t *testing.T) {
+	cases := []struct {
+		level, msg string
+		want       bool
+	}{
+		{"", "Connected to Proton", true},
+		{config.NotifyAll, "Transfers paused", true},
+		{config.NotifyErrors, "Transfers paused", false},
+		{config.NotifyErrors, "Error: adapter binary not found", true},
+		{config.NotifyErrors, "Login failed", true},
+		{config.NotifyOff, "Error: could not save settings", false},
+	}
+	for _, c := range cases {
+		if got := notificationShown(c.level, c.msg); got != c.want {
+			t.Errorf("notificationShown(%q, %q) = %v, want %v", c.level, c.msg, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+const statsUsage = `Usage: proton-lfs-cli stats [flags]
+
+Summarize the transfer history: success rate, latency per operation,
+throughput per backend and repository, deduplicated uploads and the most
+frequent errors.
+
+Flags:
+  --since <when>     Start of the window (default: 7d)
+  --until <when>     End of the window (default: now)
+  --repo <path>      Only transfers in this repository ("." for the current one)
+  --backend <name>   Only transfers through this backend (local or sdk)
+  --top <n>          Number of error codes listed (default: 5)
+  --json             Print the report as JSON
+
+<when> is a date (2006-01-02), an RFC 3339 time, or an age such as 90m,
+36h or 7d.
+
+Latency and throughput count successful transfers that moved data; uploads
+skipped because the object was already stored only count towards the
+deduplication rate. Throughput is bytes moved per second of transfer time,
+so concurrent transfers each count their own time.
+`
+
+// statsReport summarizes the transfer history over a window.
+type statsReport struct {
+	Since     time.Time    `json:"since"`
+	Until     time.Time    `json:"until"`
+	Total     groupStats   `json:"total"`
+	Dedup     dedupStats   `json:"dedup"`
+	Latency   []opLatency  `json:"latency"`
+	Backends  []groupStats `json:"backends"`
+	Repos     []groupStats `json:"repos"`
+	TopErrors []errorCount `json:"topErrors"`
+}
+
+// groupStats are the totals of one backend, repository or the whole window.
+type groupStats struct {
+	Name          string  `json:"name,omitempty"`
+	Transfers     int     `json:"transfers"`
+	Succeeded     int     `json:"succeeded"`
+	SuccessRate   float64 `json:"successRate"`   // percent
+	Bytes         int64   `json:"bytes"`         // bytes moved by successful transfers
+	ThroughputBps float64 `json:"throughputBps"` // Bytes per second of transfer time
+	durationMS    int64
+}
+
+// dedupStats counts successful uploads that found the object already stored.
+type dedupStats struct {
+	Uploads      int     `json:"uploads"`
+	Deduplicated int     `json:"deduplicated"`
+	HitRate      float64 `json:"hitRate"` // percent
+}
+
+// opLatency holds latency percentiles of one operation, in milliseconds.
+type opLatency struct {
+	Op    string `json:"op"`
+	Count int    `json:"count"`
+	P50MS int64  `json:"p50Ms"`
+	P95MS int64  `json:"p95Ms"`
+}
+
+type errorCount struct {
+	Code  string `json:"code"`
+	Count int    `json:"count"`
+}
+
+// cliStats reports transfer statistics from the history journal.
+func cliStats(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, statsUsage) }
+	since := fs.String("since", "7d", "start of the window")
+	until := fs.String("until", "", "end of the window")
+	repo := fs.String("repo", "", "repository path")
+	backend := fs.String("backend", "", "backend name")
+	top := fs.Int("top", 5, "error codes listed")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	now := time.Now()
+	var filter config.HistoryFilter
+	var err error
+	if filter.Since, err = parseWhen(*since, now); err != nil {
+		_, _ = fmt.Fprintf(w, "error: --since: %v\n", err)
+		return 1
+	}
+	if filter.Until, err = parseWhen(*until, now); err != nil {
+		_, _ = fmt.Fprintf(w, "error: --until: %v\n", err)
+		return 1
+	}
+	if *repo != "" {
+		if filter.Repo, err = resolveRepoPath(*repo); err != nil {
+			_, _ = fmt.Fprintf(w, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	entries, err := config.ReadHistory(filter)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	if name := strings.ToLower(strings.TrimSpace(*backend)); name != "" {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.Backend == name {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	report := buildStats(entries, *top)
+	report.Since, report.Until = filter.Since, filter.Until
+	if report.Until.IsZero() {
+		report.Until = now
+	}
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return 1
+		}
+		return 0
+	}
+	printStats(w, report)
+	return 0
+}
+
+// buildStats aggregates history entries into a report listing at most top
+// error codes.
+func buildStats(entries []config.HistoryEntry, top int) statsReport {
+	report := statsReport{Latency: []opLatency{}, Backends: []groupStats{}, Repos: []groupStats{}, TopErrors: []errorCount{}}
+	backends := map[string]*groupStats{}
+	repos := map[string]*groupStats{}
+	latencies := map[string][]int64{}
+	errorCounts := map[string]int{}
+
+	for _, e := range entries {
+		groups := []*groupStats{&report.Total, groupFor(backends, e.Backend), groupFor(repos, e.Repo)}
+		for _, g := range groups {
+			g.Transfers++
+		}
+		if e.State != config.StateOK {
+			code := e.ErrorCode
+			if code == "" {
+				code = e.State
+			}
+			errorCounts[code]++
+			continue
+		}
+		for _, g := range groups {
+			g.Succeeded++
+		}
+		if e.Direction == "upload" {
+			report.Dedup.Uploads++
+			if e.Deduplicated {
+				report.Dedup.Deduplicated++
+				continue
+			}
+		}
+		latencies[e.Direction] = append(latencies[e.Direction], e.DurationMS)
+		for _, g := range groups {
+			g.Bytes += e.Size
+			g.durationMS += e.DurationMS
+		}
+	}
+
+	report.Total.finish()
+	report.Backends = sortedGroups(backends)
+	report.Repos = sortedGroups(repos)
+	report.Dedup.HitRate = percent(report.Dedup.Deduplicated, report.Dedup.Uploads)
+	for _, op := range []string{"upload", "download"} {
+		if ms := latencies[op]; len(ms) > 0 {
+			sort.Slice(ms, func(i, j int) bool { return ms[i] < ms[j] })
+			report.Latency = append(report.Latency, opLatency{Op: op, Count: len(ms), P50MS: percentile(ms, 50), P95MS: percentile(ms, 95)})
+		}
+	}
+	for code, n := range errorCounts {
+		report.TopErrors = append(report.TopErrors, errorCount{Code: code, Count: n})
+	}
+	sort.Slice(report.TopErrors, func(i, j int) bool {
+		a, b := report.TopErrors[i], report.TopErrors[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Code < b.Code)
+	})
+	if top >= 0 && len(report.TopErrors) > top {
+		report.TopErrors = report.TopErrors[:top]
+	}
+	return report
+}
+
+func groupFor(groups map[string]*groupStats, name string) *groupStats {
+	if name == "" {
+		name = "(unknown)"
+	}
+	g, ok := groups[name]
+	if !ok {
+		g = &groupStats{Name: name}
+		groups[name] = g
+	}
+	return g
+}
+
+func (g *groupStats) finish() {
+	g.SuccessRate = percent(g.Succeeded, g.Transfers)
+	if g.durationMS > 0 {
+		g.ThroughputBps = float64(g.Bytes) / (float64(g.durationMS) / 1000)
+	}
+}
+
+// sortedGroups finishes the groups and orders them by transfers, busiest
+// first.
+func sortedGroups(groups map[string]*groupStats) []groupStats {
+	out := make([]groupStats, 0, len(groups))
+	for _, g := range groups {
+		g.finish()
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Transfers > out[j].Transfers || (out[i].Transfers == out[j].Transfers && out[i].Name < out[j].Name)
+	})
+	return out
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func printStats(w io.Writer, r statsReport) {
+	window := "all time"
+	if !r.Since.IsZero() {
+		window = r.Since.Local().Format("2006-01-02 15:04") + " – " + r.Until.Local().Format("2006-01-02 15:04")
+	}
+	_, _ = fmt.Fprintf(w, "Window:     %s\n", window)
+	if r.Total.Transfers == 0 {
+		_, _ = fmt.Fprintln(w, "No transfers recorded")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Transfers:  %d (%d ok, %.1f%%)\n", r.Total.Transfers, r.Total.Succeeded, r.Total.SuccessRate)
+	_, _ = fmt.Fprintf(w, "Data:       %s at %s\n", config.FormatBytes(r.Total.Bytes), formatRate(r.Total.ThroughputBps))
+	_, _ = fmt.Fprintf(w, "Dedup:      %d of %d uploads already stored (%.1f%%)\n", r.Dedup.Deduplicated, r.Dedup.Uploads, r.Dedup.HitRate)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(r.Latency) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nOPERATION\tCOUNT\tP50\tP95")
+		for _, l := range r.Latency {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", l.Op, l.Count, formatMS(l.P50MS), formatMS(l.P95MS))
+		}
+	}
+	printGroups(tw, "BACKEND", r.Backends)
+	printGroups(tw, "REPOSITORY", r.Repos)
+	if len(r.TopErrors) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nERROR\tCOUNT")
+		for _, e := range r.TopErrors {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\n", e.Code, e.Count)
+		}
+	}
+	_ = tw.Flush()
+}
+
+func printGroups(tw *tabwriter.Writer, title string, groups []groupStats) {
+	_, _ = fmt.Fprintf(tw, "\n%s\tTRANSFERS\tSUCCESS\tDATA\tTHROUGHPUT\n", title)
+	for _, g := range groups {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%s\t%s\n", g.Name, g.Transfers, g.SuccessRate, config.FormatBytes(g.Bytes), formatRate(g.ThroughputBps))
+	}
+}
+
+func formatRate(bps float64) string {
+	if bps <= 0 {
+		return "-"
+	}
+	return config.FormatBytes(int64(bps)) + "/s"
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+func TestBuildStats(t *testing.T) {
+	entries := []config.HistoryEntry{
+		{Direction: "upload", State: config.StateOK, Backend: "local", Repo: "/work/art", Size: 1000, DurationMS: 100},
+		{Direction: "upload", State: config.StateOK, Backend: "local", Repo: "/work/art", Size: 3000, DurationMS: 300},
+		{Direction: "upload", State: config.StateOK, Backend: "local", Repo: "/work/art", Size: 500, Deduplicated: true, DurationMS: 5},
+		{Direction: "download", State: config.StateOK, Backend: "sdk", Repo: "/work/app", Size: 2000, DurationMS: 1000},
+		{Direction: "upload", State: config.StateAuthRequired, ErrorCode: "auth_required", Backend: "sdk", Repo: "/work/app"},
+		{Direction: "download", State: config.StateError, ErrorCode: "timeout", Backend: "sdk", Repo: "/work/app"},
+		{Direction: "download", State: config.StateError, ErrorCode: "timeout", Backend: "sdk", Repo: "/work/app"},
+	}
+	r := buildStats(entries, 1)
+
+	if r.Total.Transfers != 7 || r.Total.Succeeded != 4 || r.Total.SuccessRate != 57.1 || r.Total.Bytes != 6000 {
+		t.Fatalf("unexpected totals: %+v", r.Total)
+	}
+	if r.Dedup != (dedupStats{Uploads: 3, Deduplicated: 1, HitRate: 33.3}) {
+		t.Fatalf("unexpected dedup: %+v", r.Dedup)
+	}
+	if len(r.Latency) != 2 || r.Latency[0] != (opLatency{Op: "upload", Count: 2, P50MS: 100, P95MS: 300}) || r.Latency[1].P95MS != 1000 {
+		t.Fatalf("unexpected latency: %+v", r.Latency)
+	}
+	if len(r.Backends) != 2 || r.Backends[0].Name != "sdk" || r.Backends[0].SuccessRate != 25 {
+		t.Fatalf("unexpected backends: %+v", r.Backends)
+	}
+	if local := r.Backends[1]; local.Bytes != 4000 || local.ThroughputBps != 10000 {
+		t.Fatalf("deduplicated uploads should not count towards throughput: %+v", local)
+	}
+	if len(r.TopErrors) != 1 || r.TopErrors[0] != (errorCount{Code: "timeout", Count: 2}) {
+		t.Fatalf("unexpected top errors: %+v", r.TopErrors)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for _, tc := range []struct {
+		p    float64
+		want int64
+	}{{50, 50}, {95, 100}, {1, 10}} {
+		if got := percentile(values, tc.p); got != tc.want {
+			t.Errorf("percentile(%v) = %d, want %d", tc.p, got, tc.want)
+		}
+	}
+	if got := percentile([]int64{7}, 95); got != 7 {
+		t.Errorf("percentile of one value = %d", got)
+	}
+}
+
+func TestCliStatsWindowAndOutput(t *testing.T) {
+	now := time.Now()
+	seedHistory(t,
+		config.HistoryEntry{Time: now.AddDate(0, 0, -30), OID: "old", Direction: "upload", State: config.StateError, ErrorCode: "timeout", Backend: "local"},
+		config.HistoryEntry{Time: now.Add(-time.Hour), OID: "a", Direction: "upload", State: config.StateOK, Backend: "local", Repo: "/work/art", Size: 2048, DurationMS: 1000},
+		config.HistoryEntry{Time: now.Add(-time.Minute), OID: "b", Direction: "download", State: config.StateOK, Backend: "sdk", Repo: "/work/art", Size: 1024, DurationMS: 500},
+	)
+
+	var buf bytes.Buffer
+	if code := cliStats(&buf, []string{"--json", "--backend", "local"}); code != 0 {
+		t.Fatalf("stats exit %d: %s", code, buf.String())
+	}
+	var report statsReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report.Total.Transfers != 1 || len(report.TopErrors) != 0 || len(report.Backends) != 1 {
+		t.Fatalf("the default window and --backend should leave one transfer: %+v", report)
+	}
+
+	buf.Reset()
+	if code := cliStats(&buf, []string{"--since", "60d"}); code != 0 {
+		t.Fatalf("stats exit %d: %s", code, buf.String())
+	}
+	out := buf.String()
+	for _, want := range []string{"Transfers:  3 (2 ok, 66.7%)", "OPERATION", "BACKEND", "/work/art", "timeout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if code := cliStats(&buf, []string{"--since", "1h", "--until", "2h"}); code != 0 || !strings.Contains(buf.String(), "No transfers recorded") {
+		t.Fatalf("expected an empty window, exit %d:\n%s", code, buf.String())
+	}
+	if code := cliStats(&buf, []string{"--since", "soon"}); code != 1 {
+		t.Fatalf("expected an invalid --since to fail, got exit %d", code)
+	}
+}
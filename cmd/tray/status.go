@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"fyne.io/systray"
@@ -53,13 +59,10 @@ func watchLoop() {
 	}
 }
 
-// sessionFilePath returns the path to the proton-drive-cli session file.
+// sessionFilePath returns the path to the active profile's proton-drive-cli
+// session file.
 func sessionFilePath() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-	return filepath.Join(home, ".proton-drive-cli", "session.json")
+	return activeProfile().SessionFilePath()
 }
 
 // applyLoginStatus checks whether a session file exists and updates the
@@ -101,12 +104,22 @@ func maybeRefreshSession() {
 	// Spawn in background — don't block the status poll loop
 	go func() {
 		cmd := exec.Command(driveCLI, "session", "refresh")
+		cmd.Env = append(cmd.Environ(), profileEnv(activeProfile())...)
 		_ = cmd.Run()
 	}()
 }
 
 func applyStatus() {
-	report, err := config.ReadStatus()
+	paused := !config.LoadPrefs().Enabled
+	applyPauseStatus(paused)
+	if paused {
+		systray.SetIcon(iconIdle)
+		systray.SetTemplateIcon(iconIdle, iconIdle)
+		systray.SetTooltip("Proton Git LFS — Paused")
+		return
+	}
+
+	report, err := config.ReadAggregateStatus()
 	if err != nil {
 		systray.SetIcon(iconIdle)
 		systray.SetTemplateIcon(iconIdle, iconIdle)
@@ -144,6 +157,8 @@ func applyStatus() {
 
 	// Update tooltip with detailed context
 	switch {
+	case report.State == config.StateTransferring && report.Summary() != "":
+		systray.SetTooltip("Proton Git LFS — " + report.Summary())
 	case report.State == config.StateTransferring && report.LastOp == "upload":
 		systray.SetTooltip("Proton Git LFS — Uploading…")
 	case report.State == config.StateTransferring && report.LastOp == "download":
@@ -175,6 +190,241 @@ func applyStatus() {
 	}
 }
 
+const statusUsage = `Usage: proton-lfs-cli status [--json] [--watch]
+
+Show session, LFS registration, profile, credential provider, and transfer
+status.
+
+Flags:
+  --json    Print the status as JSON: session, registrations, profile,
+            provider, paused, and the transfer status of all adapters
+  --watch   Keep running and print the status again whenever it changes;
+            with --json, one JSON object per line
+`
+
+// statusWatchInterval is how often status --watch checks for changes.
+const statusWatchInterval = time.Second
+
+// statusSnapshot is everything status reports, and its JSON form.
+type statusSnapshot struct {
+	Session       sessionStatus     `json:"session"`
+	Registrations []lfsRegistration `json:"registrations"`
+	Profile       string            `json:"profile"`
+	Provider      string            `json:"provider"`
+	Paused        bool              `json:"paused"`
+	// Transfer is null until an adapter has reported a transfer.
+	Transfer *config.AggregateStatus `json:"transfer"`
+}
+
+// sessionStatus describes the active profile's proton-drive-cli session.
+type sessionStatus struct {
+	Active     bool       `json:"active"`
+	Path       string     `json:"path,omitempty"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"` // last login or token refresh
+	AgeSeconds int64      `json:"ageSeconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"` // only when proton-drive-cli records one
+}
+
+// collectStatus reads the current status.
+func collectStatus() statusSnapshot {
+	prefs := config.LoadPrefs()
+	profile := activeProfile()
+	snap := statusSnapshot{
+		Session:       readSessionStatus(profile.SessionFilePath()),
+		Registrations: []lfsRegistration{},
+		Profile:       profile.Name,
+		Provider:      profile.CredentialProvider,
+		Paused:        !prefs.Enabled,
+	}
+	if regs, _ := lfsRegistrations(""); regs != nil {
+		snap.Registrations = regs
+	}
+	if report, err := config.ReadAggregateStatus(); err == nil {
+		snap.Transfer = &report
+	}
+	return snap
+}
+
+func readSessionStatus(path string) sessionStatus {
+	status := sessionStatus{Path: path}
+	info, err := os.Stat(path)
+	if path == "" || err != nil {
+		return status
+	}
+	updated := info.ModTime()
+	status.Active = true
+	status.UpdatedAt = &updated
+	status.AgeSeconds = int64(time.Since(updated).Seconds())
+	status.ExpiresAt = readSessionExpiry(path)
+	return status
+}
+
+// readSessionExpiry returns the expiresAt field of a session file, as an
+// RFC 3339 time or Unix seconds or milliseconds. No other field, and no
+// token, is kept.
+func readSessionExpiry(path string) *time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var fields struct {
+		ExpiresAt json.RawMessage `json:"expiresAt"`
+	}
+	if json.Unmarshal(data, &fields) != nil || len(fields.ExpiresAt) == 0 {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(fields.ExpiresAt, &text) == nil {
+		if t, err := time.Parse(time.RFC3339, text); err == nil {
+			return &t
+		}
+		return nil
+	}
+	var n int64
+	if json.Unmarshal(fields.ExpiresAt, &n) != nil || n <= 0 {
+		return nil
+	}
+	t := time.Unix(n, 0)
+	if n > 1e12 {
+		t = time.UnixMilli(n)
+	}
+	return &t
+}
+
+// writeStatus prints snap as text.
+func writeStatus(w io.Writer, snap statusSnapshot) {
+	switch {
+	case !snap.Session.Active:
+		_, _ = fmt.Fprintln(w, "Session:  not connected")
+	case snap.Session.ExpiresAt != nil && time.Until(*snap.Session.ExpiresAt) <= 0:
+		_, _ = fmt.Fprintf(w, "Session:  expired %s\n", relativeTime(*snap.Session.ExpiresAt))
+	default:
+		_, _ = fmt.Fprintf(w, "Session:  logged in (refreshed %s)\n", relativeTime(*snap.Session.UpdatedAt))
+	}
+
+	if len(snap.Registrations) > 0 {
+		scopes := make([]string, len(snap.Registrations))
+		for i, r := range snap.Registrations {
+			scopes[i] = r.String()
+		}
+		_, _ = fmt.Fprintf(w, "LFS:      enabled (%s)\n", strings.Join(scopes, ", "))
+	} else {
+		_, _ = fmt.Fprintln(w, "LFS:      not registered")
+	}
+
+	_, _ = fmt.Fprintf(w, "Profile:  %s\n", snap.Profile)
+	_, _ = fmt.Fprintf(w, "Provider: %s\n", snap.Provider)
+
+	report := snap.Transfer
+	switch {
+	case snap.Paused:
+		_, _ = fmt.Fprintln(w, "Transfer: paused (run 'proton-lfs-cli resume' to continue)")
+	case report == nil:
+		_, _ = fmt.Fprintln(w, "Transfer: no data")
+	case report.State == config.StateTransferring:
+		if summary := report.Summary(); summary != "" {
+			_, _ = fmt.Fprintf(w, "Transfer: %s\n", summary)
+		} else {
+			_, _ = fmt.Fprintf(w, "Transfer: %s in progress\n", report.LastOp)
+		}
+	case report.State == config.StateError:
+		msg := "failed"
+		if report.Error != "" {
+			msg = report.Error
+		}
+		_, _ = fmt.Fprintf(w, "Transfer: %s %s (%s)\n", report.LastOp, relativeTime(report.Timestamp), msg)
+	case report.State == config.StateOK:
+		_, _ = fmt.Fprintf(w, "Transfer: %s %s (ok)\n", report.LastOp, relativeTime(report.Timestamp))
+	case report.State == config.StateRateLimited, report.State == config.StateAuthRequired, report.State == config.StateCaptcha:
+		msg := report.State
+		if report.ErrorDetail != "" {
+			msg += ": " + report.ErrorDetail
+		}
+		_, _ = fmt.Fprintf(w, "Transfer: %s %s (%s)\n", report.LastOp, relativeTime(report.Timestamp), msg)
+	default:
+		_, _ = fmt.Fprintln(w, "Transfer: idle")
+	}
+}
+
+// renderStatus returns snap as printed by status, and the key that decides
+// whether --watch prints it again. The session age is left out of the JSON
+// key, since it changes every second.
+func renderStatus(snap statusSnapshot, asJSON bool) (out, key string) {
+	if !asJSON {
+		var buf bytes.Buffer
+		writeStatus(&buf, snap)
+		return buf.String(), buf.String()
+	}
+	data, _ := json.Marshal(snap)
+	snap.Session.AgeSeconds = 0
+	keyData, _ := json.Marshal(snap)
+	return string(data) + "\n", string(keyData)
+}
+
+// watchStatus prints the status whenever it changes until stop is closed.
+// Text output redraws the terminal; JSON output appends a line.
+func watchStatus(w io.Writer, asJSON bool, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		out, key := renderStatus(collectStatus(), asJSON)
+		if key != last {
+			if !asJSON {
+				_, _ = fmt.Fprint(w, "\033[H\033[2J")
+			}
+			_, _ = fmt.Fprint(w, out)
+			last = key
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// cliStatus prints session, LFS, profile, provider, and transfer status.
+func cliStatus(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { _, _ = fmt.Fprint(w, statusUsage) }
+	asJSON := fs.Bool("json", false, "print JSON")
+	watch := fs.Bool("watch", false, "print changes until interrupted")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(w, "unexpected argument: %s\n", fs.Arg(0))
+		return 1
+	}
+
+	if *watch {
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			close(stop)
+		}()
+		watchStatus(w, *asJSON, statusWatchInterval, stop)
+		return 0
+	}
+
+	snap := collectStatus()
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(snap)
+		return 0
+	}
+	writeStatus(w, snap)
+	return 0
+}
+
 func relativeTime(t time.Time) string {
 	d := time.Since(t)
 	switch {
@@ -4,26 +4,24 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+
+	"proton-lfs-cli/internal/logging"
 )
 
+// trayLogName names the tray's files in the log directory: tray-<date>.log.
+const trayLogName = "tray"
+
 // trayLog is the package-level logger for the tray app. It writes to both
-// stderr and ~/.proton-lfs/tray.log.
+// stderr and ~/.proton-lfs/logs/tray-<date>.log, which is rotated and
+// redacted like the adapter's log.
 var trayLog *log.Logger
 
 func initTrayLog() {
 	writers := []io.Writer{os.Stderr}
 
-	home, err := os.UserHomeDir()
-	if err == nil {
-		dir := filepath.Join(home, ".proton-lfs")
-		_ = os.MkdirAll(dir, 0o700)
-		f, err := os.OpenFile(filepath.Join(dir, "tray.log"),
-			os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
-		if err == nil {
-			writers = append(writers, f)
-		}
+	if f, err := logging.OpenAppLog(trayLogName); err == nil {
+		writers = append(writers, f)
 	}
 
-	trayLog = log.New(io.MultiWriter(writers...), "[tray] ", log.LstdFlags)
+	trayLog = log.New(logging.NewRedactingWriter(io.MultiWriter(writers...)), "[tray] ", log.LstdFlags)
 }
@@ -2,9 +2,9 @@ module proton-lfs-cli
 
 go 1.25
 
-require fyne.io/systray v1.12.0
-
 require (
-	github.com/godbus/dbus/v5 v5.2.2 // indirect
-	golang.org/x/sys v0.41.0 // indirect
+	fyne.io/systray v1.12.0
+	golang.org/x/sys v0.41.0
 )
+
+require github.com/godbus/dbus/v5 v5.2.2 // indirect
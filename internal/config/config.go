@@ -4,10 +4,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Backend modes
@@ -22,6 +24,18 @@ const (
 	CredentialProviderGitCredential = "git-credential"
 )
 
+// Bridge modes
+const (
+	BridgeModeSubprocess = "subprocess"
+	BridgeModeDaemon     = "daemon"
+)
+
+// Pause modes: what the adapter does at init while transfers are paused.
+const (
+	PauseModeFail = "fail" // fail init at once
+	PauseModeWait = "wait" // block until transfers are resumed
+)
+
 // ProtonCredentialHost is the host used for git credential fill/approve and
 // Proton Pass URL matching. Must match PROTON_CREDENTIAL_HOST in proton-drive-cli.
 const ProtonCredentialHost = "proton.me"
@@ -31,6 +45,18 @@ const (
 	DefaultDriveCLIBin        = "submodules/proton-drive-cli/dist/index.js"
 	DefaultStorageBase        = "LFS"
 	DefaultCredentialProvider = CredentialProviderPassCLI
+	DefaultBridgeMode         = BridgeModeSubprocess
+	DefaultCacheMaxMB         = 10240
+	DefaultChunkThresholdMB   = 2048
+	DefaultChunkSizeMB        = 256
+	DefaultBridgeTimeout      = 5 * time.Minute
+	DefaultBridgeConcurrency  = 10
+	DefaultBridgeQueueTimeout = 10 * time.Minute
+	DefaultPauseMode          = PauseModeFail
+	DefaultLogLevel           = "info"
+	DefaultLogMaxMB           = 10
+	DefaultLogMaxAgeDays      = 14
+	DefaultMetricsAddr        = "127.0.0.1:9464" // for metrics serve; the tray listens only when metrics-addr is set
 )
 
 // Environment variable names
@@ -44,8 +70,35 @@ const (
 	EnvLocalStoreDir      = "PROTON_LFS_LOCAL_STORE_DIR"
 	EnvCredentialProvider = "PROTON_CREDENTIAL_PROVIDER"
 	EnvStatusFile         = "PROTON_LFS_STATUS_FILE"
+	EnvBridgeMode         = "PROTON_LFS_BRIDGE_MODE"
+	EnvRetryAttempts      = "PROTON_LFS_RETRY_ATTEMPTS"
+	EnvRetryMaxElapsed    = "PROTON_LFS_RETRY_MAX_ELAPSED"
+	EnvCache              = "PROTON_LFS_CACHE"
+	EnvCacheDir           = "PROTON_LFS_CACHE_DIR"
+	EnvCacheMaxMB         = "PROTON_LFS_CACHE_MAX_MB"
+	EnvChunkThresholdMB   = "PROTON_LFS_CHUNK_THRESHOLD_MB"
+	EnvChunkSizeMB        = "PROTON_LFS_CHUNK_SIZE_MB"
+	EnvProfile            = "PROTON_LFS_PROFILE"
+	EnvTimeout            = "PROTON_LFS_TIMEOUT"
+	EnvConcurrency        = "PROTON_LFS_CONCURRENCY"
+	EnvQueueTimeout       = "PROTON_LFS_QUEUE_TIMEOUT"
+	EnvPauseMode          = "PROTON_LFS_PAUSE_MODE"
+	EnvLogLevel           = "PROTON_LFS_LOG_LEVEL"
+	EnvLogDir             = "PROTON_LFS_LOG_DIR"
+	EnvLogMaxMB           = "PROTON_LFS_LOG_MAX_MB"
+	EnvLogMaxAgeDays      = "PROTON_LFS_LOG_MAX_AGE_DAYS"
+	EnvHistoryFile        = "PROTON_LFS_HISTORY_FILE"
+	EnvMetricsSocket      = "PROTON_LFS_METRICS_SOCKET"
+	EnvMetricsAddr        = "PROTON_LFS_METRICS_ADDR"
 )
 
+// GitConfigSection is the git config section holding adapter settings, as in
+// lfs.proton.backend. It is read from git config and from .lfsconfig.
+const GitConfigSection = "lfs.proton"
+
+// LFSConfigFileName is the repository-committed git-lfs config file.
+const LFSConfigFileName = ".lfsconfig"
+
 // AppDir is the base directory for Proton LFS runtime files.
 const AppDir = ".proton-lfs"
 
@@ -55,6 +108,27 @@ const StatusFileName = "status.json"
 // ConfigFileName is the filename for user preferences inside AppDir.
 const ConfigFileName = "config.json"
 
+// CacheDirName is the object cache directory inside AppDir.
+const CacheDirName = "cache"
+
+// ResumeDirName is the directory inside AppDir holding the resume journal.
+const ResumeDirName = "resume"
+
+// LogDirName is the directory inside AppDir holding the adapter and tray logs.
+const LogDirName = "logs"
+
+// PrefsLockDirName is the directory inside AppDir holding the lock that
+// serialises updates of the preferences file.
+const PrefsLockDirName = "prefs-lock"
+
+// BridgeSlotsDirName is the directory inside AppDir holding the lock files
+// that limit proton-drive-cli commands across all adapters.
+const BridgeSlotsDirName = "bridge-slots"
+
+// MetricsSocketName is the Unix socket inside AppDir on which the metrics
+// collector accepts samples from adapters.
+const MetricsSocketName = "metrics.sock"
+
 // AppDirPath returns the absolute path to ~/.proton-lfs.
 func AppDirPath() string {
 	home, err := os.UserHomeDir()
@@ -77,6 +151,40 @@ func PrefsFilePath() string {
 	return filepath.Join(AppDirPath(), ConfigFileName)
 }
 
+// CacheDirPath returns the default object cache directory, ~/.proton-lfs/cache.
+func CacheDirPath() string {
+	return filepath.Join(AppDirPath(), CacheDirName)
+}
+
+// ResumeDirPath returns the resume journal directory, ~/.proton-lfs/resume.
+func ResumeDirPath() string {
+	return filepath.Join(AppDirPath(), ResumeDirName)
+}
+
+// LogDirPath returns the log directory, ~/.proton-lfs/logs, respecting
+// EnvLogDir.
+func LogDirPath() string {
+	if p := EnvTrim(EnvLogDir); p != "" {
+		return p
+	}
+	return filepath.Join(AppDirPath(), LogDirName)
+}
+
+// BridgeSlotsDirPath returns the bridge slot directory,
+// ~/.proton-lfs/bridge-slots.
+func BridgeSlotsDirPath() string {
+	return filepath.Join(AppDirPath(), BridgeSlotsDirName)
+}
+
+// MetricsSocketPath returns the metrics collector socket,
+// ~/.proton-lfs/metrics.sock, respecting EnvMetricsSocket.
+func MetricsSocketPath() string {
+	if p := EnvTrim(EnvMetricsSocket); p != "" {
+		return p
+	}
+	return filepath.Join(AppDirPath(), MetricsSocketName)
+}
+
 // EnvTrim reads an environment variable and trims whitespace.
 func EnvTrim(key string) string {
 	return strings.TrimSpace(os.Getenv(key))
@@ -103,3 +211,45 @@ func EnvBoolOrDefault(key string, fallback bool) bool {
 	}
 	return parsed
 }
+
+// EnvIntOrDefault reads an environment variable as an int; returns fallback
+// if the variable is empty or cannot be parsed.
+func EnvIntOrDefault(key string, fallback int) int {
+	value := EnvTrim(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// EnvDurationOrDefault reads an environment variable as a time.Duration
+// (e.g. "90s", "2m"); returns fallback if empty or unparsable.
+func EnvDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	value := EnvTrim(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// FormatBytes renders a byte count with a binary unit suffix, e.g. 1.5 MiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
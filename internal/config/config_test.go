@@ -1,8 +1,15 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEnvTrim(t *testing.T) {
@@ -193,3 +200,380 @@ func TestPrefsLoadCorruptReturnsDefaults(t *testing.T) {
 		t.Fatalf("corrupt file should return defaults, got %q", got.CredentialProvider)
 	}
 }
+
+func TestEnvIntOrDefault(t *testing.T) {
+	t.Setenv("TEST_INT_VALID", "7")
+	t.Setenv("TEST_INT_INVALID", "seven")
+	if got := EnvIntOrDefault("TEST_INT_VALID", 1); got != 7 {
+		t.Fatalf("EnvIntOrDefault(valid) = %d, want 7", got)
+	}
+	if got := EnvIntOrDefault("TEST_INT_INVALID", 1); got != 1 {
+		t.Fatalf("EnvIntOrDefault(invalid) = %d, want fallback 1", got)
+	}
+	if got := EnvIntOrDefault("NONEXISTENT_VAR_XYZ", 3); got != 3 {
+		t.Fatalf("EnvIntOrDefault(missing) = %d, want fallback 3", got)
+	}
+}
+
+func TestEnvDurationOrDefault(t *testing.T) {
+	t.Setenv("TEST_DURATION_VALID", "90s")
+	t.Setenv("TEST_DURATION_INVALID", "soon")
+	if got := EnvDurationOrDefault("TEST_DURATION_VALID", time.Second); got != 90*time.Second {
+		t.Fatalf("EnvDurationOrDefault(valid) = %s, want 90s", got)
+	}
+	if got := EnvDurationOrDefault("TEST_DURATION_INVALID", time.Second); got != time.Second {
+		t.Fatalf("EnvDurationOrDefault(invalid) = %s, want fallback", got)
+	}
+}
+
+func TestRouteMatching(t *testing.T) {
+	routes := []Route{
+		{Remote: "client", URL: "https://github.com/acme/*", StorageBase: "LFS/acme-client"},
+		{URL: "*github.com*acme/*", StorageBase: "LFS/acme"},
+		{Remote: "backup", Backend: "local"},
+		{StorageBase: "never"},
+	}
+	cases := []struct {
+		name, url string
+		want      string
+	}{
+		{"client", "https://github.com/acme/app.git", "LFS/acme-client"},
+		{"origin", "git@github.com:acme/app.git", "LFS/acme"},
+		{"client", "https://example.com/app.git", ""},
+		{"backup", "", "local"},
+		{"origin", "https://example.com/app.git", ""},
+	}
+	for _, tc := range cases {
+		route := MatchRoute(routes, tc.name, tc.url)
+		got := ""
+		if route != nil {
+			got = route.StorageBase + route.Backend
+		}
+		if got != tc.want {
+			t.Errorf("MatchRoute(%q, %q) = %q, want %q", tc.name, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestPrefsRoutesRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prefs := DefaultPreferences()
+	prefs.Routes = []Route{{Remote: "origin", StorageBase: "LFS/work"}}
+	if err := SavePrefs(prefs); err != nil {
+		t.Fatalf("SavePrefs: %v", err)
+	}
+	got := LoadPrefs()
+	if len(got.Routes) != 1 || got.Routes[0].StorageBase != "LFS/work" {
+		t.Fatalf("routes not preserved: %+v", got.Routes)
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	t.Setenv(EnvDriveCLISessionDir, "")
+	prefs := Preferences{
+		CredentialProvider: CredentialProviderPassCLI,
+		Profiles: []Profile{
+			{Name: "work", CredentialProvider: CredentialProviderGitCredential, Account: "me@work.example"},
+			{Name: "personal", SessionDir: "/tmp/personal"},
+		},
+		ActiveProfile: "work",
+	}
+
+	active, err := prefs.ResolveProfile("")
+	if err != nil || active.Name != "work" || active.CredentialProvider != CredentialProviderGitCredential {
+		t.Fatalf("ResolveProfile(\"\") = %+v, %v", active, err)
+	}
+	if got, want := active.SessionFilePath(), "/home/tester/.proton-drive-cli/profiles/work/session.json"; got != want {
+		t.Fatalf("SessionFilePath() = %q, want %q", got, want)
+	}
+
+	personal, _ := prefs.ResolveProfile("personal")
+	if personal.CredentialProvider != CredentialProviderPassCLI || personal.SessionDirPath() != "/tmp/personal" {
+		t.Fatalf("personal profile should inherit the provider and keep its session dir: %+v", personal)
+	}
+
+	def, _ := prefs.ResolveProfile(DefaultProfileName)
+	if got, want := def.SessionDirPath(), "/home/tester/.proton-drive-cli"; got != want {
+		t.Fatalf("default SessionDirPath() = %q, want %q", got, want)
+	}
+	t.Setenv(EnvDriveCLISessionDir, "/srv/session")
+	if got := def.SessionDirPath(); got != "/srv/session" {
+		t.Fatalf("default profile should honour %s, got %q", EnvDriveCLISessionDir, got)
+	}
+
+	if _, err := prefs.ResolveProfile("missing"); err == nil {
+		t.Fatal("expected an unknown profile to be an error")
+	}
+}
+
+func TestSetAndRemoveProfile(t *testing.T) {
+	prefs := DefaultPreferences()
+	for _, name := range []string{"", DefaultProfileName, "has space", "-dash"} {
+		if err := prefs.SetProfile(Profile{Name: name}); err == nil {
+			t.Errorf("SetProfile(%q) should fail", name)
+		}
+	}
+	if err := prefs.SetProfile(Profile{Name: "work", Account: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := prefs.SetProfile(Profile{Name: "work", Account: "b@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs.Profiles) != 1 || prefs.Profiles[0].Account != "b@example.com" {
+		t.Fatalf("SetProfile should replace by name: %+v", prefs.Profiles)
+	}
+	prefs.ActiveProfile = "work"
+	if err := prefs.RemoveProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs.Profiles) != 0 || prefs.ActiveProfile != "" {
+		t.Fatalf("RemoveProfile should clear the profile and its selection: %+v", prefs)
+	}
+}
+
+func TestPrefsMigratesLegacyFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writePrefsFile(t, `{"credentialProvider":" Git-Credential ","enabled":true}`)
+
+	got, err := ReadPrefs()
+	if err != nil {
+		t.Fatalf("ReadPrefs: %v", err)
+	}
+	if got.Version != PrefsVersion || got.CredentialProvider != CredentialProviderGitCredential {
+		t.Fatalf("legacy file not migrated: %+v", got)
+	}
+
+	writePrefsFile(t, `{"credentialProvider":"keychain"}`)
+	got = LoadPrefs()
+	if got.CredentialProvider != DefaultCredentialProvider {
+		t.Fatalf("unknown legacy provider should fall back to the default, got %q", got.CredentialProvider)
+	}
+	if !got.Enabled {
+		t.Fatal("a file without enabled must not pause transfers")
+	}
+}
+
+func TestPrefsToleratesBadAndUnknownFields(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writePrefsFile(t, `{"version":3,"credentialProvider":"git-credential","routes":"not-a-list","futureSetting":{"x":1}}`)
+
+	got := LoadPrefs()
+	if got.CredentialProvider != CredentialProviderGitCredential {
+		t.Fatalf("valid fields must survive a bad one, got %+v", got)
+	}
+	if got.Routes != nil || got.Version != 3 {
+		t.Fatalf("expected the bad field dropped and the newer version kept, got %+v", got)
+	}
+}
+
+func TestSavePrefsKeepsNewerFields(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writePrefsFile(t, `{"version":3,"credentialProvider":"pass-cli","futureSetting":{"x":1}}`)
+
+	prefs := LoadPrefs()
+	prefs.Enabled = false
+	if err := SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(PrefsFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Version       int
+		Enabled       bool
+		FutureSetting struct{ X int }
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.FutureSetting.X != 1 || saved.Version != 3 || saved.Enabled {
+		t.Fatalf("saving must keep the newer file's fields and version:\n%s", data)
+	}
+}
+
+func TestUpdatePrefsSerialisesConcurrentUpdates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := UpdatePrefs(func(p *Preferences) error {
+				return p.SetProfile(Profile{Name: fmt.Sprintf("p%d", i)})
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := LoadPrefs().Profiles; len(got) != 8 {
+		t.Fatalf("expected every update to be kept, got %+v", got)
+	}
+
+	// A failed update saves nothing, and a corrupt file is not replaced.
+	if _, err := UpdatePrefs(func(p *Preferences) error {
+		p.Profiles = nil
+		return errors.New("rejected")
+	}); err == nil || len(LoadPrefs().Profiles) != 8 {
+		t.Fatalf("a failed update must not be saved: %v", err)
+	}
+	writePrefsFile(t, "{corrupt")
+	if _, err := UpdatePrefs(func(p *Preferences) error { return nil }); err == nil {
+		t.Fatal("expected a corrupt file to be reported")
+	}
+	if data, _ := os.ReadFile(PrefsFilePath()); string(data) != "{corrupt" {
+		t.Fatalf("corrupt file was replaced: %s", data)
+	}
+}
+
+func TestSavePrefsStampsVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SavePrefs(Preferences{CredentialProvider: CredentialProviderPassCLI}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(PrefsFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version": 2`) {
+		t.Fatalf("expected the schema version in the file:\n%s", data)
+	}
+}
+
+func TestSettingSetNormalisesAndRoundTrips(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prefs := Preferences{Profiles: []Profile{{Name: "work"}}}
+	for key, value := range map[string]string{
+		"backend":         " SDK ",
+		"local-store-dir": "/srv/lfs/../store",
+		"storage-base":    "/LFS/acme/",
+		"profile":         "work",
+		"timeout":         "600s",
+		"concurrency":     "04",
+		"cache-max-mb":    "512",
+		"metrics-addr":    "localhost:9464",
+	} {
+		s, ok := LookupSetting(key)
+		if !ok {
+			t.Fatalf("no setting %q", key)
+		}
+		if err := s.Set(&prefs, value); err != nil {
+			t.Fatalf("Set(%s, %q): %v", key, value, err)
+		}
+	}
+	if err := SavePrefs(prefs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPrefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"backend": "sdk", "local-store-dir": "/srv/store", "storage-base": "LFS/acme",
+		"profile": "work", "timeout": "10m0s", "concurrency": "4", "cache-max-mb": "512",
+		"pause-mode": "", "notifications": "", "metrics-addr": "localhost:9464",
+	}
+	for key, value := range want {
+		s, _ := LookupSetting(key)
+		if s.Get(got) != value {
+			t.Errorf("%s = %q, want %q", key, s.Get(got), value)
+		}
+	}
+
+	s, _ := LookupSetting("concurrency")
+	if err := s.Set(&got, ""); err != nil || got.Concurrency != 0 {
+		t.Fatalf("unset concurrency = %d (%v), want 0", got.Concurrency, err)
+	}
+	if err := s.Set(&got, "many"); err == nil || !strings.Contains(err.Error(), "concurrency") {
+		t.Fatalf("expected an error naming the key, got %v", err)
+	}
+	addr, _ := LookupSetting("metrics-addr")
+	for _, bad := range []string{"9464", "localhost:http", "localhost:0", "localhost:70000"} {
+		if err := addr.Set(&got, bad); err == nil {
+			t.Errorf("metrics-addr %q should be rejected", bad)
+		}
+	}
+}
+
+func writePrefsFile(t *testing.T, content string) {
+	t.Helper()
+	if err := os.MkdirAll(AppDirPath(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(PrefsFilePath(), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryAppendAndFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	t.Setenv(EnvHistoryFile, path)
+	if entries, err := ReadHistory(HistoryFilter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("a missing journal should be empty, got %v, %v", entries, err)
+	}
+
+	day := time.Date(2026, 4, 2, 10, 0, 0, 0, time.UTC)
+	for _, e := range []HistoryEntry{
+		{Time: day, OID: "abc1", Direction: "upload", State: StateOK, Repo: "/r1"},
+		{Time: day.Add(time.Hour), OID: "abc2", Direction: "upload", State: StateRateLimited, Repo: "/r1"},
+		{Time: day.Add(24 * time.Hour), OID: "def3", Direction: "download", State: StateOK, Repo: "/r2"},
+	} {
+		if err := AppendHistory(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn or foreign line does not hide the rest.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("{not json\n")
+	_ = f.Close()
+
+	for _, tc := range []struct {
+		filter HistoryFilter
+		want   int
+	}{
+		{HistoryFilter{}, 3},
+		{HistoryFilter{Repo: "/r1"}, 2},
+		{HistoryFilter{State: "failed"}, 1},
+		{HistoryFilter{State: StateOK, Direction: "download"}, 1},
+		{HistoryFilter{OID: "ABC"}, 2},
+		{HistoryFilter{Since: day.Add(30 * time.Minute), Until: day.Add(24 * time.Hour)}, 1},
+	} {
+		entries, err := ReadHistory(tc.filter)
+		if err != nil || len(entries) != tc.want {
+			t.Errorf("ReadHistory(%+v) = %d entries (%v), want %d", tc.filter, len(entries), err, tc.want)
+		}
+	}
+}
+
+func TestHistoryRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	t.Setenv(EnvHistoryFile, path)
+	if err := os.WriteFile(path, []byte(`{"oid":"old","state":"ok"}`+"\n"+strings.Repeat(" ", historyMaxBytes)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendHistory(HistoryEntry{OID: "new", State: StateOK}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(rotatedHistoryPath(path)); err != nil {
+		t.Fatalf("expected the full journal to be moved aside: %v", err)
+	}
+	entries, err := ReadHistory(HistoryFilter{})
+	if err != nil || len(entries) != 2 || entries[0].OID != "old" || entries[1].OID != "new" {
+		t.Fatalf("expected both journals, oldest first, got %+v, %v", entries, err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	out, err := Probe("git", "--version")
+	if err != nil || !strings.HasPrefix(out, "git version") {
+		t.Fatalf("Probe(git --version) = %q, %v", out, err)
+	}
+	if _, err := Probe("git", "no-such-command"); err == nil || !strings.Contains(err.Error(), "no-such-command") || strings.Contains(err.Error(), "\n") {
+		t.Fatalf("expected the first stderr line in the error, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DoctorProbeTimeout bounds each external command that a doctor check runs
+// with Probe.
+const DoctorProbeTimeout = 15 * time.Second
+
+// Doctor check states.
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+	CheckSkip = "skip"
+)
+
+// DoctorCheck is the outcome of one environment check run by doctor. Fix
+// tells the user how to resolve a warning or failure.
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// DoctorReport is the JSON form of a doctor run, as attached to support
+// tickets.
+type DoctorReport struct {
+	Version string        `json:"version,omitempty"`
+	OS      string        `json:"os,omitempty"`
+	Backend string        `json:"backend,omitempty"`
+	Checks  []DoctorCheck `json:"checks"`
+	Healthy bool          `json:"healthy"` // no check failed
+}
+
+// NewDoctorReport returns the report of checks.
+func NewDoctorReport(checks []DoctorCheck) DoctorReport {
+	report := DoctorReport{Checks: checks, Healthy: true}
+	for _, c := range checks {
+		if c.Status == CheckFail {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// WriteText prints one line per check, followed by its fix.
+func (r DoctorReport) WriteText(w io.Writer) {
+	width := 0
+	for _, c := range r.Checks {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range r.Checks {
+		_, _ = fmt.Fprintf(w, "%-4s  %-*s  %s\n", c.Status, width, c.Name, c.Detail)
+		if c.Fix != "" && c.Status != CheckOK {
+			_, _ = fmt.Fprintf(w, "      %s  fix: %s\n", strings.Repeat(" ", width), c.Fix)
+		}
+	}
+}
+
+// Probe runs name with args for a doctor check and returns its trimmed
+// output. When the command fails, the error carries the first line of its
+// stderr.
+func Probe(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DoctorProbeTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(msg))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
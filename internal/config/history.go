@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HistoryFileName is the transfer history journal inside AppDir.
+const HistoryFileName = "history.jsonl"
+
+// historyMaxBytes is the journal size at which it is moved aside to
+// history.1.jsonl, replacing the previous one, and a new journal begins.
+const historyMaxBytes = 32 << 20
+
+// HistoryEntry is one finished transfer in the history journal.
+type HistoryEntry struct {
+	Time         time.Time `json:"time"`                   // when the transfer finished
+	OID          string    `json:"oid"`                    // object ID
+	Size         int64     `json:"size,omitempty"`         // object size in bytes, when known
+	Direction    string    `json:"direction"`              // upload or download
+	State        string    `json:"state"`                  // ok, or the error state (see StatusReport.State)
+	Repo         string    `json:"repo,omitempty"`         // repository the adapter ran in
+	Remote       string    `json:"remote,omitempty"`       // git remote announced at init
+	Backend      string    `json:"backend,omitempty"`      // local or sdk
+	DurationMS   int64     `json:"durationMs"`             // time from request to completion
+	Retries      int       `json:"retries,omitempty"`      // retries before the outcome
+	Deduplicated bool      `json:"deduplicated,omitempty"` // upload skipped, the object was already stored
+	ErrorCode    string    `json:"errorCode,omitempty"`    // machine-readable error code
+	Error        string    `json:"error,omitempty"`        // error message returned to git-lfs
+}
+
+// HistoryFilePath returns the path to the history journal, respecting
+// EnvHistoryFile.
+func HistoryFilePath() string {
+	if p := EnvTrim(EnvHistoryFile); p != "" {
+		return p
+	}
+	return filepath.Join(AppDirPath(), HistoryFileName)
+}
+
+// rotatedHistoryPath returns where a full journal is moved aside.
+func rotatedHistoryPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".1" + filepath.Ext(path)
+}
+
+// AppendHistory appends entry to the history journal as one JSON line. Each
+// line is a single append, so concurrent adapter processes do not interleave.
+func AppendHistory(entry HistoryEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal history entry: %w", err)
+	}
+
+	path := HistoryFilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() >= historyMaxBytes {
+		_ = os.Rename(path, rotatedHistoryPath(path))
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// HistoryFilter selects history entries. Zero fields match everything.
+type HistoryFilter struct {
+	Repo      string    // repository path, matched exactly
+	Since     time.Time // entries at or after
+	Until     time.Time // entries before
+	State     string    // ok, failed (any error state), or an exact state
+	OID       string    // OID prefix
+	Direction string    // upload or download
+}
+
+// Match reports whether entry passes the filter.
+func (f HistoryFilter) Match(entry HistoryEntry) bool {
+	switch {
+	case f.Repo != "" && entry.Repo != f.Repo:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	case f.OID != "" && !strings.HasPrefix(entry.OID, strings.ToLower(f.OID)):
+		return false
+	case f.Direction != "" && entry.Direction != f.Direction:
+		return false
+	}
+	switch f.State {
+	case "":
+		return true
+	case "failed":
+		return entry.State != StateOK
+	default:
+		return entry.State == f.State
+	}
+}
+
+// ReadHistory returns the journal entries that match filter, oldest first,
+// including the rotated journal. Lines that do not parse are skipped. A
+// missing journal is an empty history.
+func ReadHistory(filter HistoryFilter) ([]HistoryEntry, error) {
+	path := HistoryFilePath()
+	var entries []HistoryEntry
+	for _, p := range []string{rotatedHistoryPath(path), path} {
+		data, err := os.ReadFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read history: %w", err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if filter.Match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, nil
+}
@@ -1,51 +1,197 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
+
+	"proton-lfs-cli/internal/slots"
 )
 
+// prefsLockTimeout bounds how long UpdatePrefs waits for another process
+// that is updating the preferences.
+const prefsLockTimeout = 10 * time.Second
+
+// PrefsVersion is the schema version of the preferences file. Files written
+// before versioning have no version field and are treated as version 1.
+const PrefsVersion = 2
+
 // Preferences stores user-facing settings managed by the tray application.
+// The adapter uses them where no flag, environment variable or git config
+// sets a value.
 type Preferences struct {
+	Version            int    `json:"version"`
 	CredentialProvider string `json:"credentialProvider"`
-	Enabled            bool   `json:"enabled"`
+	// Enabled is false while the user has paused transfers.
+	Enabled bool `json:"enabled"`
+	// StorageBase is the Proton Drive folder for LFS objects; empty means
+	// DefaultStorageBase.
+	StorageBase string    `json:"storageBase,omitempty"`
+	Routes      []Route   `json:"routes,omitempty"`
+	Profiles    []Profile `json:"profiles,omitempty"`
+	// ActiveProfile is used where no repo or flag selects a profile; empty
+	// means the default profile.
+	ActiveProfile string `json:"activeProfile,omitempty"`
+
+	// The settings below are managed with `proton-lfs-cli config set`;
+	// empty or zero means unset. See Settings.
+	Backend       string `json:"backend,omitempty"`
+	LocalStoreDir string `json:"localStoreDir,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+	Concurrency   int    `json:"concurrency,omitempty"`
+	QueueTimeout  string `json:"queueTimeout,omitempty"`
+	CacheMaxMB    int    `json:"cacheMaxMB,omitempty"`
+	PauseMode     string `json:"pauseMode,omitempty"`
+	LogLevel      string `json:"logLevel,omitempty"`
+	Notifications string `json:"notifications,omitempty"`
+	MetricsAddr   string `json:"metricsAddr,omitempty"`
+
+	// unknown holds the keys of the file that this version does not know,
+	// such as settings of a newer version, so that saving keeps them.
+	unknown map[string]json.RawMessage
 }
 
+// prefsKeys are the JSON keys of Preferences.
+var prefsKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeFor[Preferences]()
+	for i := range t.NumField() {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
 // DefaultPreferences returns the default preferences.
 func DefaultPreferences() Preferences {
 	return Preferences{
+		Version:            PrefsVersion,
 		CredentialProvider: DefaultCredentialProvider,
 		Enabled:            true,
 	}
 }
 
+// prefsMigrations[v] upgrades a decoded preferences file from version v to
+// v+1 in place.
+var prefsMigrations = map[int]func(raw map[string]json.RawMessage){
+	// Version 1 files were edited by hand as often as by the tray. Normalise
+	// the provider, which the adapter now reads, and drop values it would
+	// reject so that they fall back to the default.
+	1: func(raw map[string]json.RawMessage) {
+		var provider string
+		if json.Unmarshal(raw["credentialProvider"], &provider) != nil {
+			delete(raw, "credentialProvider")
+			return
+		}
+		switch provider = strings.ToLower(strings.TrimSpace(provider)); provider {
+		case CredentialProviderPassCLI, CredentialProviderGitCredential:
+			raw["credentialProvider"], _ = json.Marshal(provider)
+		default:
+			delete(raw, "credentialProvider")
+		}
+	},
+}
+
+// ReadPrefs reads and migrates the preferences file. Unlike LoadPrefs it
+// reports a missing or corrupt file, and leaves unset fields empty.
+func ReadPrefs() (Preferences, error) {
+	data, err := os.ReadFile(PrefsFilePath())
+	if err != nil {
+		return Preferences{}, err
+	}
+	return decodePrefs(data)
+}
+
+// decodePrefs migrates a preferences file to PrefsVersion and decodes it.
+// A file from a newer version is decoded as far as this version understands
+// it; the keys it does not know are kept for SavePrefs. A field of the wrong
+// type is dropped instead of failing the whole file.
+func decodePrefs(data []byte) (Preferences, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Preferences{}, fmt.Errorf("parse prefs: %w", err)
+	}
+	version := 1
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil || version < 1 {
+			version = 1
+		}
+	}
+	for ; version < PrefsVersion; version++ {
+		if migrate := prefsMigrations[version]; migrate != nil {
+			migrate(raw)
+		}
+	}
+
+	// A file that never recorded a pause is not paused.
+	prefs := Preferences{Enabled: true}
+	for key, value := range raw {
+		if !prefsKeys[key] {
+			if prefs.unknown == nil {
+				prefs.unknown = make(map[string]json.RawMessage)
+			}
+			prefs.unknown[key] = value
+			continue
+		}
+		field, err := json.Marshal(map[string]json.RawMessage{key: value})
+		if err != nil {
+			continue
+		}
+		_ = json.Unmarshal(field, &prefs)
+	}
+	prefs.Version = version
+	return prefs, nil
+}
+
 // LoadPrefs reads preferences from the config file.
 // Returns defaults if the file is missing or unreadable.
 func LoadPrefs() Preferences {
-	path := PrefsFilePath()
-	data, err := os.ReadFile(path)
+	prefs, err := ReadPrefs()
 	if err != nil {
 		return DefaultPreferences()
 	}
-	var prefs Preferences
-	if err := json.Unmarshal(data, &prefs); err != nil {
-		return DefaultPreferences()
-	}
 	if prefs.CredentialProvider == "" {
 		prefs.CredentialProvider = DefaultCredentialProvider
 	}
 	return prefs
 }
 
-// SavePrefs atomically writes preferences to the config file.
+// SavePrefs atomically writes preferences to the config file, stamped with
+// the current schema version. Keys that prefs was read with but this version
+// does not know are written back unchanged.
 func SavePrefs(prefs Preferences) error {
-	data, err := json.MarshalIndent(prefs, "", "  ")
+	if prefs.Version < PrefsVersion {
+		prefs.Version = PrefsVersion
+	}
+	data, err := json.Marshal(prefs)
 	if err != nil {
 		return fmt.Errorf("marshal prefs: %w", err)
 	}
+	if len(prefs.unknown) > 0 {
+		var merged map[string]json.RawMessage
+		if err := json.Unmarshal(data, &merged); err != nil {
+			return fmt.Errorf("marshal prefs: %w", err)
+		}
+		for key, value := range prefs.unknown {
+			merged[key] = value
+		}
+		if data, err = json.Marshal(merged); err != nil {
+			return fmt.Errorf("marshal prefs: %w", err)
+		}
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return fmt.Errorf("marshal prefs: %w", err)
+	}
+	data = indented.Bytes()
 
 	path := PrefsFilePath()
 	dir := filepath.Dir(path)
@@ -63,3 +209,35 @@ func SavePrefs(prefs Preferences) error {
 	}
 	return nil
 }
+
+// UpdatePrefs reads the preferences, applies update and saves the result. It
+// holds a machine-wide lock throughout, so that the tray and the CLI changing
+// different settings at once do not overwrite each other's change. Like
+// ReadPrefs it leaves unset fields empty: a missing file starts from unset
+// preferences, and a corrupt one is reported rather than replaced. Nothing is
+// saved if update fails.
+func UpdatePrefs(update func(p *Preferences) error) (Preferences, error) {
+	dir, err := slots.Open(filepath.Join(AppDirPath(), PrefsLockDirName))
+	if err != nil {
+		return Preferences{}, fmt.Errorf("lock prefs: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), prefsLockTimeout)
+	defer cancel()
+	lock, err := dir.Acquire(ctx, 1)
+	if err != nil {
+		return Preferences{}, fmt.Errorf("lock prefs: %w", err)
+	}
+	defer lock.Release()
+
+	prefs, err := ReadPrefs()
+	if errors.Is(err, os.ErrNotExist) {
+		prefs, err = Preferences{Enabled: true}, nil
+	}
+	if err != nil {
+		return Preferences{}, fmt.Errorf("read %s: %w", PrefsFilePath(), err)
+	}
+	if err := update(&prefs); err != nil {
+		return prefs, err
+	}
+	return prefs, SavePrefs(prefs)
+}
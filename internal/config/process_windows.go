@@ -0,0 +1,19 @@
+//go:build windows
+
+package config
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists. On
+// Windows, FindProcess fails for a PID that is not running.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessStatusDirName is the directory next to the status file where each
+// adapter process publishes its own status record, as <pid>.json.
+const ProcessStatusDirName = "status.d"
+
+// processStatusMaxAge is how long a record may go without an update before
+// it is treated as left behind by a dead process whose PID was reused.
+const processStatusMaxAge = 24 * time.Hour
+
+// TransferProgress is one in-flight transfer of an adapter process.
+type TransferProgress struct {
+	OID       string    `json:"oid"`
+	Op        string    `json:"op"`                  // upload or download
+	BytesDone int64     `json:"bytesDone"`           // bytes transferred so far
+	Size      int64     `json:"size,omitempty"`      // object size, when known
+	StartedAt time.Time `json:"startedAt,omitempty"` // when the transfer began
+}
+
+// ProcessStatus is the status record of one adapter process. git-lfs runs
+// several adapters at once, so in-flight state is kept per process instead
+// of in the shared status file.
+type ProcessStatus struct {
+	PID       int                `json:"pid"`
+	StartedAt time.Time          `json:"startedAt"`
+	Transfers []TransferProgress `json:"transfers,omitempty"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// ProcessStatusDirPath returns the directory of the per-process status
+// records, next to the status file.
+func ProcessStatusDirPath() string {
+	return filepath.Join(filepath.Dir(StatusFilePath()), ProcessStatusDirName)
+}
+
+func processStatusPath(pid int) string {
+	return filepath.Join(ProcessStatusDirPath(), strconv.Itoa(pid)+".json")
+}
+
+// WriteProcessStatus atomically writes the record of process status.PID.
+func WriteProcessStatus(status ProcessStatus) error {
+	if status.UpdatedAt.IsZero() {
+		status.UpdatedAt = time.Now()
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshal process status: %w", err)
+	}
+	return writeStatusFile(processStatusPath(status.PID), data)
+}
+
+// RemoveProcessStatus deletes the record of process pid, if any.
+func RemoveProcessStatus(pid int) error {
+	err := os.Remove(processStatusPath(pid))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ReadProcessStatuses returns the records of running adapter processes,
+// ordered by PID. Records of processes that are gone are deleted.
+func ReadProcessStatuses() []ProcessStatus {
+	paths, _ := filepath.Glob(filepath.Join(ProcessStatusDirPath(), "*.json"))
+	var statuses []ProcessStatus
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var status ProcessStatus
+		if err := json.Unmarshal(data, &status); err != nil || !processAlive(status.PID) ||
+			time.Since(status.UpdatedAt) > processStatusMaxAge {
+			_ = os.Remove(path)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].PID < statuses[j].PID })
+	return statuses
+}
+
+// AggregateStatus merges the status file, which holds the outcome of the
+// most recent transfer, with the records of running adapter processes.
+// While any transfer is in flight the state is transferring.
+type AggregateStatus struct {
+	StatusReport
+	Active    []TransferProgress `json:"active,omitempty"` // in-flight transfers of all processes
+	Processes int                `json:"processes"`        // running adapter processes
+}
+
+// ReadAggregateStatus reads the status of all adapter processes. It returns
+// the status file's error only when there is neither a status file nor a
+// running adapter to report on.
+func ReadAggregateStatus() (AggregateStatus, error) {
+	last, err := ReadStatus()
+	statuses := ReadProcessStatuses()
+	if err != nil && len(statuses) == 0 {
+		return AggregateStatus{}, err
+	}
+	agg := AggregateStatus{StatusReport: last, Processes: len(statuses)}
+	for _, status := range statuses {
+		agg.Active = append(agg.Active, status.Transfers...)
+	}
+	if len(agg.Active) == 0 {
+		if err != nil {
+			agg.State = StateIdle
+		}
+		return agg, nil
+	}
+
+	op := agg.Active[0].Op
+	for _, t := range agg.Active[1:] {
+		if t.Op != op {
+			op = ""
+		}
+	}
+	agg.StatusReport = StatusReport{State: StateTransferring, LastOp: op, Timestamp: time.Now()}
+	return agg, nil
+}
+
+// Progress returns the bytes done and the total size of the in-flight
+// transfers whose size is known.
+func (s AggregateStatus) Progress() (done, total int64) {
+	for _, t := range s.Active {
+		if t.Size > 0 {
+			done += min(t.BytesDone, t.Size)
+			total += t.Size
+		}
+	}
+	return done, total
+}
+
+// Summary describes the in-flight transfers, e.g. "3 uploads in progress,
+// 45%". It is empty when nothing is in flight.
+func (s AggregateStatus) Summary() string {
+	var uploads, downloads, other int
+	for _, t := range s.Active {
+		switch t.Op {
+		case "upload":
+			uploads++
+		case "download":
+			downloads++
+		default:
+			other++
+		}
+	}
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		noun string
+	}{{uploads, "upload"}, {downloads, "download"}, {other, "transfer"}} {
+		if c.n > 0 {
+			parts = append(parts, pluralize(c.n, c.noun))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	summary := strings.Join(parts, " and ") + " in progress"
+	if done, total := s.Progress(); total > 0 {
+		summary += fmt.Sprintf(", %d%%", done*100/total)
+	}
+	return summary
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// deadPID returns the PID of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	p, err := os.StartProcess(os.Args[0], []string{os.Args[0], "-test.run=^$"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	return p.Pid
+}
+
+func TestReadAggregateStatusMergesProcesses(t *testing.T) {
+	t.Setenv(EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	if err := WriteStatus(StatusReport{State: StateError, LastOp: "upload", Error: "timeout"}); err != nil {
+		t.Fatal(err)
+	}
+
+	self := os.Getpid()
+	if err := WriteProcessStatus(ProcessStatus{PID: self, Transfers: []TransferProgress{
+		{OID: "a", Op: "upload", BytesDone: 40, Size: 100},
+		{OID: "b", Op: "upload", BytesDone: 50, Size: 100},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	dead := deadPID(t)
+	if err := WriteProcessStatus(ProcessStatus{PID: dead, Transfers: []TransferProgress{{OID: "c", Op: "download", Size: 10}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	agg, err := ReadAggregateStatus()
+	if err != nil {
+		t.Fatalf("ReadAggregateStatus: %v", err)
+	}
+	if agg.State != StateTransferring || agg.LastOp != "upload" || agg.Processes != 1 || len(agg.Active) != 2 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	if got := agg.Summary(); got != "2 uploads in progress, 45%" {
+		t.Fatalf("Summary() = %q", got)
+	}
+	if _, err := os.Stat(processStatusPath(dead)); !os.IsNotExist(err) {
+		t.Fatalf("the dead process's record should be removed, stat err = %v", err)
+	}
+
+	// Once the transfers end, the last outcome shows again.
+	if err := RemoveProcessStatus(self); err != nil {
+		t.Fatal(err)
+	}
+	agg, err = ReadAggregateStatus()
+	if err != nil || agg.State != StateError || agg.Error != "timeout" || agg.Summary() != "" {
+		t.Fatalf("expected the last outcome, got %+v, %v", agg, err)
+	}
+}
+
+func TestReadAggregateStatusWithoutData(t *testing.T) {
+	t.Setenv(EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	if _, err := ReadAggregateStatus(); !os.IsNotExist(err) {
+		t.Fatalf("expected a not-exist error without status, got %v", err)
+	}
+
+	if err := WriteProcessStatus(ProcessStatus{PID: os.Getpid()}); err != nil {
+		t.Fatal(err)
+	}
+	agg, err := ReadAggregateStatus()
+	if err != nil || agg.State != StateIdle || agg.Processes != 1 {
+		t.Fatalf("a running adapter without transfers should be idle, got %+v, %v", agg, err)
+	}
+}
+
+func TestReadProcessStatusesDropsStaleRecords(t *testing.T) {
+	t.Setenv(EnvStatusFile, filepath.Join(t.TempDir(), "status.json"))
+	if err := WriteProcessStatus(ProcessStatus{PID: os.Getpid(), UpdatedAt: time.Now().Add(-2 * processStatusMaxAge)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ReadProcessStatuses(); len(got) != 0 {
+		t.Fatalf("expected a stale record to be dropped, got %+v", got)
+	}
+}
+
+func TestAggregateStatusSummary(t *testing.T) {
+	for _, tc := range []struct {
+		active []TransferProgress
+		want   string
+	}{
+		{nil, ""},
+		{[]TransferProgress{{Op: "download"}}, "1 download in progress"},
+		{[]TransferProgress{{Op: "upload", Size: 10, BytesDone: 10}, {Op: "download", Size: 30}}, "1 upload and 1 download in progress, 25%"},
+	} {
+		if got := (AggregateStatus{Active: tc.active}).Summary(); got != tc.want {
+			t.Errorf("Summary(%+v) = %q, want %q", tc.active, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// DefaultProfileName names the implicit profile built from the top-level
+// Preferences fields. It uses proton-drive-cli's own session location.
+const DefaultProfileName = "default"
+
+// GitConfigProfileKey is the git config key that selects a profile per repo.
+const GitConfigProfileKey = "lfs.proton.profile"
+
+// EnvDriveCLISessionDir is read by proton-drive-cli to locate its session.
+const EnvDriveCLISessionDir = "PROTON_DRIVE_CLI_SESSION_DIR"
+
+// DriveCLIDirName is proton-drive-cli's state directory inside the home dir.
+const DriveCLIDirName = ".proton-drive-cli"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Profile is a named Proton account with its own credentials, session and
+// storage base, so personal and work accounts can be used side by side.
+type Profile struct {
+	Name               string `json:"name"`
+	CredentialProvider string `json:"credentialProvider,omitempty"`
+	// Account is the Proton account email used to look up credentials.
+	Account string `json:"account,omitempty"`
+	// SessionDir overrides where proton-drive-cli keeps this profile's
+	// session; empty means ~/.proton-drive-cli/profiles/<name>.
+	SessionDir  string `json:"sessionDir,omitempty"`
+	StorageBase string `json:"storageBase,omitempty"`
+}
+
+// ValidProfileName reports whether name can be used as a profile name.
+func ValidProfileName(name string) bool {
+	return profileNamePattern.MatchString(name)
+}
+
+// SessionDirPath returns the directory holding the profile's proton-drive-cli
+// session.
+func (p Profile) SessionDirPath() string {
+	if p.SessionDir != "" {
+		return p.SessionDir
+	}
+	if p.Name == "" || p.Name == DefaultProfileName {
+		return DriveCLISessionDirPath()
+	}
+	return filepath.Join(driveCLIDirPath(), "profiles", p.Name)
+}
+
+// SessionFilePath returns the path of the profile's session file.
+func (p Profile) SessionFilePath() string {
+	return filepath.Join(p.SessionDirPath(), "session.json")
+}
+
+// DriveCLISessionDirPath returns proton-drive-cli's default session directory,
+// respecting EnvDriveCLISessionDir.
+func DriveCLISessionDirPath() string {
+	if dir := EnvTrim(EnvDriveCLISessionDir); dir != "" {
+		return dir
+	}
+	return driveCLIDirPath()
+}
+
+func driveCLIDirPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return DriveCLIDirName
+	}
+	return filepath.Join(home, DriveCLIDirName)
+}
+
+// FindProfile returns the named profile. The default profile always exists.
+func (p Preferences) FindProfile(name string) (Profile, bool) {
+	if name == "" || name == DefaultProfileName {
+		return Profile{Name: DefaultProfileName, CredentialProvider: p.CredentialProvider}, true
+	}
+	for _, profile := range p.Profiles {
+		if profile.Name == name {
+			if profile.CredentialProvider == "" {
+				profile.CredentialProvider = p.CredentialProvider
+			}
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ResolveProfile returns the named profile, or the active one when name is
+// empty.
+func (p Preferences) ResolveProfile(name string) (Profile, error) {
+	if name == "" {
+		name = p.ActiveProfile
+	}
+	profile, ok := p.FindProfile(name)
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, nil
+}
+
+// SetProfile adds profile or replaces the profile with the same name.
+func (p *Preferences) SetProfile(profile Profile) error {
+	if !ValidProfileName(profile.Name) || profile.Name == DefaultProfileName {
+		return fmt.Errorf("invalid profile name %q", profile.Name)
+	}
+	for i := range p.Profiles {
+		if p.Profiles[i].Name == profile.Name {
+			p.Profiles[i] = profile
+			return nil
+		}
+	}
+	p.Profiles = append(p.Profiles, profile)
+	return nil
+}
+
+// RemoveProfile deletes the named profile, falling back to the default
+// profile if it was active.
+func (p *Preferences) RemoveProfile(name string) error {
+	for i := range p.Profiles {
+		if p.Profiles[i].Name == name {
+			p.Profiles = append(p.Profiles[:i], p.Profiles[i+1:]...)
+			if p.ActiveProfile == name {
+				p.ActiveProfile = ""
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown profile %q", name)
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Route sends transfers for matching git remotes to their own backend,
+// storage base or credential provider. Empty fields keep the adapter's
+// configured value. A route matches by remote name, by URL pattern, or by
+// both when both are set; the first matching route in Preferences.Routes wins.
+type Route struct {
+	// Remote is a git remote name such as "origin".
+	Remote string `json:"remote,omitempty"`
+	// URL is a pattern for the remote's URL; "*" matches any characters.
+	URL string `json:"url,omitempty"`
+
+	Backend            string `json:"backend,omitempty"`
+	Profile            string `json:"profile,omitempty"`
+	StorageBase        string `json:"storageBase,omitempty"`
+	CredentialProvider string `json:"credentialProvider,omitempty"`
+	LocalStoreDir      string `json:"localStoreDir,omitempty"`
+}
+
+// Matches reports whether the route applies to the remote with the given
+// name and URL. A route without a remote or URL matches nothing.
+func (r Route) Matches(name, url string) bool {
+	if r.Remote == "" && r.URL == "" {
+		return false
+	}
+	if r.Remote != "" && r.Remote != name {
+		return false
+	}
+	if r.URL != "" && !matchURLPattern(r.URL, url) {
+		return false
+	}
+	return true
+}
+
+// MatchRoute returns the first route matching the remote, or nil.
+func MatchRoute(routes []Route, name, url string) *Route {
+	for i := range routes {
+		if routes[i].Matches(name, url) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+func matchURLPattern(pattern, url string) bool {
+	if url == "" {
+		return false
+	}
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	matched, err := regexp.MatchString(expr, url)
+	return err == nil && matched
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification levels: which tray notifications are shown.
+const (
+	NotifyAll    = "all"
+	NotifyErrors = "errors"
+	NotifyOff    = "off"
+)
+
+// DefaultNotifications is the notification level when none is set.
+const DefaultNotifications = NotifyAll
+
+// Setting sources, from highest to lowest precedence. SourceFlag covers the
+// adapter's command-line flags, which git-lfs passes from
+// lfs.customtransfer.proton.args.
+const (
+	SourceFlag      = "flag"
+	SourceEnv       = "env"
+	SourceGitConfig = "git config"
+	SourceLFSConfig = LFSConfigFileName
+	SourcePrefs     = "prefs"
+	SourceDefault   = "default"
+)
+
+// Setting describes a setting that can be saved in the preferences file.
+// Key is also the adapter flag name and, when GitConfig is set, the name
+// under lfs.proton in git config. LFSConfig marks the tuning settings that a
+// repository's committed .lfsconfig may also set: whoever controls the
+// repository must not choose where objects go, which credentials are used,
+// or the machine-wide concurrency limit that every repository shares.
+// Env is the environment variable that sets it, if any.
+type Setting struct {
+	Key       string
+	Env       string
+	GitConfig bool
+	LFSConfig bool
+	Default   string
+	Help      string
+
+	// parse validates a value and returns it in normal form.
+	parse func(p Preferences, value string) (string, error)
+	get   func(p Preferences) string
+	set   func(p *Preferences, value string)
+}
+
+// Settings lists the persisted settings in the order they are shown.
+var Settings = []Setting{
+	{
+		Key: "backend", Env: EnvBackend, GitConfig: true, Default: BackendLocal,
+		Help:  "Transfer backend: local or sdk",
+		parse: oneOf(BackendLocal, BackendSDK),
+		get:   func(p Preferences) string { return p.Backend },
+		set:   func(p *Preferences, v string) { p.Backend = v },
+	},
+	{
+		Key: "local-store-dir", Env: EnvLocalStoreDir, GitConfig: true,
+		Help:  "Object directory of the local backend (absolute path)",
+		parse: parseAbsPath,
+		get:   func(p Preferences) string { return p.LocalStoreDir },
+		set:   func(p *Preferences, v string) { p.LocalStoreDir = v },
+	},
+	{
+		Key: "storage-base", Env: EnvStorageBase, GitConfig: true, Default: DefaultStorageBase,
+		Help:  "Proton Drive folder holding LFS objects",
+		parse: parseStorageBase,
+		get:   func(p Preferences) string { return p.StorageBase },
+		set:   func(p *Preferences, v string) { p.StorageBase = v },
+	},
+	{
+		Key: "credential-provider", Env: EnvCredentialProvider, GitConfig: true, Default: DefaultCredentialProvider,
+		Help:  "Where credentials come from: pass-cli or git-credential",
+		parse: oneOf(CredentialProviderPassCLI, CredentialProviderGitCredential),
+		get:   func(p Preferences) string { return p.CredentialProvider },
+		set:   func(p *Preferences, v string) { p.CredentialProvider = v },
+	},
+	{
+		Key: "profile", Env: EnvProfile, GitConfig: true, Default: DefaultProfileName,
+		Help:  "Active account profile",
+		parse: parseProfile,
+		get:   func(p Preferences) string { return p.ActiveProfile },
+		set:   func(p *Preferences, v string) { p.ActiveProfile = v },
+	},
+	{
+		Key: "timeout", Env: EnvTimeout, GitConfig: true, LFSConfig: true, Default: DefaultBridgeTimeout.String(),
+		Help:  "Timeout for a single proton-drive-cli command, e.g. 10m",
+		parse: parseDuration,
+		get:   func(p Preferences) string { return p.Timeout },
+		set:   func(p *Preferences, v string) { p.Timeout = v },
+	},
+	{
+		Key: "concurrency", Env: EnvConcurrency, GitConfig: true, Default: strconv.Itoa(DefaultBridgeConcurrency),
+		Help:  "Maximum concurrent proton-drive-cli commands on this machine",
+		parse: parsePositiveInt,
+		get:   func(p Preferences) string { return formatInt(p.Concurrency) },
+		set:   func(p *Preferences, v string) { p.Concurrency, _ = strconv.Atoi(v) },
+	},
+	{
+		Key: "queue-timeout", Env: EnvQueueTimeout, GitConfig: true, Default: DefaultBridgeQueueTimeout.String(),
+		Help:  "How long a command waits for a free concurrency slot",
+		parse: parseDuration,
+		get:   func(p Preferences) string { return p.QueueTimeout },
+		set:   func(p *Preferences, v string) { p.QueueTimeout = v },
+	},
+	{
+		Key: "cache-max-mb", Env: EnvCacheMaxMB, Default: strconv.Itoa(DefaultCacheMaxMB),
+		Help:  "Object cache size limit in MiB",
+		parse: parsePositiveInt,
+		get:   func(p Preferences) string { return formatInt(p.CacheMaxMB) },
+		set:   func(p *Preferences, v string) { p.CacheMaxMB, _ = strconv.Atoi(v) },
+	},
+	{
+		Key: "pause-mode", Env: EnvPauseMode, GitConfig: true, Default: DefaultPauseMode,
+		Help:  "While paused, fail transfers (fail) or wait until resumed (wait)",
+		parse: oneOf(PauseModeFail, PauseModeWait),
+		get:   func(p Preferences) string { return p.PauseMode },
+		set:   func(p *Preferences, v string) { p.PauseMode = v },
+	},
+	{
+		Key: "log-level", Env: EnvLogLevel, GitConfig: true, LFSConfig: true, Default: DefaultLogLevel,
+		Help:  "Adapter log level: debug, info, warn or error",
+		parse: oneOf("debug", "info", "warn", "error"),
+		get:   func(p Preferences) string { return p.LogLevel },
+		set:   func(p *Preferences, v string) { p.LogLevel = v },
+	},
+	{
+		Key: "notifications", Default: DefaultNotifications,
+		Help:  "Tray notifications to show: all, errors or off",
+		parse: oneOf(NotifyAll, NotifyErrors, NotifyOff),
+		get:   func(p Preferences) string { return p.Notifications },
+		set:   func(p *Preferences, v string) { p.Notifications = v },
+	},
+	{
+		Key: "metrics-addr", Env: EnvMetricsAddr,
+		Help:  "Address the tray serves Prometheus metrics on, e.g. " + DefaultMetricsAddr + " (unset: socket only)",
+		parse: parseListenAddr,
+		get:   func(p Preferences) string { return p.MetricsAddr },
+		set:   func(p *Preferences, v string) { p.MetricsAddr = v },
+	},
+}
+
+// LookupSetting returns the setting named key.
+func LookupSetting(key string) (Setting, bool) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// SettingKeys returns the keys of all settings.
+func SettingKeys() []string {
+	keys := make([]string, len(Settings))
+	for i, s := range Settings {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+// Get returns the value saved in p, or "" when p does not set it.
+func (s Setting) Get(p Preferences) string {
+	return strings.TrimSpace(s.get(p))
+}
+
+// Set validates value and saves it in p. An empty value unsets the setting.
+func (s Setting) Set(p *Preferences, value string) error {
+	value = strings.TrimSpace(value)
+	if value != "" {
+		var err error
+		if value, err = s.parse(*p, value); err != nil {
+			return fmt.Errorf("%s: %w", s.Key, err)
+		}
+	}
+	s.set(p, value)
+	return nil
+}
+
+// SettingValue is the effective value of a setting and where it came from.
+// Origin names the variable, key or file of the source.
+type SettingValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+func oneOf(values ...string) func(Preferences, string) (string, error) {
+	return func(_ Preferences, value string) (string, error) {
+		value = strings.ToLower(value)
+		for _, v := range values {
+			if value == v {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("invalid value %q (supported: %s)", value, strings.Join(values, ", "))
+	}
+}
+
+func parseAbsPath(_ Preferences, value string) (string, error) {
+	if !filepath.IsAbs(value) {
+		return "", fmt.Errorf("%q is not an absolute path", value)
+	}
+	return filepath.Clean(value), nil
+}
+
+func parseStorageBase(_ Preferences, value string) (string, error) {
+	base := strings.Trim(value, "/")
+	if base == "" {
+		return "", fmt.Errorf("invalid storage base %q", value)
+	}
+	return base, nil
+}
+
+// parseProfile accepts the default profile and the profiles in p.
+func parseProfile(p Preferences, value string) (string, error) {
+	if _, ok := p.FindProfile(value); !ok {
+		return "", fmt.Errorf("unknown profile %q (add it with 'proton-lfs-cli config profile add')", value)
+	}
+	return value, nil
+}
+
+func parseDuration(_ Preferences, value string) (string, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return "", fmt.Errorf("invalid duration %q (e.g. 90s or 10m)", value)
+	}
+	return d.String(), nil
+}
+
+func parsePositiveInt(_ Preferences, value string) (string, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid value %q (need a positive integer)", value)
+	}
+	return strconv.Itoa(n), nil
+}
+
+// parseListenAddr accepts a host:port address to listen on.
+func parseListenAddr(_ Preferences, value string) (string, error) {
+	host, port, err := net.SplitHostPort(value)
+	if n, convErr := strconv.Atoi(port); err != nil || convErr != nil || n <= 0 || n > 65535 {
+		return "", fmt.Errorf("invalid address %q (e.g. %s)", value, DefaultMetricsAddr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func formatInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
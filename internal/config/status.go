@@ -17,11 +17,12 @@ const (
 	StateRateLimited  = "rate_limited"  // Rate-limited by Proton API
 	StateAuthRequired = "auth_required" // Authentication required or expired
 	StateCaptcha      = "captcha"       // CAPTCHA verification required
+	StatePaused       = "paused"        // Transfers paused by the user
 )
 
 // StatusReport is the JSON structure written to the status file.
 type StatusReport struct {
-	State       string    `json:"state"`                 // Current operation state (idle, transferring, ok, error, rate_limited, auth_required, captcha)
+	State       string    `json:"state"`                 // Current operation state (idle, transferring, ok, error, rate_limited, auth_required, captcha, paused)
 	LastOID     string    `json:"lastOid,omitempty"`     // OID of last operation
 	LastOp      string    `json:"lastOp,omitempty"`      // Type of last operation (upload, download)
 	Error       string    `json:"error,omitempty"`       // Human-readable error message
@@ -42,9 +43,13 @@ func WriteStatus(report StatusReport) error {
 		return fmt.Errorf("marshal status: %w", err)
 	}
 
-	path := StatusFilePath()
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o700); err != nil {
+	return writeStatusFile(StatusFilePath(), data)
+}
+
+// writeStatusFile writes data to path through a temp file and a rename, so
+// that readers never see a partial file.
+func writeStatusFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return fmt.Errorf("create status dir: %w", err)
 	}
 
@@ -0,0 +1,128 @@
+// Package logging provides the log files shared by the Proton LFS adapter and
+// tray application: a size- and age-rotated file writer, redaction of
+// credentials, and slog handlers that apply it.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// sensitivePatterns mark the start of text that may carry a credential: a
+// token, a session ID, or a bearer header.
+var sensitivePatterns = []string{"Bearer ", "token=", "session=", "AccessToken", "RefreshToken", "UID:"}
+
+// Redact truncates s from the first sensitive pattern onward.
+func Redact(s string) string {
+	for _, pattern := range sensitivePatterns {
+		if idx := strings.Index(s, pattern); idx >= 0 {
+			return s[:idx] + "[redacted]"
+		}
+	}
+	return s
+}
+
+// redactingWriter redacts each write, which log.Logger makes one per line.
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter returns a writer that redacts every line written to w.
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return redactingWriter{w: w}
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(string(p), "\n") {
+		body, newline := strings.CutSuffix(line, "\n")
+		out.WriteString(Redact(body))
+		if newline {
+			out.WriteByte('\n')
+		}
+	}
+	if _, err := io.WriteString(r.w, out.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ParseLevel parses a level name: debug, info, warn or error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (supported: debug, info, warn, error)", name)
+	}
+	return level, nil
+}
+
+// redactAttr is a slog ReplaceAttr function that redacts the message and
+// every string or error value.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch v := a.Value; v.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(Redact(v.String()))
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			a.Value = slog.StringValue(Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// NewJSONHandler returns a handler writing redacted JSON lines to w.
+func NewJSONHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr})
+}
+
+// NewTextHandler returns a handler writing redacted key=value lines to w.
+func NewTextHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr})
+}
+
+// fanout passes each record to every handler enabled for its level.
+type fanout []slog.Handler
+
+// Fanout returns a handler that writes each record to all of handlers.
+func Fanout(handlers ...slog.Handler) slog.Handler {
+	return fanout(handlers)
+}
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
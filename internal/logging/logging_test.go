@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	for input, want := range map[string]string{
+		"upload failed":                      "upload failed",
+		"auth failed: Bearer abc123":         "auth failed: [redacted]",
+		"GET /x?token=secret&y=1":            "GET /x?[redacted]",
+		"restored AccessToken for UID:12345": "restored [redacted]",
+	} {
+		if got := Redact(input); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRedactingWriterKeepsLines(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(NewRedactingWriter(&buf), "[tray] ", 0)
+	logger.Print("login ok session=abc")
+	logger.Print("status refreshed")
+
+	if got, want := buf.String(), "[tray] login ok [redacted]\n[tray] status refreshed\n"; got != want {
+		t.Fatalf("redacted log = %q, want %q", got, want)
+	}
+}
+
+func TestJSONHandlerRedactsValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewJSONHandler(&buf, slog.LevelInfo))
+	logger.Debug("hidden")
+	logger.Error("Transfer failed", "oid", "abc", "error", errors.New("bridge said token=xyz"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "Transfer failed" || record["oid"] != "abc" || record["error"] != "bridge said [redacted]" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+}
+
+func TestFanoutHonorsEachLevel(t *testing.T) {
+	var info, debug bytes.Buffer
+	logger := slog.New(Fanout(NewJSONHandler(&info, slog.LevelInfo), NewTextHandler(&debug, slog.LevelDebug))).With("session_id", "s1")
+	logger.Debug("detail")
+	logger.Info("summary")
+
+	if strings.Contains(info.String(), "detail") || !strings.Contains(info.String(), `"session_id":"s1"`) {
+		t.Fatalf("info handler output: %q", info.String())
+	}
+	if !strings.Contains(debug.String(), "detail") || !strings.Contains(debug.String(), "summary") {
+		t.Fatalf("debug handler output: %q", debug.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel(" warn "); err != nil || level != slog.LevelWarn {
+		t.Fatalf("ParseLevel(warn) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("chatty"); err == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	l, err := Open(dir, "adapter", Rotation{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.now = func() time.Time { return day }
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := l.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	current := filepath.Join(dir, "adapter-2026-03-14.log")
+	for path, want := range map[string]string{
+		current: "third\n",
+		filepath.Join(dir, "adapter-2026-03-14.1.log"): "first\n",
+		filepath.Join(dir, "adapter-2026-03-14.2.log"): "second\n",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", filepath.Base(path), data, err, want)
+		}
+	}
+	if l.Path() != current {
+		t.Fatalf("Path() = %q, want %q", l.Path(), current)
+	}
+}
+
+func TestFileStartsNewDayAndPrunesOldLogs(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "adapter-2026-01-01.log")
+	other := filepath.Join(dir, "tray-2026-01-01.log")
+	for _, path := range []string{old, other} {
+		if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		stale := time.Now().Add(-30 * 24 * time.Hour)
+		if err := os.Chtimes(path, stale, stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := Open(dir, "adapter", Rotation{MaxAge: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale adapter log to be pruned, stat err = %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("another log's files must be kept: %v", err)
+	}
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	l.now = func() time.Time { return tomorrow }
+	if _, err := l.Write([]byte("next day\n")); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "adapter-"+tomorrow.Format(time.DateOnly)+".log"); l.Path() != want {
+		t.Fatalf("Path() = %q, want %q", l.Path(), want)
+	}
+}
+
+func TestFileReopensAfterRotationByAnotherProcess(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, "adapter", Rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	path := l.Path()
+	if err := os.Rename(path, path+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "after\n" {
+		t.Fatalf("expected the write in a fresh file, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"proton-lfs-cli/internal/config"
+)
+
+// Rotation bounds a log file's size and how long old logs are kept. Zero
+// disables either limit.
+type Rotation struct {
+	MaxSize int64
+	MaxAge  time.Duration
+}
+
+// File is an append-only log file named <name>-<date>.log in a directory. A
+// new file starts each day, and a file that would grow past MaxSize is moved
+// aside to <name>-<date>.<n>.log first. Logs older than MaxAge are removed
+// when a file is opened. Several processes may write the same file: each
+// write is a single append, and a file rotated by another process is noticed
+// and reopened.
+type File struct {
+	mu       sync.Mutex
+	dir      string
+	name     string
+	rotation Rotation
+	now      func() time.Time
+
+	f    *os.File
+	path string
+}
+
+// Open creates dir if needed and opens today's log file for name in it.
+func Open(dir, name string, rotation Rotation) (*File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	l := &File{dir: dir, name: name, rotation: rotation, now: time.Now}
+	if err := l.reopen(l.currentPath()); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path returns the file currently written to.
+func (l *File) Path() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.path
+}
+
+// Write appends p, rotating the file first when needed.
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+	if err := l.rotate(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return l.f.Write(p)
+}
+
+// Close closes the current file.
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+func (l *File) currentPath() string {
+	return filepath.Join(l.dir, l.name+"-"+l.now().Format(time.DateOnly)+".log")
+}
+
+// rotate switches to a fresh file before a write of n bytes when the day
+// changed, the file was moved by another process, or it would outgrow
+// MaxSize.
+func (l *File) rotate(n int64) error {
+	path := l.currentPath()
+	if path != l.path {
+		return l.reopen(path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return l.reopen(path)
+	}
+	if open, err := l.f.Stat(); err != nil || !os.SameFile(info, open) {
+		return l.reopen(path)
+	}
+	if l.rotation.MaxSize <= 0 || info.Size() == 0 || info.Size()+n <= l.rotation.MaxSize {
+		return nil
+	}
+	// A failed rename means another process rotated first; reopening picks
+	// up its new file either way.
+	_ = os.Rename(path, l.rotatedPath(path))
+	return l.reopen(path)
+}
+
+// rotatedPath returns the first unused <name>-<date>.<n>.log for path.
+func (l *File) rotatedPath(path string) string {
+	base := path[:len(path)-len(".log")]
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s.%d.log", base, i)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+func (l *File) reopen(path string) error {
+	if l.f != nil {
+		_ = l.f.Close()
+		l.f = nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	l.f, l.path = f, path
+	l.prune()
+	return nil
+}
+
+// prune removes this log's files last written more than MaxAge ago.
+func (l *File) prune() {
+	if l.rotation.MaxAge <= 0 {
+		return
+	}
+	matches, _ := filepath.Glob(filepath.Join(l.dir, l.name+"-*.log"))
+	cutoff := l.now().Add(-l.rotation.MaxAge)
+	for _, match := range matches {
+		if match == l.path {
+			continue
+		}
+		if info, err := os.Stat(match); err == nil && info.ModTime().Before(cutoff) {
+			_ = os.Remove(match)
+		}
+	}
+}
+
+// OpenAppLog opens the log file for name, e.g. adapter or tray, in the
+// configured log directory, with the configured size and age limits.
+func OpenAppLog(name string) (*File, error) {
+	return Open(config.LogDirPath(), name, Rotation{
+		MaxSize: int64(config.EnvIntOrDefault(config.EnvLogMaxMB, config.DefaultLogMaxMB)) << 20,
+		MaxAge:  time.Duration(config.EnvIntOrDefault(config.EnvLogMaxAgeDays, config.DefaultLogMaxAgeDays)) * 24 * time.Hour,
+	})
+}
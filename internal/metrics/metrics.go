@@ -0,0 +1,337 @@
+// Package metrics provides the counters and histograms of the Proton LFS
+// adapter, their Prometheus text exposition, and the collector that adapter
+// processes push their samples to.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric names.
+const (
+	BridgeSpawns          = "proton_lfs_bridge_spawns_total"
+	BridgeCommandDuration = "proton_lfs_bridge_command_duration_seconds"
+	BridgeExits           = "proton_lfs_bridge_exits_total"
+	BridgeQueueWait       = "proton_lfs_bridge_queue_wait_seconds"
+	Transfers             = "proton_lfs_transfers_total"
+	TransferDuration      = "proton_lfs_transfer_duration_seconds"
+	TransferBytes         = "proton_lfs_transfer_bytes_total"
+	TransferRetries       = "proton_lfs_transfer_retries_total"
+	TransferErrors        = "proton_lfs_transfer_errors_total"
+	DedupHits             = "proton_lfs_dedup_hits_total"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the duration
+// histograms: from a quick exists check to a multi-gigabyte upload.
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 900}
+
+// family describes one metric.
+type family struct {
+	help    string
+	buckets []float64 // nil for counters
+}
+
+// families lists every metric a Registry accepts. Samples of other names are
+// dropped, so an adapter cannot add arbitrary series to the collector.
+var families = map[string]family{
+	BridgeSpawns:          {help: "proton-drive-cli bridge processes started, by mode (subprocess or daemon)."},
+	BridgeCommandDuration: {help: "Duration of bridge commands, by command.", buckets: durationBuckets},
+	BridgeExits:           {help: "Exited bridge processes, by command and exit code (-1 when killed)."},
+	BridgeQueueWait:       {help: "Time bridge commands waited for a free concurrency slot.", buckets: durationBuckets},
+	Transfers:             {help: "Finished LFS transfers, by operation and state."},
+	TransferDuration:      {help: "Duration of successful LFS transfers, by operation.", buckets: durationBuckets},
+	TransferBytes:         {help: "Bytes moved by successful LFS transfers, by operation."},
+	TransferRetries:       {help: "Retries of LFS transfers, by operation."},
+	TransferErrors:        {help: "Failed LFS transfers, by operation and error code."},
+	DedupHits:             {help: "Uploads skipped because the object was already stored."},
+}
+
+// Snapshot is a set of samples, as pushed from an adapter to the collector.
+type Snapshot struct {
+	Counters   []CounterSample   `json:"counters,omitempty"`
+	Histograms []HistogramSample `json:"histograms,omitempty"`
+}
+
+// CounterSample is the value of one counter series.
+type CounterSample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// HistogramSample is one histogram series. Counts holds the observations per
+// bucket of the metric, not cumulated, with the +Inf bucket last.
+type HistogramSample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Counts []uint64          `json:"counts"`
+	Sum    float64           `json:"sum"`
+}
+
+// seriesKey identifies a series: the metric name and its rendered labels.
+type seriesKey struct {
+	name   string
+	labels string
+}
+
+type counter struct {
+	labels map[string]string
+	value  float64
+}
+
+type histogram struct {
+	labels map[string]string
+	counts []uint64
+	sum    float64
+}
+
+// Registry holds counter and histogram series. It is safe for concurrent
+// use. A nil Registry records nothing.
+type Registry struct {
+	mu         sync.Mutex
+	counters   map[seriesKey]*counter
+	histograms map[seriesKey]*histogram
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[seriesKey]*counter),
+		histograms: make(map[seriesKey]*histogram),
+	}
+}
+
+// Add adds v to the counter name. labels are key, value pairs.
+func (r *Registry) Add(name string, v float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	r.addCounter(name, pairs(labels), v)
+}
+
+// Inc adds one to the counter name.
+func (r *Registry) Inc(name string, labels ...string) {
+	r.Add(name, 1, labels...)
+}
+
+// Observe records v in the histogram name. labels are key, value pairs.
+func (r *Registry) Observe(name string, v float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	f, ok := families[name]
+	if !ok || f.buckets == nil {
+		return
+	}
+	counts := make([]uint64, len(f.buckets)+1)
+	counts[sort.SearchFloat64s(f.buckets, v)]++
+	r.addHistogram(name, pairs(labels), counts, v)
+}
+
+// Merge adds the samples of s. Samples of unknown metrics, negative counter
+// values and histograms with the wrong number of buckets are dropped.
+func (r *Registry) Merge(s Snapshot) {
+	if r == nil {
+		return
+	}
+	for _, c := range s.Counters {
+		if c.Value >= 0 && !math.IsInf(c.Value, 0) {
+			r.addCounter(c.Name, c.Labels, c.Value)
+		}
+	}
+	for _, h := range s.Histograms {
+		if f, ok := families[h.Name]; ok && f.buckets != nil && len(h.Counts) == len(f.buckets)+1 {
+			r.addHistogram(h.Name, h.Labels, h.Counts, h.Sum)
+		}
+	}
+}
+
+// Take returns the registry's samples and resets it, so that each sample is
+// pushed once.
+func (r *Registry) Take() Snapshot {
+	var s Snapshot
+	if r == nil {
+		return s
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, c := range r.counters {
+		s.Counters = append(s.Counters, CounterSample{Name: key.name, Labels: c.labels, Value: c.value})
+	}
+	for key, h := range r.histograms {
+		s.Histograms = append(s.Histograms, HistogramSample{Name: key.name, Labels: h.labels, Counts: h.counts, Sum: h.sum})
+	}
+	r.counters = make(map[seriesKey]*counter)
+	r.histograms = make(map[seriesKey]*histogram)
+	return s
+}
+
+func (r *Registry) addCounter(name string, labels map[string]string, v float64) {
+	if f, ok := families[name]; !ok || f.buckets != nil {
+		return
+	}
+	key := seriesKey{name, renderLabels(labels)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.counters[key]
+	if !ok {
+		c = &counter{labels: labels}
+		r.counters[key] = c
+	}
+	c.value += v
+}
+
+func (r *Registry) addHistogram(name string, labels map[string]string, counts []uint64, sum float64) {
+	key := seriesKey{name, renderLabels(labels)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[key]
+	if !ok {
+		h = &histogram{labels: labels, counts: make([]uint64, len(counts))}
+		r.histograms[key] = h
+	}
+	for i, n := range counts {
+		h.counts[i] += n
+	}
+	h.sum += sum
+}
+
+// WriteText writes every series in the Prometheus text exposition format,
+// ordered by metric name and labels.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	byName := make(map[string][]string)
+	lines := make(map[seriesKey][]string)
+	for key, c := range r.counters {
+		byName[key.name] = append(byName[key.name], key.labels)
+		lines[key] = []string{key.name + key.labels + " " + formatFloat(c.value)}
+	}
+	for key, h := range r.histograms {
+		byName[key.name] = append(byName[key.name], key.labels)
+		lines[key] = histogramLines(key.name, h, families[key.name].buckets)
+	}
+	r.mu.Unlock()
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		kind := "counter"
+		if families[name].buckets != nil {
+			kind = "histogram"
+		}
+		_, _ = fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, families[name].help, name, kind)
+		series := byName[name]
+		sort.Strings(series)
+		for _, labels := range series {
+			for _, line := range lines[seriesKey{name, labels}] {
+				_, _ = bw.WriteString(line + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// histogramLines renders the cumulative buckets, sum and count of h.
+func histogramLines(name string, h *histogram, buckets []float64) []string {
+	out := make([]string, 0, len(h.counts)+2)
+	var cumulative uint64
+	for i, n := range h.counts {
+		cumulative += n
+		le := "+Inf"
+		if i < len(buckets) {
+			le = formatFloat(buckets[i])
+		}
+		labels := make(map[string]string, len(h.labels)+1)
+		for k, v := range h.labels {
+			labels[k] = v
+		}
+		labels["le"] = le
+		out = append(out, name+"_bucket"+renderLabels(labels)+" "+strconv.FormatUint(cumulative, 10))
+	}
+	labels := renderLabels(h.labels)
+	out = append(out,
+		name+"_sum"+labels+" "+formatFloat(h.sum),
+		name+"_count"+labels+" "+strconv.FormatUint(cumulative, 10))
+	return out
+}
+
+// pairs turns key, value pairs into a label map. A trailing key without a
+// value is dropped.
+func pairs(kv []string) map[string]string {
+	if len(kv) < 2 {
+		return nil
+	}
+	labels := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		labels[kv[i]] = kv[i+1]
+	}
+	return labels
+}
+
+// renderLabels renders labels as {a="1",b="2"}, sorted by name, or "" when
+// there are none. Names that are not valid Prometheus label names are
+// dropped.
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if validLabelName(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func validLabelName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Inc(Transfers, "state", "ok", "op", "upload")
+	r.Add(Transfers, 2, "op", "upload", "state", "ok")
+	r.Inc(TransferErrors, "op", "download", "error_code", `odd"code`)
+	r.Observe(TransferDuration, 0.3, "op", "upload")
+	r.Observe(TransferDuration, 1000, "op", "upload")
+	r.Inc("proton_lfs_unknown_total")
+	r.Inc(TransferDuration) // a histogram is not a counter
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE proton_lfs_transfers_total counter\n",
+		`proton_lfs_transfers_total{op="upload",state="ok"} 3` + "\n",
+		`proton_lfs_transfer_errors_total{error_code="odd\"code",op="download"} 1` + "\n",
+		"# TYPE proton_lfs_transfer_duration_seconds histogram\n",
+		`proton_lfs_transfer_duration_seconds_bucket{le="0.25",op="upload"} 0` + "\n",
+		`proton_lfs_transfer_duration_seconds_bucket{le="0.5",op="upload"} 1` + "\n",
+		`proton_lfs_transfer_duration_seconds_bucket{le="900",op="upload"} 1` + "\n",
+		`proton_lfs_transfer_duration_seconds_bucket{le="+Inf",op="upload"} 2` + "\n",
+		`proton_lfs_transfer_duration_seconds_sum{op="upload"} 1000.3` + "\n",
+		`proton_lfs_transfer_duration_seconds_count{op="upload"} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "unknown") || strings.Count(out, "# TYPE") != 3 {
+		t.Errorf("unexpected series:\n%s", out)
+	}
+}
+
+func TestRegistryTakeAndMerge(t *testing.T) {
+	r := NewRegistry()
+	r.Inc(DedupHits)
+	r.Observe(BridgeCommandDuration, 0.01, "command", "upload")
+	s := r.Take()
+	if len(s.Counters) != 1 || len(s.Histograms) != 1 {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	if again := r.Take(); len(again.Counters)+len(again.Histograms) != 0 {
+		t.Fatalf("Take should reset the registry, got %+v", again)
+	}
+
+	// Merging twice doubles the values; invalid samples are dropped.
+	collector := NewRegistry()
+	collector.Merge(s)
+	collector.Merge(s)
+	collector.Merge(Snapshot{
+		Counters:   []CounterSample{{Name: DedupHits, Value: -5}, {Name: "other_total", Value: 1}},
+		Histograms: []HistogramSample{{Name: BridgeCommandDuration, Counts: []uint64{1}}},
+	})
+	var buf bytes.Buffer
+	_ = collector.WriteText(&buf)
+	out := buf.String()
+	if !strings.Contains(out, "proton_lfs_dedup_hits_total 2\n") || !strings.Contains(out, `proton_lfs_bridge_command_duration_seconds_count{command="upload"} 2`) {
+		t.Fatalf("unexpected merged output:\n%s", out)
+	}
+	if strings.Contains(out, "other_total") {
+		t.Fatalf("unknown metric merged:\n%s", out)
+	}
+
+	var nilRegistry *Registry
+	nilRegistry.Inc(DedupHits)
+	nilRegistry.Observe(TransferDuration, 1)
+	if s := nilRegistry.Take(); len(s.Counters) != 0 {
+		t.Fatal("a nil registry should record nothing")
+	}
+}
+
+func TestServerCollectsPushes(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	srv, err := Listen(socket, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer func() { _ = srv.Close() }()
+
+	if _, err := Listen(socket, ""); err != ErrCollectorRunning {
+		t.Fatalf("a second collector should be refused, got %v", err)
+	}
+
+	r := NewRegistry()
+	r.Add(TransferBytes, 4096, "op", "upload")
+	if err := Push(socket, r.Take()); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	data, err := Fetch(socket)
+	if err != nil || !strings.Contains(string(data), `proton_lfs_transfer_bytes_total{op="upload"} 4096`) {
+		t.Fatalf("Fetch = %q, %v", data, err)
+	}
+
+	base := "http://" + srv.Addr().String()
+	resp, err := http.Get(base + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("scrape: %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	body, _ := json.Marshal(Snapshot{})
+	resp, err = http.Post(base+"/push", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("the scrape address should not take pushes, got %s", resp.Status)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	// Leave a socket file nobody listens on, as a crashed collector would.
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+
+	second, err := Listen(socket, "")
+	if err != nil {
+		t.Fatalf("a stale socket should be replaced, got %v", err)
+	}
+	_ = second.Close()
+
+	if err := Push(socket, Snapshot{}); err == nil {
+		t.Fatal("expected a push without a collector to fail")
+	}
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pushTimeout bounds a push or fetch over the collector socket, so that an
+// adapter never waits on a stuck collector for long.
+const pushTimeout = time.Second
+
+// maxPushBytes bounds the body of one push.
+const maxPushBytes = 1 << 20
+
+// ErrCollectorRunning is returned by Listen when another collector already
+// answers on the socket.
+var ErrCollectorRunning = errors.New("metrics collector already running")
+
+// Server is the metrics collector. It accepts snapshots pushed by adapters on
+// a Unix socket and serves the merged registry at /metrics, on the socket and
+// optionally on a TCP address for Prometheus to scrape.
+type Server struct {
+	Registry *Registry
+
+	socketPath string
+	servers    []*http.Server
+	tcpAddr    net.Addr
+}
+
+// Listen starts a collector on socketPath and, when addr is not empty, on
+// the TCP address addr. A stale socket left by a collector that exited is
+// replaced.
+func Listen(socketPath, addr string) (*Server, error) {
+	s := &Server{Registry: NewRegistry(), socketPath: socketPath}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+		return nil, fmt.Errorf("create metrics socket dir: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", socketPath, pushTimeout); err == nil {
+		_ = conn.Close()
+		return nil, ErrCollectorRunning
+	}
+	_ = os.Remove(socketPath)
+	unixListener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("listen on metrics socket: %w", err)
+	}
+	_ = os.Chmod(socketPath, 0o600)
+
+	// Only the socket accepts pushes; the TCP endpoint is read-only.
+	socketMux := http.NewServeMux()
+	socketMux.HandleFunc("/metrics", s.serveMetrics)
+	socketMux.HandleFunc("/push", s.servePush)
+	s.serve(unixListener, socketMux)
+
+	if addr != "" {
+		tcpListener, err := net.Listen("tcp", addr)
+		if err != nil {
+			_ = s.Close()
+			return nil, fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		s.tcpAddr = tcpListener.Addr()
+		tcpMux := http.NewServeMux()
+		tcpMux.HandleFunc("/metrics", s.serveMetrics)
+		s.serve(tcpListener, tcpMux)
+	}
+	return s, nil
+}
+
+func (s *Server) serve(l net.Listener, handler http.Handler) {
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	s.servers = append(s.servers, srv)
+	go func() { _ = srv.Serve(l) }()
+}
+
+// Addr returns the TCP address the collector serves /metrics on, or nil.
+func (s *Server) Addr() net.Addr {
+	return s.tcpAddr
+}
+
+// Close stops the collector and removes its socket.
+func (s *Server) Close() error {
+	var errs []error
+	for _, srv := range s.servers {
+		errs = append(errs, srv.Close())
+	}
+	_ = os.Remove(s.socketPath)
+	return errors.Join(errs...)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = s.Registry.WriteText(w)
+}
+
+func (s *Server) servePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var snapshot Snapshot
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxPushBytes)).Decode(&snapshot); err != nil {
+		http.Error(w, "invalid snapshot", http.StatusBadRequest)
+		return
+	}
+	s.Registry.Merge(snapshot)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// socketClient returns an HTTP client that connects to the collector socket.
+func socketClient(socketPath string) *http.Client {
+	return &http.Client{
+		Timeout: pushTimeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+}
+
+// Push sends s to the collector listening on socketPath.
+func Push(socketPath string, s Snapshot) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal metrics: %w", err)
+	}
+	resp, err := socketClient(socketPath).Post("http://collector/push", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("push metrics: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("push metrics: %s", resp.Status)
+	}
+	return nil
+}
+
+// Fetch returns the text exposition of the collector listening on
+// socketPath.
+func Fetch(socketPath string) ([]byte, error) {
+	resp, err := socketClient(socketPath).Get("http://collector/metrics")
+	if err != nil {
+		return nil, fmt.Errorf("fetch metrics: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch metrics: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,99 @@
+// Package slots is a counting semaphore shared by every process on the
+// machine. Each slot is a file in a shared directory, held with an exclusive
+// lock. The operating system drops the lock when its holder exits, so an
+// adapter that crashes or is killed never leaks a slot. TryLock holds a
+// single lock file the same way.
+package slots
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Acquire polls for a free slot, starting at minPoll and doubling up to
+// maxPoll between attempts. Lock files cannot be waited on with a timeout,
+// so waiters poll instead of queueing in order.
+const (
+	minPoll = 10 * time.Millisecond
+	maxPoll = 250 * time.Millisecond
+)
+
+// Dir is a directory of slot files.
+type Dir struct {
+	path string
+}
+
+// Open returns the slot directory at path, creating it if needed.
+func Open(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, fmt.Errorf("create slot dir: %w", err)
+	}
+	return &Dir{path: path}, nil
+}
+
+// Slot is a held slot or lock file.
+type Slot struct {
+	f *os.File
+}
+
+// Release frees the slot.
+func (s *Slot) Release() {
+	_ = unlockFile(s.f)
+	_ = s.f.Close()
+}
+
+// TryAcquire takes the first free slot among slots 0 to n-1, or returns nil
+// when all of them are held. Processes that use different n share the low
+// slots, so at most the largest n run at once.
+func (d *Dir) TryAcquire(n int) (*Slot, error) {
+	for i := range n {
+		slot, err := TryLock(filepath.Join(d.path, fmt.Sprintf("slot-%d.lock", i)))
+		if slot != nil || err != nil {
+			return slot, err
+		}
+	}
+	return nil, nil
+}
+
+// TryLock takes the exclusive lock on the file at path, creating the file if
+// needed, or returns nil when another holder has it.
+func TryLock(path string) (*Slot, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	locked, err := tryLockFile(f)
+	if locked {
+		return &Slot{f: f}, nil
+	}
+	_ = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("lock file: %w", err)
+	}
+	return nil, nil
+}
+
+// Acquire waits until one of slots 0 to n-1 is free and takes it. It
+// returns ctx.Err() if ctx is done first.
+func (d *Dir) Acquire(ctx context.Context, n int) (*Slot, error) {
+	delay := minPoll
+	for {
+		slot, err := d.TryAcquire(n)
+		if slot != nil || err != nil {
+			return slot, err
+		}
+		// Jitter keeps waiters that started together from polling together.
+		timer := time.NewTimer(delay + rand.N(delay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxPoll)
+	}
+}
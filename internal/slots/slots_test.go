@@ -0,0 +1,154 @@
+package slots
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as another process holding a slot.
+func TestMain(m *testing.M) {
+	if dir := os.Getenv("SLOTS_TEST_HOLD_DIR"); dir != "" {
+		d, err := Open(dir)
+		if err != nil {
+			os.Exit(2)
+		}
+		if slot, _ := d.TryAcquire(1); slot == nil {
+			os.Exit(3)
+		}
+		_, _ = os.Stdout.WriteString("held\n")
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestTryAcquireLimitsSlots(t *testing.T) {
+	d, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var held []*Slot
+	for range 3 {
+		slot, err := d.TryAcquire(3)
+		if err != nil || slot == nil {
+			t.Fatalf("TryAcquire = %v, %v; want a slot", slot, err)
+		}
+		held = append(held, slot)
+	}
+	if slot, err := d.TryAcquire(3); slot != nil || err != nil {
+		t.Fatalf("fourth TryAcquire = %v, %v; want no slot", slot, err)
+	}
+	// A caller with a higher limit can still use the slots above 3.
+	if slot, _ := d.TryAcquire(4); slot == nil {
+		t.Fatal("TryAcquire(4) should take the fourth slot")
+	} else {
+		slot.Release()
+	}
+
+	held[1].Release()
+	slot, err := d.TryAcquire(3)
+	if err != nil || slot == nil {
+		t.Fatalf("TryAcquire after release = %v, %v; want a slot", slot, err)
+	}
+	slot.Release()
+	held[0].Release()
+	held[2].Release()
+}
+
+func TestTryLockExcludesSecondHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "object.lock")
+	lock, err := TryLock(path)
+	if err != nil || lock == nil {
+		t.Fatalf("TryLock = %v, %v; want the lock", lock, err)
+	}
+	if second, err := TryLock(path); second != nil || err != nil {
+		t.Fatalf("second TryLock = %v, %v; want no lock", second, err)
+	}
+	lock.Release()
+	lock, err = TryLock(path)
+	if err != nil || lock == nil {
+		t.Fatalf("TryLock after release = %v, %v; want the lock", lock, err)
+	}
+	lock.Release()
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	d, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := d.TryAcquire(1)
+	if first == nil {
+		t.Fatal("expected a slot")
+	}
+	time.AfterFunc(50*time.Millisecond, first.Release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	slot, err := d.Acquire(ctx, 1)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	slot.Release()
+}
+
+func TestAcquireTimesOut(t *testing.T) {
+	d, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, _ := d.TryAcquire(1)
+	if held == nil {
+		t.Fatal("expected a slot")
+	}
+	defer held.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if slot, err := d.Acquire(ctx, 1); slot != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v, %v; want DeadlineExceeded", slot, err)
+	}
+}
+
+func TestSlotSharedAcrossProcessesAndFreedOnExit(t *testing.T) {
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "SLOTS_TEST_HOLD_DIR="+dir)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cmd.Process.Kill() }()
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "held\n" {
+		t.Fatalf("helper process did not take the slot: %q, %v", line, err)
+	}
+
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot, _ := d.TryAcquire(1); slot != nil {
+		slot.Release()
+		t.Fatal("the slot held by another process must not be free")
+	}
+
+	// Killing the holder frees its slot without any cleanup on its part.
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	slot, err := d.Acquire(ctx, 1)
+	if err != nil {
+		t.Fatalf("Acquire after the holder exited: %v", err)
+	}
+	slot.Release()
+}